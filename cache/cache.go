@@ -0,0 +1,229 @@
+// Package cache persists parsed Documents on disk so unchanged files are
+// not re-parsed on every run.
+//
+// Entries are keyed by the docmap version, the parse settings, the file's
+// absolute path, its size and modification time, and a SHA-256 of its
+// content. Any of those changing — including upgrading docmap — produces a
+// different key, so a stale entry is never served. Entries from another
+// version or for a changed file become garbage for Prune; entries made with
+// other settings stay, since a run with those settings can still use them.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// entryExt is the file extension used for cache entries.
+const entryExt = ".docmap"
+
+// Store is an on-disk Document cache rooted at Dir. A Store is safe for
+// concurrent use: entries are written to a temp file and renamed into place.
+type Store struct {
+	Dir      string
+	Version  string
+	Settings string // parse settings (tokenizer, custom callouts, ...) entries depend on
+}
+
+// header is written ahead of the encoded Document so Prune can tell which
+// source file and docmap version an entry belongs to without decoding it.
+type header struct {
+	Version  string
+	Settings string
+	Path     string
+	Size     int64
+	ModTime  int64
+	Hash     string
+}
+
+// DefaultDir returns the cache directory: $DOCMAP_CACHE_DIR if set,
+// otherwise docmap/ under the user cache directory (XDG_CACHE_HOME on
+// Linux, ~/Library/Caches on macOS, %LocalAppData% on Windows).
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("DOCMAP_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "docmap"), nil
+}
+
+// Open returns a Store rooted at dir for the given docmap version.
+func Open(dir, version string) *Store {
+	return &Store{Dir: dir, Version: version}
+}
+
+// Parse returns the cached Document for path if one exists for its current
+// content, and otherwise calls parse and stores the result. Cache read and
+// write failures are never fatal — they fall through to parse.
+func (s *Store) Parse(path string, parse func(string) (*parser.Document, error)) (*parser.Document, error) {
+	h, err := s.headerFor(path)
+	if err != nil {
+		return parse(path)
+	}
+	entry := s.entryPath(h)
+	if doc, ok := readEntry(entry, h); ok {
+		return doc, nil
+	}
+	doc, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	s.write(entry, h, doc)
+	return doc, nil
+}
+
+// headerFor stats and hashes path to build its cache key.
+func (s *Store) headerFor(path string) (header, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return header{}, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return header{}, err
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return header{}, err
+	}
+	sum := sha256.Sum256(content)
+	return header{
+		Version:  s.Version,
+		Settings: s.Settings,
+		Path:     abs,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Hash:     hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// entryPath derives the on-disk location of an entry from its header.
+// Entries are sharded by the first two hex characters of the key.
+func (s *Store) entryPath(h header) string {
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d\x00%s", h.Version, h.Settings, h.Path, h.Size, h.ModTime, h.Hash)))
+	name := hex.EncodeToString(key[:])
+	return filepath.Join(s.Dir, name[:2], name+entryExt)
+}
+
+func readEntry(path string, want header) (*parser.Document, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	r := bytes.NewReader(data)
+	var got header
+	if err := gob.NewDecoder(r).Decode(&got); err != nil || got != want {
+		return nil, false
+	}
+	doc, err := parser.DecodeDocument(r)
+	if err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+func (s *Store) write(path string, h header, doc *parser.Document) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(h); err != nil {
+		return
+	}
+	if err := parser.EncodeDocument(&buf, doc); err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(buf.Bytes())
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// PruneStats reports what Prune removed.
+type PruneStats struct {
+	Removed int
+	Kept    int
+	Bytes   int64
+}
+
+// Prune deletes every entry that can no longer be served: entries written
+// by a different docmap version, entries whose source file is gone or has
+// changed, and unreadable or leftover temp files.
+func (s *Store) Prune() (PruneStats, error) {
+	var stats PruneStats
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if s.live(path) {
+			stats.Kept++
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		stats.Removed++
+		stats.Bytes += info.Size()
+		return nil
+	})
+	return stats, err
+}
+
+// Clear removes the whole cache directory.
+func (s *Store) Clear() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// live reports whether the entry at path would still be served for its
+// source file under the settings it was written with. Only the version has
+// to match the Store's: settings vary between projects and runs sharing a
+// cache, so pruning never judges them.
+func (s *Store) live(path string) bool {
+	if !strings.HasSuffix(path, entryExt) {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var h header
+	if err := gob.NewDecoder(f).Decode(&h); err != nil {
+		return false
+	}
+	if h.Version != s.Version {
+		return false
+	}
+	current, err := s.headerFor(h.Path)
+	if err != nil {
+		return false
+	}
+	current.Settings = h.Settings
+	return current == h && s.entryPath(current) == path
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+func countingParser(calls *int) func(string) (*parser.Document, error) {
+	return func(path string) (*parser.Document, error) {
+		*calls++
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return parser.Parse(string(content)), nil
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseHitsCacheForUnchangedFile(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "doc.md")
+	writeFile(t, src, "# Title\n\n## Child\n\ntext\n")
+	store := Open(t.TempDir(), "v1")
+
+	calls := 0
+	first, err := store.Parse(src, countingParser(&calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Parse(src, countingParser(&calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 parse, got %d", calls)
+	}
+	if second.TotalTokens != first.TotalTokens || len(second.GetAllSections()) != 2 {
+		t.Error("cached document differs from parsed document")
+	}
+}
+
+func TestParseInvalidatesOnChange(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "doc.md")
+	writeFile(t, src, "# One\n")
+	store := Open(t.TempDir(), "v1")
+
+	calls := 0
+	store.Parse(src, countingParser(&calls))
+	writeFile(t, src, "# Two\n")
+	// Force a different mtime even on coarse-grained filesystems.
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(src, later, later)
+
+	doc, err := store.Parse(src, countingParser(&calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected re-parse after change, got %d parses", calls)
+	}
+	if doc.Sections[0].Title != "Two" {
+		t.Errorf("expected fresh content, got %q", doc.Sections[0].Title)
+	}
+}
+
+func TestParseInvalidatesOnVersion(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "doc.md")
+	writeFile(t, src, "# One\n")
+	dir := t.TempDir()
+
+	calls := 0
+	Open(dir, "v1").Parse(src, countingParser(&calls))
+	Open(dir, "v2").Parse(src, countingParser(&calls))
+	if calls != 2 {
+		t.Errorf("expected version bump to bypass cache, got %d parses", calls)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	srcDir := t.TempDir()
+	keep := filepath.Join(srcDir, "keep.md")
+	gone := filepath.Join(srcDir, "gone.md")
+	writeFile(t, keep, "# Keep\n")
+	writeFile(t, gone, "# Gone\n")
+	dir := t.TempDir()
+
+	calls := 0
+	Open(dir, "v1").Parse(keep, countingParser(&calls))
+	Open(dir, "v1").Parse(gone, countingParser(&calls))
+	Open(dir, "v0").Parse(keep, countingParser(&calls))
+	tuned := Open(dir, "v1")
+	tuned.Settings = "cl100k"
+	tuned.Parse(keep, countingParser(&calls))
+	os.Remove(gone)
+
+	// Entries made with other settings are still live.
+	stats, err := Open(dir, "v1").Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Removed != 2 || stats.Kept != 2 {
+		t.Errorf("expected 2 removed / 2 kept, got %+v", stats)
+	}
+	calls = 0
+	tuned.Parse(keep, countingParser(&calls))
+	if calls != 0 {
+		t.Error("prune dropped an entry made with other settings")
+	}
+}
+
+func TestPruneMissingDir(t *testing.T) {
+	stats, err := Open(filepath.Join(t.TempDir(), "nope"), "v1").Prune()
+	if err != nil {
+		t.Fatalf("expected missing cache dir to be a no-op, got %v", err)
+	}
+	if stats.Removed != 0 {
+		t.Errorf("expected nothing removed, got %+v", stats)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JordanCoin/docmap/cache"
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// openCache returns the default on-disk parse cache, or nil if no cache
+// directory can be determined. Token counts depend on the tokenizer and
+// parses on custom callouts and type mappings, so entries are keyed by
+// those settings too. Pruning only checks the version and source file, so
+// it keeps entries made with other settings (another project's
+// .docmap.yaml, a different --tokenizer).
+func openCache(rules fileRules) *cache.Store {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil
+	}
+	store := cache.Open(dir, cacheVersion())
+	store.Settings = parser.ActiveTokenizer().Name()
+	if callouts := parser.CalloutVariants(); len(callouts) > 0 {
+		store.Settings += "+callouts=" + strings.Join(callouts, ",")
+	}
+	if types := rules.signature(); types != "" {
+		store.Settings += "+types=" + types
+	}
+	return store
+}
+
+// cacheVersion is the version string cache entries are keyed by. Release
+// builds use the release version. Development builds all report "dev", so
+// the binary's modification time is mixed in to keep a rebuilt parser from
+// serving entries written by an older one.
+func cacheVersion() string {
+	if version != "dev" {
+		return version
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return version
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return version
+	}
+	return fmt.Sprintf("%s-%d", version, info.ModTime().UnixNano())
+}
+
+// runCache implements `docmap cache <prune|clear|dir>`.
+func runCache(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: docmap cache <prune|clear|dir>")
+		os.Exit(1)
+	}
+	store := openCache(fileRules{})
+	if store == nil {
+		fmt.Fprintln(os.Stderr, "Error: no cache directory available")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "prune":
+		start := time.Now()
+		stats, err := store.Prune()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pruning cache: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Pruned %d entries (%s freed), kept %d in %s\n",
+			stats.Removed, formatBytes(stats.Bytes), stats.Kept, time.Since(start).Round(time.Millisecond))
+	case "clear":
+		if err := store.Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing cache: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cleared %s\n", store.Dir)
+	case "dir":
+		fmt.Println(store.Dir)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown cache command %q. Try: prune, clear, dir\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// JSONLinkReport is the answer to `docmap check links --json`.
+type JSONLinkReport struct {
+	Files   int           `json:"files"`
+	Checked int           `json:"checked"`
+	Broken  []LinkProblem `json:"broken"`
+}
+
+// LinkProblem is one broken link, image, or wiki link.
+type LinkProblem struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Kind   string `json:"kind"` // link, image, wiki, or embed
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+func (p LinkProblem) String() string {
+	return fmt.Sprintf("%s:%d: broken %s %q: %s", p.File, p.Line, p.Kind, p.Target, p.Reason)
+}
+
+// htmlAnchorRe finds explicit anchors (<a name="x">, id="x") that links may
+// target besides heading slugs.
+var htmlAnchorRe = regexp.MustCompile(`\b(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+// linkChecker validates the local links of every markdown file under root.
+type linkChecker struct {
+	root     string
+	wikiRoot string // where wiki page names resolve; see projectRoot
+	opts     parseOptions
+	paths    []string // every file under wikiRoot, for wiki link lookup
+	docs     map[string]*parser.Document
+	lines    map[string][]string
+}
+
+// runCheck implements `docmap check <what> [dir|file]`.
+func runCheck(args []string, opts parseOptions, jsonMode bool) {
+	if len(args) == 0 || args[0] != "links" {
+		fmt.Fprintln(os.Stderr, "Usage: docmap check links [dir|file] [--json]")
+		os.Exit(1)
+	}
+	target := "."
+	if len(args) > 1 {
+		target = args[1]
+	}
+	report, err := checkLinks(target, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonMode {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		for _, p := range report.Broken {
+			fmt.Println(p)
+		}
+		fmt.Fprintf(os.Stderr, "checked %d link%s in %d file%s: %d broken\n",
+			report.Checked, plural(report.Checked), report.Files, plural(report.Files), len(report.Broken))
+	}
+	if len(report.Broken) > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkLinks checks every markdown file under target (or target itself).
+// Diagnostics name files relative to the directory checked.
+func checkLinks(target string, opts parseOptions) (JSONLinkReport, error) {
+	root, sources, err := markdownSources(target, opts.Files)
+	if err != nil {
+		return JSONLinkReport{}, err
+	}
+	c := &linkChecker{root: root, wikiRoot: root, opts: opts, docs: map[string]*parser.Document{}, lines: map[string][]string{}}
+	if len(sources) == 1 && sources[0] == target {
+		c.wikiRoot = projectRoot(root)
+	}
+	c.paths = wikiPaths(c.wikiRoot, opts.Files)
+
+	report := JSONLinkReport{Files: len(sources), Broken: []LinkProblem{}}
+	for i, r := range parseFiles(sources, opts) {
+		if r.err != nil {
+			continue
+		}
+		c.docs[sources[i]] = r.doc
+		checked, problems := c.checkDocument(sources[i], r.doc)
+		report.Checked += checked
+		report.Broken = append(report.Broken, problems...)
+	}
+	sort.SliceStable(report.Broken, func(i, j int) bool {
+		a, b := report.Broken[i], report.Broken[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// wikiPaths lists the files wiki links under root may name: every file a
+// directory map of root picks up, plus attachments such as images that
+// aren't ignored or excluded.
+func wikiPaths(root string, rules fileRules) []string {
+	var paths []string
+	walkTree(root, rules, nil, func(p string) {
+		if rules.wants(root, p) || (rules.kind(p) == "" && !rules.excluded(root, p)) {
+			paths = append(paths, p)
+		}
+	})
+	return paths
+}
+
+// projectRoot is the directory a single file's wiki links resolve against,
+// as if its whole project had been checked: the nearest directory above dir
+// holding a .docmap.yaml inside the git checkout, else the checkout, else
+// dir itself. It keeps dir's relative or absolute form.
+func projectRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	top := gitRoot(abs)
+	if cfg := findConfig(abs); cfg != "" && (top == "" || isWithin(top, filepath.Dir(cfg))) {
+		top = filepath.Dir(cfg)
+	}
+	if top == "" {
+		return dir
+	}
+	if filepath.IsAbs(dir) {
+		return top
+	}
+	if rel, err := filepath.Rel(abs, top); err == nil {
+		return filepath.Join(dir, rel)
+	}
+	return top
+}
+
+// markdownSources returns the markdown files to check under target (or
+// target itself) and the directory diagnostics are reported relative to.
+func markdownSources(target string, rules fileRules) (string, []string, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		return filepath.Dir(target), []string{target}, nil
+	}
+	var sources []string
+	for _, p := range collectFiles(target, rules) {
+		if rules.kind(p) == kindMarkdown {
+			sources = append(sources, p)
+		}
+	}
+	return target, sources, nil
+}
+
+// checkDocument validates every local link in doc, returning how many
+// were checked and which are broken.
+func (c *linkChecker) checkDocument(path string, doc *parser.Document) (int, []LinkProblem) {
+	var problems []LinkProblem
+	checked := 0
+	report := func(n parser.Node, kind, target, needle, reason string) {
+		problems = append(problems, LinkProblem{
+			File:   c.relative(path),
+			Line:   c.locate(path, n, needle),
+			Kind:   kind,
+			Target: target,
+			Reason: reason,
+		})
+	}
+
+	for _, root := range doc.Nodes {
+		parser.Walk(root, func(n parser.Node) bool {
+			switch v := n.(type) {
+			case *parser.CodeBlock, *parser.HTMLBlock, *parser.MathBlock:
+				return false
+			case *parser.Link:
+				if isExternalLink(v.URL) || v.URL == "" {
+					return true
+				}
+				checked++
+				if reason := c.checkURL(path, v.URL); reason != "" {
+					report(n, "link", v.URL, v.URL, reason)
+				}
+			case *parser.Image:
+				if isExternalLink(v.URL) || v.URL == "" {
+					return true
+				}
+				checked++
+				if reason := c.checkURL(path, v.URL); reason != "" {
+					report(n, "image", v.URL, v.URL, reason)
+				}
+			case *parser.WikiLink:
+				checked++
+				if reason := c.checkWiki(path, v.Target, v.Anchor, v.Block); reason != "" {
+					target := v.Target
+					if v.Anchor != "" {
+						target += "#" + v.Anchor
+					} else if v.Block != "" {
+						target += "#^" + v.Block
+					}
+					report(n, "wiki", "[["+target+"]]", "[["+target, reason)
+				}
+			case *parser.WikiEmbed:
+				checked++
+				if reason := c.checkWiki(path, v.Target, "", ""); reason != "" {
+					report(n, "embed", "![["+v.Target+"]]", "![["+v.Target, reason)
+				}
+			}
+			return true
+		})
+	}
+	return checked, problems
+}
+
+// checkURL validates a relative link or image URL, returning why it is
+// broken or "" if it resolves.
+func (c *linkChecker) checkURL(from, link string) string {
+	target, anchor := resolveLinkPath(from, c.root, link)
+	info, err := os.Stat(target)
+	if err != nil {
+		return "file not found"
+	}
+	if anchor == "" || info.IsDir() || c.opts.Files.kind(target) != kindMarkdown {
+		return ""
+	}
+	if !c.hasAnchor(target, anchor) {
+		return fmt.Sprintf("no heading or anchor #%s in %s", anchor, c.relative(target))
+	}
+	return ""
+}
+
+// checkWiki validates an Obsidian [[target#anchor]] or [[target#^block]].
+func (c *linkChecker) checkWiki(from, name, anchor, block string) string {
+	target := from
+	if name != "" {
+		if target = resolveWikiPage(name, c.wikiRoot, c.paths); target == "" {
+			return "no file named " + name
+		}
+	}
+	doc := c.document(target)
+	switch {
+	case doc == nil:
+		return ""
+	case anchor != "" && findHeading(doc, anchor) == nil:
+		return fmt.Sprintf("no heading %q in %s", anchor, c.relative(target))
+	case block != "" && !strings.Contains(strings.Join(c.source(target), "\n"), "^"+block):
+		return fmt.Sprintf("no block ^%s in %s", block, c.relative(target))
+	}
+	return ""
+}
+
+// hasAnchor reports whether a markdown file defines #anchor, as a GitHub
+// heading slug or an explicit HTML id/name.
+func (c *linkChecker) hasAnchor(path, anchor string) bool {
+	doc := c.document(path)
+	if doc == nil {
+		return true
+	}
+	if doc.SectionByAnchor(anchor) != nil {
+		return true
+	}
+	for _, m := range htmlAnchorRe.FindAllStringSubmatch(strings.Join(c.source(path), "\n"), -1) {
+		if strings.EqualFold(m[1], anchor) {
+			return true
+		}
+	}
+	return false
+}
+
+// document parses path on first use. Non-markdown files have no anchors
+// to check and return nil.
+func (c *linkChecker) document(path string) *parser.Document {
+	if doc, ok := c.docs[path]; ok {
+		return doc
+	}
+	var doc *parser.Document
+	if c.opts.Files.kind(path) == kindMarkdown {
+		doc, _ = c.opts.parse(path)
+	}
+	c.docs[path] = doc
+	return doc
+}
+
+func (c *linkChecker) source(path string) []string {
+	if lines, ok := c.lines[path]; ok {
+		return lines
+	}
+	lines := readLines(path)
+	c.lines[path] = lines
+	return lines
+}
+
+// locate finds the exact line of a link inside its node's line range. The
+// AST records inline links with their paragraph's range, so the source is
+// searched for needle; the range start is the fallback.
+func (c *linkChecker) locate(path string, n parser.Node, needle string) int {
+	lines := c.source(path)
+	for l := n.LineStart(); l <= n.LineEnd() && l <= len(lines); l++ {
+		if l > 0 && strings.Contains(lines[l-1], needle) {
+			return l
+		}
+	}
+	return n.LineStart()
+}
+
+func (c *linkChecker) relative(path string) string {
+	if rel, err := filepath.Rel(c.root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
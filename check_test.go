@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLinkFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md": "# Project\n\n" +
+			"See [the guide](docs/guide.md) and [setup](docs/guide.md#getting-started).\n" +
+			"Also [missing](docs/missing.md) and [bad anchor](docs/guide.md#nope).\n" +
+			"![logo](img/logo.png) ![gone](img/gone.png)\n" +
+			"[Upstream](https://example.com/x.md) [mail](mailto:a@b.c)\n\n" +
+			"## Local\n\n[up](#project) [down](#local) [nowhere](#elsewhere)\n\n" +
+			"```md\n[fake](not-there.md)\n```\n\n" +
+			"Inline `[code](nope.md)` is not a link.\n",
+		"docs/guide.md": "# Guide\n\n## Getting Started\n\n[back](../README.md#local)\n\n" +
+			"## Usage\n\n## Usage\n\n[second](#usage-1) [named](#custom)\n\n<a name=\"custom\"></a>\n\n" +
+			"Block text ^blk1\n",
+		"docs/notes.md": "# Notes\n\n[[guide]] [[guide#Usage]] [[guide#Missing]] [[guide#^blk1]] [[guide#^blk9]]\n\n" +
+			"[[Nowhere]] ![[logo.png]] ![[absent.png]]\n",
+		"img/logo.png": "png",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheckLinks(t *testing.T) {
+	report, err := checkLinks(writeLinkFixture(t), parseOptions{Jobs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 3 {
+		t.Errorf("files = %d, want 3", report.Files)
+	}
+
+	var got []string
+	for _, p := range report.Broken {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`README.md:4: broken link "docs/missing.md": file not found`,
+		`README.md:4: broken link "docs/guide.md#nope": no heading or anchor #nope in docs/guide.md`,
+		`README.md:5: broken image "img/gone.png": file not found`,
+		`README.md:10: broken link "#elsewhere": no heading or anchor #elsewhere in README.md`,
+		`docs/notes.md:3: broken wiki "[[guide#Missing]]": no heading "Missing" in docs/guide.md`,
+		`docs/notes.md:3: broken wiki "[[guide#^blk9]]": no block ^blk9 in docs/guide.md`,
+		`docs/notes.md:5: broken embed "![[absent.png]]": no file named absent.png`,
+		`docs/notes.md:5: broken wiki "[[Nowhere]]": no file named Nowhere`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("broken links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckLinksSingleFile(t *testing.T) {
+	dir := writeLinkFixture(t)
+	report, err := checkLinks(filepath.Join(dir, "docs", "guide.md"), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 1 || report.Checked != 3 {
+		t.Errorf("files = %d, checked = %d; want 1 and 3", report.Files, report.Checked)
+	}
+	if len(report.Broken) != 0 {
+		t.Errorf("guide.md should have no broken links, got %v", report.Broken)
+	}
+}
+
+func TestCheckLinksSingleFileWikiRoot(t *testing.T) {
+	dir := writeLinkFixture(t)
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	report, err := checkLinks(filepath.Join(dir, "docs", "notes.md"), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// logo.png lives outside docs/; the repository root still finds it.
+	var got []string
+	for _, p := range report.Broken {
+		got = append(got, p.Target)
+	}
+	want := []string{"[[guide#Missing]]", "[[guide#^blk9]]", "![[absent.png]]", "[[Nowhere]]"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("broken = %v, want %v", got, want)
+	}
+}
+
+func TestCheckLinksWikiSkipsIgnoredFiles(t *testing.T) {
+	dir := writeLinkFixture(t)
+	writeFiles(t, dir, map[string]string{
+		".gitignore":              "node_modules/\n",
+		".docmapignore":           "absent.png\n",
+		"node_modules/Nowhere.md": "# Nowhere\n",
+		"img/absent.png":          "png",
+	})
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	report, err := checkLinks(filepath.Join(dir, "docs", "notes.md"), parseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range report.Broken {
+		got = append(got, p.Target)
+	}
+	// Ignored files are not wiki targets, even when their names match.
+	want := []string{"[[guide#Missing]]", "[[guide#^blk9]]", "![[absent.png]]", "[[Nowhere]]"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("broken = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// .docmap.yaml holds per-project defaults. It is looked up from the target
+// upward, like .editorconfig: the nearest file wins, and CLI flags override
+// anything it sets. Globs are relative to the directory holding the file.
+
+// configFileNames are the names a project config may have, in lookup order.
+var configFileNames = []string{".docmap.yaml", ".docmap.yml"}
+
+// projectConfig is the contents of .docmap.yaml.
+type projectConfig struct {
+	Include    []string          `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude    []string          `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	IgnoreDirs []string          `yaml:"ignore_dirs,omitempty" json:"ignore_dirs,omitempty"`
+	Types      map[string]string `yaml:"types,omitempty" json:"types,omitempty"`
+	Tokenizer  string            `yaml:"tokenizer,omitempty" json:"tokenizer,omitempty"`
+	Output     string            `yaml:"output,omitempty" json:"output,omitempty"` // tree or json
+	Callouts   []string          `yaml:"callouts,omitempty" json:"callouts,omitempty"`
+	Lint       lintSettings      `yaml:"lint,omitempty" json:"lint,omitempty"`
+
+	path string // the file this was read from; "" when none was found
+
+	// Set when --include or --exclude replaced the config's globs; flag
+	// globs are relative to the walked directory, not the config's.
+	includeFlags, excludeFlags bool
+}
+
+// lintSettings is the lint: block of .docmap.yaml.
+type lintSettings struct {
+	Disable   []string `yaml:"disable,omitempty" json:"disable,omitempty"`
+	MaxTokens int      `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	Format    string   `yaml:"format,omitempty" json:"format,omitempty"` // human, json, or sarif
+}
+
+// findConfig returns the nearest config file at or above start, or "".
+func findConfig(start string) string {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return ""
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		for _, name := range configFileNames {
+			p := filepath.Join(dir, name)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				return p
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configTarget is the path the config is looked up from: whatever file or
+// directory the command operates on, or the working directory.
+func configTarget(positional []string, stdinMode bool) string {
+	if stdinMode {
+		return "."
+	}
+	args := positional
+	if len(os.Args) > 1 && len(args) > 0 && args[0] == os.Args[1] {
+		switch args[0] {
+		case "mcp", "lsp":
+			args = nil
+		case "serve", "pack", "lint":
+			args = args[1:]
+		case "check", "config":
+			args = args[min(2, len(args)):]
+		}
+	}
+	if len(args) == 0 {
+		return "."
+	}
+	return args[0]
+}
+
+// loadConfig reads the config that applies to start. Without one, the zero
+// config (all defaults) is returned.
+func loadConfig(start string) (projectConfig, error) {
+	path := findConfig(start)
+	if path == "" {
+		return projectConfig{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return projectConfig{}, err
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return projectConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.path = path
+	return cfg, nil
+}
+
+// parseConfig decodes and validates config YAML. Unknown keys are errors so
+// typos don't silently fall back to defaults.
+func parseConfig(data []byte) (projectConfig, error) {
+	var cfg projectConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return projectConfig{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return projectConfig{}, err
+	}
+	return cfg, nil
+}
+
+// validate rejects values no command could act on. Tokenizer names are
+// checked when the tokenizer is loaded.
+func (c projectConfig) validate() error {
+	switch c.Output {
+	case "", "tree", "json":
+	default:
+		return fmt.Errorf("unknown output %q (want tree or json)", c.Output)
+	}
+	switch c.Lint.Format {
+	case "", "human", "json", "sarif":
+	default:
+		return fmt.Errorf("unknown lint format %q (want human, json, or sarif)", c.Lint.Format)
+	}
+	for _, id := range c.Lint.Disable {
+		if _, ok := lookupLintRule(id); !ok {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+	}
+	for ext, kind := range c.Types {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("type mapping %q: extensions start with a dot", ext)
+		}
+		if !slices.Contains(fileKinds(), kind) {
+			return fmt.Errorf("type mapping %s: unknown type %q (want one of %s)", ext, kind, strings.Join(fileKinds(), ", "))
+		}
+	}
+	return nil
+}
+
+// setFileFlags applies --include and --exclude, each replacing the
+// config's globs when given.
+func (c *projectConfig) setFileFlags(include, exclude []string) {
+	if include != nil {
+		c.Include, c.includeFlags = include, true
+	}
+	if exclude != nil {
+		c.Exclude, c.excludeFlags = exclude, true
+	}
+}
+
+// fileRules returns the file selection settings, with config globs
+// relative to the config file's directory.
+func (c projectConfig) fileRules() fileRules {
+	r := fileRules{
+		Include:       c.Include,
+		Exclude:       c.Exclude,
+		IncludeAtRoot: c.includeFlags,
+		ExcludeAtRoot: c.excludeFlags,
+		IgnoreDirs:    c.IgnoreDirs,
+	}
+	if c.path != "" {
+		r.Base = filepath.Dir(c.path)
+	}
+	if len(c.Types) > 0 {
+		r.Types = map[string]string{}
+		for ext, kind := range c.Types {
+			r.Types[strings.ToLower(ext)] = kind
+		}
+	}
+	return r
+}
+
+// lintConfig returns the lint rule settings.
+func (c projectConfig) lintConfig() lintConfig {
+	cfg := lintConfig{MaxTokens: c.Lint.MaxTokens}
+	for _, id := range c.Lint.Disable {
+		if cfg.Disabled == nil {
+			cfg.Disabled = map[string]bool{}
+		}
+		cfg.Disabled[id] = true
+	}
+	return cfg
+}
+
+// lintFormat is the lint output format, falling back to the general
+// output mode.
+func (c projectConfig) lintFormat() string {
+	if c.Lint.Format != "" {
+		return c.Lint.Format
+	}
+	if c.Output == "json" {
+		return "json"
+	}
+	return "human"
+}
+
+// effective fills in every default so `docmap config show` lists the
+// settings actually in force.
+func (c projectConfig) effective() projectConfig {
+	out := c
+	if out.Tokenizer == "" {
+		out.Tokenizer = parser.DefaultTokenizer
+	}
+	if out.Output == "" {
+		out.Output = "tree"
+	}
+	out.Types = c.fileRules().types()
+	out.Lint.Format = c.lintFormat()
+	out.Lint.MaxTokens = c.lintConfig().maxTokens()
+	return out
+}
+
+// runConfig implements `docmap config show`.
+func runConfig(args []string, cfg projectConfig, jsonMode bool) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: docmap config show [dir|file] [--json]")
+		os.Exit(1)
+	}
+	writeConfig(os.Stdout, cfg, jsonMode)
+}
+
+// writeConfig prints the effective configuration, noting where it came from.
+func writeConfig(w io.Writer, cfg projectConfig, jsonMode bool) {
+	eff := cfg.effective()
+	if jsonMode {
+		json.NewEncoder(w).Encode(struct {
+			Source string `json:"source,omitempty"`
+			projectConfig
+		}{cfg.path, eff})
+		return
+	}
+	if cfg.path != "" {
+		fmt.Fprintf(w, "# %s (merged with flags and defaults)\n", cfg.path)
+	} else {
+		fmt.Fprintln(w, "# no .docmap.yaml found; defaults and flags only")
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	enc.Encode(eff)
+	enc.Close()
+}
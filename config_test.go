@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadConfigDiscoversUpward(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".docmap.yaml":        "tokenizer: cl100k\nlint:\n  max_tokens: 500\n",
+		"docs/guide/intro.md": "# Intro\n",
+	})
+
+	cfg, err := loadConfig(filepath.Join(dir, "docs", "guide", "intro.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.path != filepath.Join(dir, ".docmap.yaml") {
+		t.Errorf("path = %q", cfg.path)
+	}
+	if cfg.Tokenizer != "cl100k" || cfg.lintConfig().maxTokens() != 500 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if rules := cfg.fileRules(); rules.Base != dir {
+		t.Errorf("globs should be relative to %s, got %q", dir, rules.Base)
+	}
+
+	// The nearest file wins.
+	writeFiles(t, dir, map[string]string{"docs/.docmap.yml": "output: json\n"})
+	cfg, err = loadConfig(filepath.Join(dir, "docs", "guide"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Output != "json" || cfg.Tokenizer != "" {
+		t.Errorf("expected only the nearer config, got %+v", cfg)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, tc := range []struct{ yaml, want string }{
+		{"tokeniser: cl100k\n", "field tokeniser not found"},
+		{"output: html\n", `unknown output "html"`},
+		{"lint:\n  disable: [no-such-rule]\n", `unknown lint rule "no-such-rule"`},
+		{"lint:\n  format: xml\n", `unknown lint format "xml"`},
+		{"types:\n  mdx: markdown\n", "extensions start with a dot"},
+		{"types:\n  .mdx: latex\n", `unknown type "latex"`},
+	} {
+		_, err := parseConfig([]byte(tc.yaml))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: got error %v, want %q", tc.yaml, err, tc.want)
+		}
+	}
+	if _, err := parseConfig(nil); err != nil {
+		t.Errorf("empty config should be valid, got %v", err)
+	}
+}
+
+func TestConfigFileRules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".docmap.yaml": "include: [docs, README.md]\nexclude: [drafts, '**/CHANGELOG.md']\n" +
+			"ignore_dirs: [node_modules]\ntypes:\n  .mdx: markdown\n  .yml: ignore\n",
+		"README.md":                   "# R\n",
+		"notes.md":                    "# not included\n",
+		"docs/a.md":                   "# A\n",
+		"docs/b.MDX":                  "# B\n",
+		"docs/c.yml":                  "k: v\n",
+		"docs/d.yaml":                 "k: v\n",
+		"docs/CHANGELOG.md":           "# C\n",
+		"docs/drafts/wip.md":          "# W\n",
+		"docs/node_modules/pkg/x.md":  "# X\n",
+		"docs/api/v1/node_modules.md": "# a file, not a dir\n",
+	})
+	cfg, err := loadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range collectFiles(filepath.Join(dir, "docs"), cfg.fileRules()) {
+		rel, _ := filepath.Rel(dir, p)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := "docs/a.md,docs/api/v1/node_modules.md,docs/b.MDX,docs/d.yaml"
+	if strings.Join(got, ",") != want {
+		t.Errorf("collected %v, want %s", got, want)
+	}
+
+	docs := parseDirectory(dir, parseOptions{Jobs: 1, Files: cfg.fileRules()})
+	if names := strings.Join(docNames(docs), ","); names != "README.md,docs/a.md,docs/api/v1/node_modules.md,docs/b.MDX,docs/d.yaml" {
+		t.Errorf("parsed %s", names)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.md", "a.md", true},
+		{"*.md", "docs/a.md", false},
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**/b.md", "docs/b.md", true},
+		{"docs/**/b.md", "docs/x/y/b.md", true},
+		{"**/b.md", "b.md", true},
+		{"docs/*.md", "docs/x/a.md", false},
+		{"d?cs/[ab].md", "docs/a.md", true},
+	} {
+		if got := matchGlob(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte("callouts: [danger]\nlint:\n  disable: [fence-language]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Tokenizer = "o200k" // as a --tokenizer flag would
+
+	var buf bytes.Buffer
+	writeConfig(&buf, cfg, false)
+	out := buf.String()
+	for _, want := range []string{
+		"# no .docmap.yaml found",
+		"tokenizer: o200k",
+		"output: tree",
+		".md: markdown",
+		"- danger",
+		"- fence-language",
+		"max_tokens: 2000",
+		"format: human",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("config show missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFlagGlobsRelativeToTarget(t *testing.T) {
+	files := map[string]string{
+		"docs/guide/a.md": "# A\n",
+		"docs/other.md":   "# O\n",
+	}
+	collect := func(dir string) string {
+		cfg, err := loadConfig(filepath.Join(dir, "docs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.setFileFlags([]string{"guide/**"}, []string{"other.md"})
+		return relPaths(t, dir, collectFiles(filepath.Join(dir, "docs"), cfg.fileRules()))
+	}
+
+	plain := t.TempDir()
+	writeFiles(t, plain, files)
+	configured := t.TempDir()
+	writeFiles(t, configured, files)
+	writeFiles(t, configured, map[string]string{".docmap.yaml": "exclude: [docs/guide/b.md]\n"})
+
+	// --include resolves against the walked directory either way.
+	for _, dir := range []string{plain, configured} {
+		if got := collect(dir); got != "docs/guide/a.md" {
+			t.Errorf("%s: collected %q, want docs/guide/a.md", dir, got)
+		}
+	}
+
+	// Config globs stay relative to the config file.
+	cfg, err := loadConfig(configured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, configured, map[string]string{"docs/guide/b.md": "# B\n"})
+	if got := relPaths(t, configured, collectFiles(filepath.Join(configured, "docs"), cfg.fileRules())); got != "docs/guide/a.md,docs/other.md" {
+		t.Errorf("config exclude: collected %q", got)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JordanCoin/docmap/cache"
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// parseOptions controls how a directory is walked and parsed.
+type parseOptions struct {
+	Jobs    int          // worker count; <= 0 means runtime.NumCPU()
+	Timings io.Writer    // when non-nil, per-file parse times are written here
+	Cache   *cache.Store // when non-nil, parsed documents are cached on disk
+	Files   fileRules    // which files a walk picks up and how each parses
+}
+
+// parse parses one file, going through the on-disk cache when enabled. The
+// search index is built here so it's cached with the parse.
+func (o parseOptions) parse(path string) (*parser.Document, error) {
+	parse := func(path string) (*parser.Document, error) {
+		doc, err := parseFile(path, o.Files.kind(path))
+		if err != nil {
+			return nil, err
+		}
+		doc.BuildIndex()
+		return doc, nil
+	}
+	if o.Cache != nil {
+		return o.Cache.Parse(path, parse)
+	}
+	return parse(path)
+}
+
+// fileResult is the outcome of parsing one file in the worker pool.
+type fileResult struct {
+	doc     *parser.Document
+	elapsed time.Duration
+	err     error
+}
+
+// notDocument reports whether the file turned out not to be a document at
+// all, like a package.json, rather than failing to parse.
+func (r fileResult) notDocument() bool {
+	return errors.Is(r.err, parser.ErrNotSpec)
+}
+
+// parseDirectory finds every supported file under dir and parses them with a
+// bounded worker pool. Files are discovered with filepath.Walk first so the
+// returned slice is always in walk order, regardless of which worker finishes
+// first — MultiTree and --json output stay identical for any --jobs value.
+func parseDirectory(dir string, opts parseOptions) []*parser.Document {
+	paths := collectFiles(dir, opts.Files)
+	results := parseFiles(paths, opts)
+
+	var docs []*parser.Document
+	for i, r := range results {
+		if r.notDocument() {
+			continue
+		}
+		relPath, _ := filepath.Rel(dir, paths[i])
+		if opts.Timings != nil {
+			status := ""
+			if r.err != nil {
+				status = " (skipped)"
+			}
+			fmt.Fprintf(opts.Timings, "%10s  %s%s\n", r.elapsed.Round(time.Microsecond), relPath, status)
+		}
+		if r.err != nil {
+			// Skip files that can't be read or parsed.
+			continue
+		}
+		r.doc.Filename = relPath
+		docs = append(docs, r.doc)
+	}
+	return docs
+}
+
+// parseFiles parses paths with opts.Jobs workers. results[i] always
+// corresponds to paths[i].
+func parseFiles(paths []string, opts parseOptions) []fileResult {
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(paths) {
+		jobs = len(paths)
+	}
+
+	results := make([]fileResult, len(paths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				start := time.Now()
+				doc, err := opts.parse(paths[i])
+				results[i] = fileResult{doc: doc, elapsed: time.Since(start), err: err}
+			}
+		}()
+	}
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// noFilesMessage is printed when a directory holds nothing docmap parses.
+const noFilesMessage = "No markdown, reStructuredText, AsciiDoc, notebook, HTML, PDF, YAML, or API spec JSON files found"
+
+// collectFiles walks dir and returns every file rules pick up, in walk
+// order. Hidden files and directories are skipped, as is anything matched
+// by .gitignore, .git/info/exclude, or .docmapignore.
+func collectFiles(dir string, rules fileRules) []string {
+	var paths []string
+	walkTree(dir, rules, nil, func(path string) {
+		if rules.wants(dir, path) {
+			paths = append(paths, path)
+		}
+	})
+	return paths
+}
+
+// collectDirs returns dir and every directory beneath it that collectFiles
+// would descend into.
+func collectDirs(dir string, rules fileRules) []string {
+	var dirs []string
+	walkTree(dir, rules, func(path string) {
+		dirs = append(dirs, path)
+	}, nil)
+	return dirs
+}
+
+// walkTree walks dir, calling onDir (if set) for each directory it enters
+// and onFile (if set) for each file that isn't hidden or ignored, whatever
+// its type.
+func walkTree(dir string, rules fileRules, onDir, onFile func(path string)) {
+	ignore := newIgnoreMatcher(dir, rules.Base)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != dir {
+				if strings.HasPrefix(info.Name(), ".") || rules.skipDir(dir, path) || ignore.ignored(path, true) {
+					return filepath.SkipDir
+				}
+			}
+			ignore.load(path)
+			if onDir != nil {
+				onDir(path)
+			}
+			return nil
+		}
+		if onFile == nil || ignore.ignored(path, false) {
+			return nil
+		}
+
+		// Skip hidden files
+		base := filepath.Base(path)
+		if strings.HasPrefix(base, ".") {
+			return nil
+		}
+
+		onFile(path)
+		return nil
+	})
+}
+
+// parseFile parses a single file with the parser for kind (see
+// fileRules.kind). Anything that isn't PDF, YAML, a JSON API spec,
+// reStructuredText, AsciiDoc, a notebook or HTML is treated as markdown.
+// The returned Document's Filename is left for the caller to set.
+func parseFile(path, kind string) (*parser.Document, error) {
+	if kind == kindPDF {
+		doc, err := parser.ParsePDF(path)
+		if err != nil {
+			return nil, fmt.Errorf("parsing PDF: %w", err)
+		}
+		return doc, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	return parseContent(kind, string(content))
+}
+
+// parseContent parses in-memory content as kind. PDFs are binary and only
+// go through parseFile.
+func parseContent(kind, content string) (*parser.Document, error) {
+	switch kind {
+	case kindYAML:
+		doc, err := parser.ParseYAML(content)
+		if err != nil {
+			return nil, fmt.Errorf("parsing YAML: %w", err)
+		}
+		return doc, nil
+	case kindJSON:
+		doc, err := parser.ParseJSON(content)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+		return doc, nil
+	case kindRST:
+		return parser.ParseRST(content), nil
+	case kindAsciiDoc:
+		return parser.ParseAsciiDoc(content), nil
+	case kindNotebook:
+		doc, err := parser.ParseNotebook([]byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("parsing notebook: %w", err)
+		}
+		return doc, nil
+	case kindHTML:
+		return parser.ParseHTML(content), nil
+	}
+	return parser.Parse(content), nil
+}
+
+// readLines returns path's lines, or nil for files that aren't text.
+// Notebooks yield their markdown rendering, which is what their line
+// numbers refer to.
+func readLines(path string) []string {
+	if strings.HasSuffix(strings.ToLower(path), ".pdf") {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".ipynb") {
+		source, _, _, err := parser.NotebookSource(data)
+		if err != nil {
+			return nil
+		}
+		data = []byte(source)
+	}
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+}
+
+// sourceLines returns a function reading the source lines of documents
+// loaded from target, a directory (documents are named relative to it) or
+// a single file.
+func sourceLines(target string, isDir bool) func(*parser.Document) []string {
+	return func(doc *parser.Document) []string {
+		if isDir {
+			return readLines(filepath.Join(target, doc.Filename))
+		}
+		return readLines(target)
+	}
+}
+
+// loadTarget parses target the way the CLI does: every supported file under
+// it when it is a directory (Filename relative to target), otherwise just
+// that file (Filename is its base name). isDir reports which case applied.
+func loadTarget(target string, opts parseOptions) (docs []*parser.Document, isDir bool, err error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.IsDir() {
+		return parseDirectory(target, opts), true, nil
+	}
+	doc, err := opts.parse(target)
+	if err != nil {
+		return nil, false, err
+	}
+	doc.Filename = filepath.Base(target)
+	return []*parser.Document{doc}, false, nil
+}
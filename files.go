@@ -0,0 +1,196 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File kinds: which parser a file goes through.
+const (
+	kindMarkdown = "markdown"
+	kindYAML     = "yaml"
+	kindJSON     = "json" // OpenAPI/AsyncAPI specs only
+	kindRST      = "rst"
+	kindAsciiDoc = "asciidoc"
+	kindNotebook = "notebook"
+	kindHTML     = "html"
+	kindPDF      = "pdf"
+	kindIgnore   = "ignore" // a types: mapping that turns an extension off
+)
+
+// defaultTypes maps the extensions docmap picks up out of the box.
+var defaultTypes = map[string]string{
+	".md":    kindMarkdown,
+	".pdf":   kindPDF,
+	".rst":   kindRST,
+	".adoc":  kindAsciiDoc,
+	".ipynb": kindNotebook,
+	".html":  kindHTML,
+	".htm":   kindHTML,
+	".yaml":  kindYAML,
+	".yml":   kindYAML,
+	".json":  kindJSON,
+}
+
+// fileKinds lists the kinds a types: mapping may name.
+func fileKinds() []string {
+	return []string{kindMarkdown, kindYAML, kindJSON, kindRST, kindAsciiDoc, kindNotebook, kindHTML, kindPDF, kindIgnore}
+}
+
+// fileRules decide which files a directory walk picks up and which parser
+// each one gets. The zero value takes every file whose extension is in
+// defaultTypes.
+type fileRules struct {
+	Base       string            // directory globs are relative to; "" means the walked directory
+	Include    []string          // when set, only files matching one of these
+	Exclude    []string          // files never picked up, even if included
+	IgnoreDirs []string          // directory names or globs never descended into
+	Types      map[string]string // extension (".mdx") to kind, on top of defaultTypes
+
+	// IncludeAtRoot and ExcludeAtRoot make Include and Exclude relative to
+	// the walked directory rather than Base, as for --include and --exclude.
+	IncludeAtRoot bool
+	ExcludeAtRoot bool
+}
+
+// kind returns the parser kind for path, or "" if it isn't a supported file.
+func (r fileRules) kind(p string) string {
+	ext := strings.ToLower(filepath.Ext(p))
+	kind, ok := r.Types[ext]
+	if !ok {
+		kind = defaultTypes[ext]
+	}
+	if kind == kindIgnore {
+		return ""
+	}
+	return kind
+}
+
+// types returns the effective extension mapping, defaults included.
+func (r fileRules) types() map[string]string {
+	out := map[string]string{}
+	for ext, kind := range defaultTypes {
+		out[ext] = kind
+	}
+	for ext, kind := range r.Types {
+		out[ext] = kind
+	}
+	return out
+}
+
+// skipDir reports whether a walk should not descend into dir.
+func (r fileRules) skipDir(root, dir string) bool {
+	return len(r.IgnoreDirs) > 0 && matchAny(r.IgnoreDirs, r.rel(root, dir))
+}
+
+// wants reports whether a walk of root picks up the file at p.
+func (r fileRules) wants(root, p string) bool {
+	if r.kind(p) == "" {
+		return false
+	}
+	if len(r.Include) > 0 && !matchAny(r.Include, r.globRel(root, p, r.IncludeAtRoot)) {
+		return false
+	}
+	return !r.excluded(root, p)
+}
+
+// excluded reports whether p matches an Exclude glob.
+func (r fileRules) excluded(root, p string) bool {
+	return matchAny(r.Exclude, r.globRel(root, p, r.ExcludeAtRoot))
+}
+
+// globRel is rel, or p relative to root itself when atRoot is set.
+func (r fileRules) globRel(root, p string, atRoot bool) string {
+	if atRoot {
+		return relSlash(root, p)
+	}
+	return r.rel(root, p)
+}
+
+// rel is p relative to the rules' base (or root), slash-separated.
+func (r fileRules) rel(root, p string) string {
+	base := r.Base
+	if base == "" {
+		base = root
+	}
+	return relSlash(base, p)
+}
+
+// relSlash is p relative to base, slash-separated.
+func relSlash(base, p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	if abs, err := filepath.Abs(base); err == nil {
+		base = abs
+	}
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// signature identifies the settings that change how a file parses, for
+// keying the parse cache.
+func (r fileRules) signature() string {
+	var parts []string
+	for ext, kind := range r.Types {
+		parts = append(parts, ext+"="+kind)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// matchAny reports whether rel, or any directory above it, matches one of
+// patterns. A pattern without a slash matches a file or directory name at
+// any depth ("CHANGELOG.md", "drafts"); one with a slash matches the path
+// from the base directory ("docs/internal/**"). "**" spans directories.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		for p := rel; p != "." && p != "" && p != ".."; p = path.Dir(p) {
+			name := p
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(p)
+			}
+			if matchGlob(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether a slash-separated path matches pattern. Each
+// segment follows path.Match syntax; a "**" segment matches zero or more
+// whole segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -2,9 +2,19 @@ module github.com/JordanCoin/docmap
 
 go 1.25.5
 
-require github.com/ledongthuc/pdf v0.0.0-20250511090121-5959a4027728
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/ledongthuc/pdf v0.0.0-20250511090121-5959a4027728
+	github.com/pkoukk/tiktoken-go v0.1.8
+	github.com/pkoukk/tiktoken-go-loader v0.0.2
+	github.com/yuin/goldmark v1.8.2
+	golang.org/x/net v0.57.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/yuin/goldmark v1.8.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
 )
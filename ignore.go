@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Directory walks honour the same ignore files git does, so vendored
+// dependencies and build output stay out of the map: .git/info/exclude,
+// then every .gitignore from the repository root down, each followed by a
+// .docmapignore in the same directory for docmap-only rules. Later
+// patterns take precedence, so deeper files override shallower ones and
+// "!pattern" can re-include what an earlier pattern ignored.
+
+// ignoreFileNames are read in each directory, lowest precedence first.
+var ignoreFileNames = []string{".gitignore", ".docmapignore"}
+
+// ignorePattern is one line of an ignore file.
+type ignorePattern struct {
+	base     string // directory of the ignore file, relative to the matcher's top ("" for the top)
+	glob     string
+	negate   bool // "!pattern" re-includes
+	dirOnly  bool // "pattern/" matches directories only
+	anchored bool // a slash at the start or middle: match the path from base, not just the name
+}
+
+// ignoreMatcher accumulates the patterns that apply during one walk.
+type ignoreMatcher struct {
+	top      string // absolute directory patterns are relative to
+	patterns []ignorePattern
+}
+
+// newIgnoreMatcher prepares a matcher for walking dir. Patterns are read
+// from the enclosing git repository's root down to dir's parent; dir and
+// its subdirectories add theirs through load as the walk reaches them.
+// Outside a repository the search starts at base (the project config's
+// directory) when set, else at dir itself.
+func newIgnoreMatcher(dir, base string) *ignoreMatcher {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	top := gitRoot(abs)
+	if top == "" && base != "" {
+		if b, err := filepath.Abs(base); err == nil && isWithin(b, abs) {
+			top = b
+		}
+	}
+	if top == "" {
+		top = abs
+	}
+
+	m := &ignoreMatcher{top: top}
+	if info, err := os.Stat(filepath.Join(top, ".git")); err == nil && info.IsDir() {
+		m.addFile(filepath.Join(top, ".git", "info", "exclude"), "")
+	}
+	var ancestors []string
+	for d := filepath.Dir(abs); isWithin(top, d) && d != abs; d = filepath.Dir(d) {
+		ancestors = append([]string{d}, ancestors...)
+		if d == top {
+			break
+		}
+	}
+	for _, d := range ancestors {
+		m.load(d)
+	}
+	return m
+}
+
+// gitRoot returns the nearest directory at or above dir holding .git.
+func gitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// isWithin reports whether p is dir or inside it.
+func isWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// load reads the ignore files in dir.
+func (m *ignoreMatcher) load(dir string) {
+	base := m.rel(dir)
+	for _, name := range ignoreFileNames {
+		m.addFile(filepath.Join(dir, name), base)
+	}
+}
+
+func (m *ignoreMatcher) addFile(file, base string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if p, ok := parseIgnoreLine(line); ok {
+			p.base = base
+			m.patterns = append(m.patterns, p)
+		}
+	}
+}
+
+// parseIgnoreLine parses one gitignore line; ok is false for blanks and
+// comments.
+func parseIgnoreLine(line string) (p ignorePattern, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return p, false
+	}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+	// fnmatch spells a negated class [!...]; path.Match wants [^...].
+	p.glob = strings.ReplaceAll(line, "[!", "[^")
+	return p, true
+}
+
+// ignored reports whether the file or directory at p is ignored. The last
+// matching pattern decides.
+func (m *ignoreMatcher) ignored(p string, isDir bool) bool {
+	rel := m.rel(p)
+	if rel == "" {
+		return false
+	}
+	ignored := false
+	for _, pat := range m.patterns {
+		if pat.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if pat.base != "" {
+			if !strings.HasPrefix(rel, pat.base+"/") {
+				continue
+			}
+			sub = rel[len(pat.base)+1:]
+		}
+		name := sub
+		if !pat.anchored {
+			name = path.Base(sub)
+		}
+		if matchGlob(pat.glob, name) {
+			ignored = !pat.negate
+		}
+	}
+	return ignored
+}
+
+// rel is p relative to the matcher's top, slash-separated; "" for the top.
+func (m *ignoreMatcher) rel(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	rel, err := filepath.Rel(m.top, p)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIgnoreFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".git/info/exclude":              "scratch.md\n",
+		".gitignore":                     "# build output\nnode_modules/\n/build\n*.log.md\n!keep.log.md\ndocs/**/generated/\n",
+		".docmapignore":                  "CHANGELOG.md\n",
+		"README.md":                      "# R\n",
+		"CHANGELOG.md":                   "# C\n",
+		"scratch.md":                     "# S\n",
+		"build/out.md":                   "# B\n",
+		"src/build/doc.md":               "# not anchored, so kept\n",
+		"debug.log.md":                   "# L\n",
+		"keep.log.md":                    "# K\n",
+		"node_modules/pkg/README.md":     "# N\n",
+		"docs/guide.md":                  "# G\n",
+		"docs/api/generated/ref.md":      "# generated\n",
+		"docs/api/index.md":              "# I\n",
+		"docs/.gitignore":                "draft-*.md\n!draft-final.md\n",
+		"docs/draft-one.md":              "# D1\n",
+		"docs/draft-final.md":            "# DF\n",
+		"docs/.obsidian/workspace.md":    "# hidden dir\n",
+		"vendor/node_modules":            "a file, not a directory\n",
+		"vendor/lib/node_modules/x/y.md": "# N2\n",
+	})
+	return dir
+}
+
+func relPaths(t *testing.T, root string, paths []string) string {
+	t.Helper()
+	var out []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestCollectFilesHonoursIgnoreFiles(t *testing.T) {
+	dir := writeIgnoreFixture(t)
+	got := relPaths(t, dir, collectFiles(dir, fileRules{}))
+	want := "README.md,docs/api/index.md,docs/draft-final.md,docs/guide.md,keep.log.md,src/build/doc.md"
+	if got != want {
+		t.Errorf("collected\n  %s\nwant\n  %s", got, want)
+	}
+}
+
+func TestCollectDirsSkipsIgnoredTrees(t *testing.T) {
+	dir := writeIgnoreFixture(t)
+	got := relPaths(t, dir, collectDirs(dir, fileRules{}))
+	want := ".,docs,docs/api,src,src/build,vendor,vendor/lib"
+	if got != want {
+		t.Errorf("collected dirs\n  %s\nwant\n  %s", got, want)
+	}
+}
+
+func TestCollectFilesInSubdirUsesRepoIgnores(t *testing.T) {
+	dir := writeIgnoreFixture(t)
+	// Walking docs/ alone still applies the root .gitignore (generated/)
+	// and docs/.gitignore.
+	got := relPaths(t, dir, collectFiles(filepath.Join(dir, "docs"), fileRules{}))
+	if want := "docs/api/index.md,docs/draft-final.md,docs/guide.md"; got != want {
+		t.Errorf("collected %s, want %s", got, want)
+	}
+}
+
+func TestCollectFilesIncludeExclude(t *testing.T) {
+	dir := writeIgnoreFixture(t)
+	rules := fileRules{Include: []string{"docs/**", "README.md"}, Exclude: []string{"api"}}
+	got := relPaths(t, dir, collectFiles(dir, rules))
+	if want := "README.md,docs/draft-final.md,docs/guide.md"; got != want {
+		t.Errorf("collected %s, want %s", got, want)
+	}
+}
+
+func TestParseIgnoreLine(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		ok   bool
+		want ignorePattern
+	}{
+		{"", false, ignorePattern{}},
+		{"# comment", false, ignorePattern{}},
+		{`\#literal`, true, ignorePattern{glob: "#literal"}},
+		{"!keep.md", true, ignorePattern{glob: "keep.md", negate: true}},
+		{`\!bang.md`, true, ignorePattern{glob: "!bang.md"}},
+		{"build/", true, ignorePattern{glob: "build", dirOnly: true}},
+		{"/root.md  ", true, ignorePattern{glob: "root.md", anchored: true}},
+		{"a/b/", true, ignorePattern{glob: "a/b", dirOnly: true, anchored: true}},
+		{"[!a]*.md", true, ignorePattern{glob: "[^a]*.md"}},
+	} {
+		got, ok := parseIgnoreLine(tc.line)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v; want %+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestIgnoreOutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".docmapignore":  "private/\n",
+		"a.md":           "# A\n",
+		"private/b.md":   "# B\n",
+		"sub/private.md": "# a file named private is kept\n",
+	})
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		t.Fatal("fixture must not be a repository")
+	}
+	got := relPaths(t, dir, collectFiles(dir, fileRules{}))
+	if want := "a.md,sub/private.md"; got != want {
+		t.Errorf("collected %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+	"github.com/JordanCoin/docmap/render"
+)
+
+// Typed results for the focused views (--section/--expand, --type, --at,
+// --since, --search, --refs). They reuse JSONSection and JSONNode so a
+// consumer that understands --json output understands these too. The
+// machine-facing modes return them instead of terminal output.
+
+// JSONSectionResult is the answer to --section / --expand.
+type JSONSectionResult struct {
+	Filename   string      `json:"filename"`
+	Breadcrumb string      `json:"breadcrumb"`
+	Section    JSONSection `json:"section"`
+	Content    string      `json:"content,omitempty"`
+}
+
+// JSONExtractResult is the answer to --extract and --lines: verbatim source
+// lines, untruncated.
+type JSONExtractResult struct {
+	Filename   string `json:"filename"`
+	Breadcrumb string `json:"breadcrumb,omitempty"`
+	LineStart  int    `json:"line_start"`
+	LineEnd    int    `json:"line_end"`
+	Content    string `json:"content"`
+}
+
+// JSONTypeResult is the answer to --type, grouped by section.
+type JSONTypeResult struct {
+	Type  string        `json:"type"`
+	Total int           `json:"total"`
+	Hits  []JSONTypeHit `json:"hits"`
+}
+
+// JSONTypeHit is one section's matches for a --type drill-down. Count and
+// Checked carry aggregate-only kinds (tasks, wiki links, mentions, ...).
+type JSONTypeHit struct {
+	Filename   string     `json:"filename"`
+	Breadcrumb string     `json:"breadcrumb"`
+	Tokens     int        `json:"tokens"`
+	LineStart  int        `json:"line_start,omitempty"`
+	LineEnd    int        `json:"line_end,omitempty"`
+	Nodes      []JSONNode `json:"nodes,omitempty"`
+	Count      int        `json:"count,omitempty"`
+	Checked    int        `json:"checked,omitempty"`
+}
+
+// JSONAtResult is the answer to --at.
+type JSONAtResult struct {
+	Filename   string    `json:"filename"`
+	Line       int       `json:"line"`
+	Breadcrumb string    `json:"breadcrumb,omitempty"`
+	Node       *JSONNode `json:"node,omitempty"`
+}
+
+// JSONSinceResult is the answer to --since.
+type JSONSinceResult struct {
+	Filename     string         `json:"filename"`
+	Ref          string         `json:"ref"`
+	ChangedLines []int          `json:"changed_lines"`
+	Hits         []JSONSinceHit `json:"hits"`
+}
+
+// JSONSinceHit is one section touched by the diff.
+type JSONSinceHit struct {
+	Breadcrumb string     `json:"breadcrumb"`
+	Tokens     int        `json:"tokens"`
+	Nodes      []JSONNode `json:"nodes,omitempty"`
+	Lines      []int      `json:"lines,omitempty"`
+}
+
+// JSONSearchResult is the answer to --search.
+type JSONSearchResult struct {
+	Query   string            `json:"query"`
+	Total   int               `json:"total"` // matches before --limit
+	Matches []JSONSearchMatch `json:"matches"`
+}
+
+// JSONSearchMatch is one matching section, best BM25 score first.
+// Section.Children is omitted; matching subsections are reported as matches
+// of their own.
+type JSONSearchMatch struct {
+	Filename   string          `json:"filename"`
+	Breadcrumb string          `json:"breadcrumb"`
+	Score      float64         `json:"score"`
+	Section    JSONSection     `json:"section"`
+	Lines      []JSONLineMatch `json:"lines,omitempty"`
+}
+
+// JSONLineMatch is one matching line. Spans are [start, end) byte offsets
+// of the matches within Text; Line is omitted for PDFs.
+type JSONLineMatch struct {
+	Line  int      `json:"line,omitempty"`
+	Text  string   `json:"text"`
+	Spans [][2]int `json:"spans,omitempty"`
+}
+
+// JSONRefsResult is the answer to --refs.
+type JSONRefsResult struct {
+	References []JSONRefEdge `json:"references"`
+	Hubs       []JSONRefHub  `json:"hubs,omitempty"`
+}
+
+// JSONRefEdge is one link from a document to another markdown file.
+type JSONRefEdge struct {
+	From   string `json:"from"`
+	Target string `json:"target"`
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+}
+
+// JSONRefHub is a file referenced by two or more links.
+type JSONRefHub struct {
+	File  string `json:"file"`
+	Count int    `json:"count"`
+}
+
+// convertSectionOnly serializes s without its children.
+func convertSectionOnly(s *parser.Section) JSONSection {
+	return JSONSection{
+		Level:     s.Level,
+		Title:     s.Title,
+		Tokens:    s.Tokens,
+		LineStart: s.LineStart,
+		LineEnd:   s.LineEnd,
+		KeyTerms:  s.KeyTerms,
+		Notables:  convertNodeList(s.Notables),
+	}
+}
+
+func querySection(doc *parser.Document, name string, index int, expand bool) (JSONSectionResult, error) {
+	s, err := resolveSection(doc, name, index)
+	if err != nil {
+		return JSONSectionResult{}, err
+	}
+	res := JSONSectionResult{
+		Filename:   doc.Filename,
+		Breadcrumb: render.Breadcrumb(s),
+		Section:    convertSections([]*parser.Section{s})[0],
+	}
+	if expand {
+		res.Content = s.Content
+	}
+	return res, nil
+}
+
+// resolveSection picks the section a --section/--expand name refers to.
+// A whole-title match wins over partial ones and an unambiguous fuzzy
+// match is accepted; otherwise the caller picks among the candidates with
+// a 1-based index or an "@line" suffix ("Config@42", any line inside the
+// section), and an ambiguousPathError lists them.
+func resolveSection(doc *parser.Document, ref string, index int) (*parser.Section, error) {
+	name, line := parser.SplitLineSuffix(ref)
+	found := doc.MatchSections(name)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("section %q not found", name)
+	}
+	candidates := make([]*parser.Section, len(found))
+	for i, m := range found {
+		candidates[i] = m.Section
+	}
+	hint := fmt.Sprintf("add parent titles to the path, or pick one with its number as the section index (--section-index N) or by line as %q",
+		fmt.Sprintf("%s@%d", name, found[0].Section.LineStart))
+
+	switch {
+	case line > 0:
+		// The innermost candidate around the line.
+		var best *parser.Section
+		for _, s := range candidates {
+			if s.LineStart <= line && line <= s.LineEnd && (best == nil || s.LineStart > best.LineStart) {
+				best = s
+			}
+		}
+		if best == nil {
+			return nil, &ambiguousPathError{path: name, matches: candidates,
+				header: fmt.Sprintf("no section matching %q at line %d; candidates:", name, line), hint: hint}
+		}
+		return best, nil
+	case index > 0:
+		if index > len(candidates) {
+			return nil, &ambiguousPathError{path: name, matches: candidates,
+				header: fmt.Sprintf("section index %d out of range; %q matches %d sections:", index, name, len(candidates)), hint: hint}
+		}
+		return candidates[index-1], nil
+	case len(found) > 1 && found[1].Kind == found[0].Kind && found[1].Distance == found[0].Distance:
+		return nil, &ambiguousPathError{path: name, matches: candidates, hint: hint}
+	}
+	return found[0].Section, nil
+}
+
+// ambiguousPathError lists the sections a breadcrumb path or section name
+// could mean, numbered for --section-index, with their lines.
+type ambiguousPathError struct {
+	path    string
+	matches []*parser.Section
+	header  string // defaults to "<path> matches N sections:"
+	hint    string
+}
+
+func (e *ambiguousPathError) Error() string {
+	var b strings.Builder
+	if e.header != "" {
+		b.WriteString(e.header)
+	} else {
+		fmt.Fprintf(&b, "%q matches %d sections:", e.path, len(e.matches))
+	}
+	for i, s := range e.matches {
+		fmt.Fprintf(&b, "\n  %d. %s (line %d)", i+1, render.Breadcrumb(s), s.LineStart)
+	}
+	b.WriteString("\n" + e.hint)
+	return b.String()
+}
+
+// queryExtract slices the section at a breadcrumb path out of lines, the
+// document's source. Without withChildren it stops where the first
+// subsection begins. Ambiguous paths are settled as in resolveSection, by
+// index or an "@line" suffix. Documents without source lines (PDFs) fall
+// back to the parsed section content.
+func queryExtract(doc *parser.Document, lines []string, path string, index int, withChildren bool) (JSONExtractResult, error) {
+	s, err := resolveSection(doc, path, index)
+	if err != nil {
+		return JSONExtractResult{}, err
+	}
+	end := s.OwnLineEnd()
+	if withChildren {
+		end = s.LineEnd
+	}
+	res := JSONExtractResult{
+		Filename:   doc.Filename,
+		Breadcrumb: render.Breadcrumb(s),
+		LineStart:  s.LineStart,
+		LineEnd:    end,
+	}
+	if s.LineStart > 0 && end >= s.LineStart && end <= len(lines) {
+		res.Content = strings.Join(lines[s.LineStart-1:end], "\n")
+		return res, nil
+	}
+	res.Content = sectionText(s, withChildren)
+	return res, nil
+}
+
+// sectionText rebuilds a section from its parsed content.
+func sectionText(s *parser.Section, withChildren bool) string {
+	text := s.Title + "\n\n" + s.Content
+	if withChildren {
+		for _, c := range s.Children {
+			text += "\n\n" + sectionText(c, true)
+		}
+	}
+	return text
+}
+
+// queryLines slices a raw line range ("120-180", "120-", or "120") out of
+// lines. The end is clamped to the last line.
+func queryLines(doc *parser.Document, lines []string, spec string) (JSONExtractResult, error) {
+	if lines == nil {
+		return JSONExtractResult{}, fmt.Errorf("--lines needs a text file")
+	}
+	// A trailing newline leaves an empty final element that isn't a line.
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	startText, endText, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(startText))
+	if err != nil {
+		return JSONExtractResult{}, fmt.Errorf("invalid line range %q", spec)
+	}
+	end := start
+	if isRange {
+		end = len(lines)
+		if t := strings.TrimSpace(endText); t != "" {
+			if end, err = strconv.Atoi(t); err != nil {
+				return JSONExtractResult{}, fmt.Errorf("invalid line range %q", spec)
+			}
+		}
+	}
+	end = min(end, len(lines))
+	if start < 1 || start > len(lines) || end < start {
+		return JSONExtractResult{}, fmt.Errorf("line range %q is outside 1-%d", spec, len(lines))
+	}
+	return JSONExtractResult{
+		Filename:  doc.Filename,
+		LineStart: start,
+		LineEnd:   end,
+		Content:   strings.Join(lines[start-1:end], "\n"),
+	}, nil
+}
+
+func queryType(docs []*parser.Document, kindName, lang, variant string) (JSONTypeResult, error) {
+	kind, ok := render.ResolveKindName(kindName)
+	if !ok {
+		return JSONTypeResult{}, fmt.Errorf("unknown type %q", kindName)
+	}
+	res := JSONTypeResult{Type: string(kind), Hits: []JSONTypeHit{}}
+	for _, doc := range docs {
+		for _, h := range render.FindType(doc, kind, lang, variant) {
+			res.Hits = append(res.Hits, JSONTypeHit{
+				Filename:   doc.Filename,
+				Breadcrumb: render.Breadcrumb(h.Section),
+				Tokens:     h.Section.Tokens,
+				LineStart:  h.Section.LineStart,
+				LineEnd:    h.Section.LineEnd,
+				Nodes:      convertNodeList(h.Nodes),
+				Count:      h.Count,
+				Checked:    h.Checked,
+			})
+			res.Total += len(h.Nodes) + h.Count
+		}
+	}
+	return res, nil
+}
+
+func queryAt(doc *parser.Document, line int) JSONAtResult {
+	res := JSONAtResult{Filename: doc.Filename, Line: line}
+	node, section := render.NodeAt(doc, line)
+	if section != nil {
+		res.Breadcrumb = render.Breadcrumb(section)
+	}
+	if node != nil {
+		j := convertNode(node)
+		res.Node = &j
+	}
+	return res
+}
+
+func querySince(doc *parser.Document, changed map[int]bool, ref string) JSONSinceResult {
+	res := JSONSinceResult{
+		Filename:     doc.Filename,
+		Ref:          ref,
+		ChangedLines: []int{},
+		Hits:         []JSONSinceHit{},
+	}
+	for line := range changed {
+		res.ChangedLines = append(res.ChangedLines, line)
+	}
+	sort.Ints(res.ChangedLines)
+	for _, h := range render.FindChanges(doc, changed) {
+		res.Hits = append(res.Hits, JSONSinceHit{
+			Breadcrumb: render.Breadcrumb(h.Section),
+			Tokens:     h.Section.Tokens,
+			Nodes:      convertNodeList(h.Notables),
+			Lines:      h.Lines,
+		})
+	}
+	return res
+}
+
+func querySearch(docs []*parser.Document, query string, opts render.SearchOptions, linesOf func(*parser.Document) []string) (JSONSearchResult, error) {
+	q, err := render.ParseSearchQuery(query, opts)
+	if err != nil {
+		return JSONSearchResult{}, err
+	}
+	results := render.Search(docs, q, linesOf)
+	res := JSONSearchResult{Query: query, Total: len(results), Matches: []JSONSearchMatch{}}
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	for _, r := range results {
+		m := JSONSearchMatch{
+			Filename:   r.Filename,
+			Breadcrumb: render.Breadcrumb(r.Section),
+			Score:      math.Round(r.Score*1000) / 1000,
+			Section:    convertSectionOnly(r.Section),
+		}
+		for _, lm := range r.Matches {
+			m.Lines = append(m.Lines, JSONLineMatch{Line: lm.Line, Text: lm.Text, Spans: lm.Spans})
+		}
+		res.Matches = append(res.Matches, m)
+	}
+	return res, nil
+}
+
+func queryRefs(docs []*parser.Document) JSONRefsResult {
+	res := JSONRefsResult{References: []JSONRefEdge{}}
+	counts := map[string]int{}
+	for _, doc := range docs {
+		for _, ref := range doc.References {
+			res.References = append(res.References, JSONRefEdge{
+				From:   doc.Filename,
+				Target: ref.Target,
+				Text:   ref.Text,
+				Line:   ref.Line,
+			})
+			counts[ref.Target]++
+		}
+	}
+	for file, n := range counts {
+		if n >= 2 {
+			res.Hubs = append(res.Hubs, JSONRefHub{File: file, Count: n})
+		}
+	}
+	sort.Slice(res.Hubs, func(i, j int) bool {
+		if res.Hubs[i].Count != res.Hubs[j].Count {
+			return res.Hubs[i].Count > res.Hubs[j].Count
+		}
+		return res.Hubs[i].File < res.Hubs[j].File
+	})
+	return res
+}
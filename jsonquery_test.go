@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+const extractFixture = "# Guide\n\nIntro.\n\n## Install\n\nPick a platform.\n\n### Linux\n\napt install docmap\n\n## Usage\n\n### Linux\n\nRun it.\n"
+
+func TestQueryExtract(t *testing.T) {
+	doc := parser.Parse(extractFixture)
+	lines := strings.Split(extractFixture, "\n")
+
+	res, err := queryExtract(doc, lines, "Guide > Install", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Content != "## Install\n\nPick a platform.\n" || res.LineStart != 5 || res.LineEnd != 8 {
+		t.Errorf("own content: unexpected result %+v", res)
+	}
+
+	res, err = queryExtract(doc, lines, "Guide > Install", 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(res.Content, "### Linux\n\napt install docmap") || res.LineEnd != 11 {
+		t.Errorf("with children: unexpected result %+v", res)
+	}
+
+	res, err = queryExtract(doc, lines, "usage > linux", 0, false)
+	if err != nil || res.Breadcrumb != "Guide > Usage > Linux" || res.Content != "### Linux\n\nRun it." {
+		t.Errorf("breadcrumb path: unexpected result %+v, %v", res, err)
+	}
+
+	_, err = queryExtract(doc, lines, "Linux", 0, false)
+	var ambiguous *ambiguousPathError
+	if !errors.As(err, &ambiguous) || len(ambiguous.matches) != 2 {
+		t.Fatalf("expected an ambiguity error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Guide > Install > Linux (line 9)") {
+		t.Errorf("ambiguity error should list candidates, got %q", err)
+	}
+
+	if _, err := queryExtract(doc, lines, "Missing", 0, false); err == nil {
+		t.Error("expected an error for an unknown path")
+	}
+
+	// Sibling sections with the same title differ only by position.
+	siblings := "# Notes\n\n## Todo\n\nOne.\n\n## Todo\n\nTwo.\n"
+	doc = parser.Parse(siblings)
+	lines = strings.Split(siblings, "\n")
+	_, err = queryExtract(doc, lines, "Notes > Todo", 0, false)
+	if err == nil || !strings.Contains(err.Error(), "2. Notes > Todo (line 7)") || !strings.Contains(err.Error(), `"Notes > Todo@3"`) {
+		t.Errorf("sibling ambiguity should list candidates with lines, got %v", err)
+	}
+	for _, pick := range []struct {
+		path  string
+		index int
+	}{{"Notes > Todo@8", 0}, {"Todo", 2}} {
+		res, err := queryExtract(doc, lines, pick.path, pick.index, false)
+		if err != nil || res.Content != "## Todo\n\nTwo." {
+			t.Errorf("%q #%d: got %+v, %v", pick.path, pick.index, res, err)
+		}
+	}
+}
+
+func TestQueryLines(t *testing.T) {
+	doc := parser.Parse(extractFixture)
+	lines := strings.Split(extractFixture, "\n")
+	cases := []struct {
+		spec       string
+		start, end int
+		first      string
+		wantErr    bool
+	}{
+		{spec: "5-7", start: 5, end: 7, first: "## Install"},
+		{spec: "17", start: 17, end: 17, first: "Run it."},
+		{spec: "15-", start: 15, end: 17, first: "### Linux"},
+		{spec: "16-999", start: 16, end: 17, first: ""},
+		{spec: "0-3", wantErr: true},
+		{spec: "18", wantErr: true},
+		{spec: "9-3", wantErr: true},
+		{spec: "abc", wantErr: true},
+	}
+	for _, c := range cases {
+		res, err := queryLines(doc, lines, c.spec)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("--lines %s: expected an error, got %+v", c.spec, res)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("--lines %s: %v", c.spec, err)
+			continue
+		}
+		if res.LineStart != c.start || res.LineEnd != c.end || strings.Split(res.Content, "\n")[0] != c.first {
+			t.Errorf("--lines %s: unexpected result %+v", c.spec, res)
+		}
+	}
+}
+
+func TestResolveSection(t *testing.T) {
+	doc := parser.Parse(extractFixture + "\n## Config\n\nSettings.\n\n## Runtime config\n\nFlags.\n")
+	cases := []struct {
+		name  string
+		index int
+		want  int // LineStart, or 0 for an error
+	}{
+		{"Config", 0, 19},   // the exact title beats "Runtime config"
+		{"confg", 0, 19},    // a typo
+		{"Linux", 0, 0},     // two equally good matches
+		{"Linux", 2, 15},    // picked by number
+		{"Linux", 3, 0},     // out of range
+		{"Linux@16", 0, 15}, // picked by a line inside it
+		{"Linux@3", 0, 0},   // no candidate around that line
+		{"Missing", 0, 0},
+	}
+	for _, c := range cases {
+		s, err := resolveSection(doc, c.name, c.index)
+		switch {
+		case c.want == 0 && err == nil:
+			t.Errorf("resolveSection(%q, %d): expected an error, got %q", c.name, c.index, s.Title)
+		case c.want != 0 && err != nil:
+			t.Errorf("resolveSection(%q, %d): %v", c.name, c.index, err)
+		case c.want != 0 && s.LineStart != c.want:
+			t.Errorf("resolveSection(%q, %d) at line %d, want %d", c.name, c.index, s.LineStart, c.want)
+		}
+	}
+
+	_, err := resolveSection(doc, "Linux", 0)
+	for _, want := range []string{"1. Guide > Install > Linux (line 9)", "2. Guide > Usage > Linux (line 15)", `"Linux@9"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ambiguity error should contain %q, got %q", want, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// Link resolution shared by the language server and `docmap check links`.
+
+// isExternalLink reports whether a link URL points off the local tree.
+func isExternalLink(u string) bool {
+	if strings.Contains(u, "://") || strings.HasPrefix(u, "//") {
+		return true
+	}
+	for _, scheme := range []string{"mailto:", "tel:", "data:", "javascript:"} {
+		if strings.HasPrefix(strings.ToLower(u), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLinkPath splits a local link URL into the file it names and its
+// #fragment. Relative paths resolve against from's directory; paths
+// starting with "/" resolve against root. An empty file part (a bare
+// "#anchor") means from itself.
+func resolveLinkPath(from, root, link string) (path, anchor string) {
+	file, anchor, _ := strings.Cut(link, "#")
+	file, _, _ = strings.Cut(file, "?")
+	if unescaped, err := url.PathUnescape(file); err == nil {
+		file = unescaped
+	}
+	switch {
+	case file == "":
+		return from, anchor
+	case strings.HasPrefix(file, "/"):
+		return filepath.Join(root, filepath.FromSlash(file)), anchor
+	}
+	return filepath.Join(filepath.Dir(from), filepath.FromSlash(file)), anchor
+}
+
+// resolveWikiPage finds the file an Obsidian-style [[name]] points at: a
+// root-relative path or a bare file name, with or without the .md
+// extension. A full path match wins over a base-name match. It returns ""
+// when no file matches.
+func resolveWikiPage(name, root string, paths []string) string {
+	trim := func(p string) string {
+		return strings.TrimSuffix(strings.ToLower(filepath.ToSlash(p)), ".md")
+	}
+	want := trim(strings.TrimSpace(name))
+	var byBase string
+	for _, p := range paths {
+		rel := p
+		if r, err := filepath.Rel(root, p); err == nil {
+			rel = r
+		}
+		if trim(rel) == want {
+			return p
+		}
+		if byBase == "" && trim(filepath.Base(p)) == want {
+			byBase = p
+		}
+	}
+	return byBase
+}
+
+// findHeading matches a wiki #heading by title or by GitHub anchor.
+func findHeading(doc *parser.Document, heading string) *parser.Section {
+	for _, sec := range doc.GetAllSections() {
+		if strings.EqualFold(sec.Title, heading) {
+			return sec
+		}
+	}
+	return doc.SectionByAnchor(parser.Slug(heading))
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// docmap lint checks markdown structure using the section tree and typed
+// AST. Each rule is independent and can be switched off by ID.
+
+// JSONLintReport is the answer to `docmap lint --format json`.
+type JSONLintReport struct {
+	Files    int           `json:"files"`
+	Problems []LintProblem `json:"problems"`
+	Root     string        `json:"-"` // absolute directory Problems' files are relative to
+}
+
+// LintProblem is one rule violation.
+type LintProblem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Rule, p.Message)
+}
+
+// lintConfig selects which rules run and tunes their thresholds.
+type lintConfig struct {
+	Disabled  map[string]bool // rule IDs to skip
+	MaxTokens int             // section-tokens threshold; 0 means the default
+}
+
+const defaultMaxSectionTokens = 2000
+
+func (c lintConfig) maxTokens() int {
+	if c.MaxTokens > 0 {
+		return c.MaxTokens
+	}
+	return defaultMaxSectionTokens
+}
+
+// lintFile is one parsed markdown file under lint.
+type lintFile struct {
+	doc   *parser.Document
+	lines []string
+}
+
+// lintRule is one check. report records a violation at a 1-indexed line.
+type lintRule struct {
+	ID          string
+	Description string
+	check       func(f *lintFile, cfg lintConfig, report func(line int, msg string))
+}
+
+var lintRules = []lintRule{
+	{"heading-increment", "Heading levels increase one at a time", lintHeadingIncrement},
+	{"single-h1", "A document has at most one H1", lintSingleH1},
+	{"duplicate-heading", "Sibling sections have distinct titles", lintDuplicateHeading},
+	{"empty-heading", "Headings have text", lintEmptyHeading},
+	{"unclosed-callout", "Callout markers are closed: > [!NOTE]", lintUnclosedCallout},
+	{"table-columns", "Table rows have as many cells as the header", lintTableColumns},
+	{"fence-language", "Fenced code blocks name a language", lintFenceLanguage},
+	{"section-tokens", "Sections stay under the token threshold", lintSectionTokens},
+}
+
+// lookupLintRule finds a rule by ID.
+func lookupLintRule(id string) (lintRule, bool) {
+	for _, r := range lintRules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return lintRule{}, false
+}
+
+// runLint implements `docmap lint [dir|file] [--format human|json|sarif]`.
+func runLint(target, format string, cfg lintConfig, opts parseOptions) {
+	if target == "" {
+		target = "."
+	}
+	for id := range cfg.Disabled {
+		if _, ok := lookupLintRule(id); !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown lint rule %q\n", id)
+			os.Exit(1)
+		}
+	}
+	report, err := lintTarget(target, cfg, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "", "human":
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+		fmt.Fprintf(os.Stderr, "linted %d file%s: %d problem%s\n",
+			report.Files, plural(report.Files), len(report.Problems), plural(len(report.Problems)))
+	case "json":
+		json.NewEncoder(os.Stdout).Encode(report)
+	case "sarif":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		enc.Encode(report.sarif(cfg))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want human, json, or sarif)\n", format)
+		os.Exit(1)
+	}
+	if len(report.Problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintTarget runs every enabled rule over the markdown files under target.
+// Problems name files relative to the directory linted.
+func lintTarget(target string, cfg lintConfig, opts parseOptions) (JSONLintReport, error) {
+	root, sources, err := markdownSources(target, opts.Files)
+	if err != nil {
+		return JSONLintReport{}, err
+	}
+	report := JSONLintReport{Files: len(sources), Problems: []LintProblem{}}
+	report.Root, _ = filepath.Abs(root)
+	for i, r := range parseFiles(sources, opts) {
+		if r.err != nil {
+			continue
+		}
+		name := sources[i]
+		if rel, err := filepath.Rel(root, name); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		report.Problems = append(report.Problems, lintDocument(name, r.doc, readLines(sources[i]), cfg)...)
+	}
+	return report, nil
+}
+
+// lintDocument applies the enabled rules to one document, sorted by line.
+func lintDocument(name string, doc *parser.Document, lines []string, cfg lintConfig) []LintProblem {
+	f := &lintFile{doc: doc, lines: lines}
+	var problems []LintProblem
+	for _, rule := range lintRules {
+		if cfg.Disabled[rule.ID] {
+			continue
+		}
+		rule.check(f, cfg, func(line int, msg string) {
+			problems = append(problems, LintProblem{File: name, Line: line, Rule: rule.ID, Message: msg})
+		})
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// headings returns the document's top-level headings — the ones that form
+// the section tree — in source order.
+func (f *lintFile) headings() []*parser.Heading {
+	var out []*parser.Heading
+	for _, n := range f.doc.Nodes {
+		if h, ok := n.(*parser.Heading); ok {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// walk visits every node in the document.
+func (f *lintFile) walk(fn func(parser.Node) bool) {
+	for _, n := range f.doc.Nodes {
+		parser.Walk(n, fn)
+	}
+}
+
+func lintHeadingIncrement(f *lintFile, _ lintConfig, report func(int, string)) {
+	prev := 0
+	for _, h := range f.headings() {
+		if prev > 0 && h.Level > prev+1 {
+			report(h.LineStart(), fmt.Sprintf("heading level jumps from H%d to H%d", prev, h.Level))
+		}
+		prev = h.Level
+	}
+}
+
+func lintSingleH1(f *lintFile, _ lintConfig, report func(int, string)) {
+	first := 0
+	for _, h := range f.headings() {
+		if h.Level != 1 {
+			continue
+		}
+		if first == 0 {
+			first = h.LineStart()
+			continue
+		}
+		report(h.LineStart(), fmt.Sprintf("multiple H1 headings (first at line %d)", first))
+	}
+}
+
+func lintDuplicateHeading(f *lintFile, _ lintConfig, report func(int, string)) {
+	var check func(siblings []*parser.Section)
+	check = func(siblings []*parser.Section) {
+		seen := map[string]int{}
+		for _, s := range siblings {
+			title := strings.TrimSpace(s.Title)
+			if title != "" {
+				if line, ok := seen[title]; ok {
+					report(s.LineStart, fmt.Sprintf("duplicate heading %q (first at line %d)", title, line))
+				} else {
+					seen[title] = s.LineStart
+				}
+			}
+			check(s.Children)
+		}
+	}
+	check(f.doc.Sections)
+}
+
+func lintEmptyHeading(f *lintFile, _ lintConfig, report func(int, string)) {
+	for _, h := range f.headings() {
+		if strings.TrimSpace(h.Title) == "" {
+			report(h.LineStart(), fmt.Sprintf("empty H%d heading", h.Level))
+		}
+	}
+}
+
+// openCalloutRe matches a callout marker that never reaches its "]".
+var openCalloutRe = regexp.MustCompile(`^\[!([A-Za-z]+)(?:\s|$)`)
+
+func lintUnclosedCallout(f *lintFile, _ lintConfig, report func(int, string)) {
+	// Well-formed markers were already turned into Callout nodes, so only
+	// plain blockquotes can hold a broken one.
+	f.walk(func(n parser.Node) bool {
+		bq, ok := n.(*parser.Blockquote)
+		if !ok || len(bq.Kids) == 0 {
+			return true
+		}
+		if p, ok := bq.Kids[0].(*parser.Paragraph); ok {
+			first := strings.TrimSpace(strings.SplitN(p.Raw, "\n", 2)[0])
+			if m := openCalloutRe.FindStringSubmatch(first); m != nil {
+				report(bq.LineStart(), fmt.Sprintf("callout marker [!%s is missing its closing ]", m[1]))
+			}
+		}
+		return true
+	})
+}
+
+func lintTableColumns(f *lintFile, _ lintConfig, report func(int, string)) {
+	f.walk(func(n parser.Node) bool {
+		t, ok := n.(*parser.Table)
+		if !ok {
+			return true
+		}
+		// The parser pads and truncates rows to the header width, so the
+		// cells are counted from source. The second line is the delimiter row.
+		for l := t.LineStart(); l <= t.LineEnd() && l <= len(f.lines); l++ {
+			if l == t.LineStart()+1 || l < 1 {
+				continue
+			}
+			if cells := countTableCells(f.lines[l-1]); cells != len(t.Headers) {
+				report(l, fmt.Sprintf("table row has %d cell%s, header has %d", cells, plural(cells), len(t.Headers)))
+			}
+		}
+		return false
+	})
+}
+
+// countTableCells counts the cells in a GFM table row, ignoring escaped
+// pipes, pipes inside code spans, and the optional outer pipes.
+func countTableCells(line string) int {
+	row := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), ">"))
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = row[:len(row)-1]
+	}
+	cells := 1
+	inCode := false
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '`':
+			inCode = !inCode
+		case '|':
+			if !inCode {
+				cells++
+			}
+		}
+	}
+	return cells
+}
+
+func lintFenceLanguage(f *lintFile, _ lintConfig, report func(int, string)) {
+	f.walk(func(n parser.Node) bool {
+		cb, ok := n.(*parser.CodeBlock)
+		if !ok || !cb.Fenced || cb.Language != "" {
+			return true
+		}
+		// A code block's range starts at its first line of code; report the
+		// opening fence when it is the line above.
+		line := cb.LineStart()
+		if line > 1 && line-1 <= len(f.lines) && isFenceLine(f.lines[line-2]) {
+			line--
+		}
+		report(line, "code fence has no language")
+		return true
+	})
+}
+
+func isFenceLine(line string) bool {
+	s := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), ">"))
+	return strings.HasPrefix(s, "```") || strings.HasPrefix(s, "~~~")
+}
+
+func lintSectionTokens(f *lintFile, cfg lintConfig, report func(int, string)) {
+	limit := cfg.maxTokens()
+	tok := parser.ActiveTokenizer()
+	for _, s := range f.doc.GetAllSections() {
+		// Only the section's own text: its subsections are checked on their own.
+		if n := tok.Count(s.Content); n > limit {
+			report(s.LineStart, fmt.Sprintf("section %q has %d tokens (max %d)", s.Title, n, limit))
+		}
+	}
+}
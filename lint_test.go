@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+const lintFixture = `# Guide
+
+### Skipped
+
+## Setup
+
+## Setup
+
+##
+
+# Second
+
+> [!WARNING
+> Mind the gap.
+
+> [!TIP]
+> Fine.
+
+| a | b |
+|---|---|
+| 1 | 2 |
+| 1 |
+| 1 | 2 | 3 |
+| ` + "`x|y`" + ` | z \| w |
+
+` + "```" + `
+no language
+` + "```" + `
+
+` + "```go" + `
+fine()
+` + "```" + `
+`
+
+func lintString(t *testing.T, content string, cfg lintConfig) []string {
+	t.Helper()
+	doc := parser.Parse(content)
+	var got []string
+	for _, p := range lintDocument("doc.md", doc, strings.Split(content, "\n"), cfg) {
+		got = append(got, p.String())
+	}
+	return got
+}
+
+func TestLintRules(t *testing.T) {
+	got := lintString(t, lintFixture, lintConfig{})
+	want := []string{
+		"doc.md:3: heading-increment: heading level jumps from H1 to H3",
+		"doc.md:7: duplicate-heading: duplicate heading \"Setup\" (first at line 5)",
+		"doc.md:9: empty-heading: empty H2 heading",
+		"doc.md:11: single-h1: multiple H1 headings (first at line 1)",
+		"doc.md:13: unclosed-callout: callout marker [!WARNING is missing its closing ]",
+		"doc.md:22: table-columns: table row has 1 cell, header has 2",
+		"doc.md:23: table-columns: table row has 3 cells, header has 2",
+		"doc.md:26: fence-language: code fence has no language",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintDisableAndThreshold(t *testing.T) {
+	cfg := lintConfig{Disabled: map[string]bool{}, MaxTokens: 5}
+	for _, r := range lintRules {
+		if r.ID != "section-tokens" {
+			cfg.Disabled[r.ID] = true
+		}
+	}
+	got := lintString(t, "# Short\n\nok\n\n## Long\n\n"+strings.Repeat("words ", 20)+"\n", cfg)
+	if len(got) != 1 || !strings.HasPrefix(got[0], `doc.md:5: section-tokens: section "Long" has`) {
+		t.Errorf("expected only the long section, got %v", got)
+	}
+
+	if got := lintString(t, lintFixture, lintConfig{Disabled: map[string]bool{"fence-language": true, "table-columns": true}}); len(got) != 5 {
+		t.Errorf("expected 5 problems with two rules disabled, got %v", got)
+	}
+}
+
+func TestLintTargetSARIF(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "a.md"), []byte("# A\n\n#### Deep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.yaml"), []byte("key: value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := lintTarget(dir, lintConfig{}, parseOptions{Jobs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 1 || len(report.Problems) != 1 || report.Problems[0].File != "docs/a.md" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	data, err := json.Marshal(report.sarif(lintConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected SARIF: %s", data)
+	}
+	run := log.Runs[0]
+	res := run.Results[0]
+	loc := res.Locations[0].PhysicalLocation
+	if res.RuleID != "heading-increment" || run.Tool.Driver.Rules[res.RuleIndex].ID != res.RuleID {
+		t.Errorf("result rule = %s (index %d)", res.RuleID, res.RuleIndex)
+	}
+	if loc.ArtifactLocation.URI != "docs/a.md" || loc.Region.StartLine != 3 {
+		t.Errorf("location = %s:%d", loc.ArtifactLocation.URI, loc.Region.StartLine)
+	}
+
+	// Linting a subdirectory of a repository still reports repo-relative
+	// paths, resolved against the %SRCROOT% base.
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	report, err = lintTarget(filepath.Join(dir, "docs"), lintConfig{}, parseOptions{Jobs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := report.sarif(lintConfig{}).Runs[0]
+	artifact := sub.Results[0].Locations[0].PhysicalLocation.ArtifactLocation
+	if report.Problems[0].File != "a.md" || artifact.URI != "docs/a.md" || artifact.URIBaseID != sarifSrcRoot {
+		t.Errorf("subdirectory lint: file %q, artifact %+v", report.Problems[0].File, artifact)
+	}
+	if base := sub.OriginalURIBaseIDs[sarifSrcRoot].URI; base != pathToURI(dir)+"/" {
+		t.Errorf("%%SRCROOT%% = %q, want the repository root", base)
+	}
+}
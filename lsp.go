@@ -0,0 +1,858 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+	"github.com/JordanCoin/docmap/render"
+)
+
+// docmap lsp is a Language Server Protocol server on stdio. Messages use the
+// same JSON-RPC envelopes as docmap mcp, framed with Content-Length headers.
+// The workspace is parsed with the same watchState that drives --watch, so
+// files on disk are re-parsed only when their size or mtime changes; files
+// open in the editor are parsed from the buffer instead.
+
+// LSP SymbolKind values used for sections.
+const (
+	lspSymbolNamespace = 3
+	lspSymbolString    = 15
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspSymbolInformation struct {
+	Name          string      `json:"name"`
+	Kind          int         `json:"kind"`
+	Location      lspLocation `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    *lspRange        `json:"range,omitempty"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+// lspParams is the union of the request params the server reads.
+type lspParams struct {
+	RootURI          string            `json:"rootUri"`
+	RootPath         string            `json:"rootPath"`
+	WorkspaceFolders []lspTextDocument `json:"workspaceFolders"`
+	TextDocument     lspTextDocument   `json:"textDocument"`
+	ContentChanges   []lspTextDocument `json:"contentChanges"`
+	Position         lspPosition       `json:"position"`
+	Query            string            `json:"query"`
+	Context          struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+// lspBuffer is a file open in the editor.
+type lspBuffer struct {
+	text string
+	doc  *parser.Document
+}
+
+// lspServer answers LSP requests for one workspace root.
+type lspServer struct {
+	opts parseOptions
+	root string
+	ws   *watchState
+	open map[string]*lspBuffer // keyed by absolute path
+}
+
+// runLSP implements `docmap lsp`.
+func runLSP(opts parseOptions) {
+	srv := &lspServer{opts: opts}
+	if err := srv.serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve reads framed requests from r until exit or EOF.
+func (s *lspServer) serve(r io.Reader, w io.Writer) error {
+	in := bufio.NewReader(r)
+	for {
+		body, err := readLSPMessage(in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeLSPMessage(w, rpcErrorResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(req)
+		if len(req.ID) == 0 {
+			continue
+		}
+		var resp any = rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if rerr != nil {
+			resp = rpcErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+		}
+		if err := writeLSPMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// readLSPMessage reads one Content-Length framed message body.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func writeLSPMessage(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+func (s *lspServer) handle(req rpcRequest) (any, *rpcError) {
+	var params lspParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+	path := uriToPath(params.TextDocument.URI)
+
+	switch req.Method {
+	case "initialize":
+		root := params.RootPath
+		if len(params.WorkspaceFolders) > 0 {
+			root = uriToPath(params.WorkspaceFolders[0].URI)
+		} else if params.RootURI != "" {
+			root = uriToPath(params.RootURI)
+		}
+		s.setRoot(root)
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":        map[string]any{"openClose": true, "change": 1},
+				"documentSymbolProvider":  true,
+				"workspaceSymbolProvider": true,
+				"definitionProvider":      true,
+				"referencesProvider":      true,
+				"hoverProvider":           true,
+			},
+			"serverInfo": map[string]any{"name": "docmap", "version": version},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		s.setBuffer(path, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		// Full sync: the last change holds the whole document.
+		if n := len(params.ContentChanges); n > 0 {
+			s.setBuffer(path, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		delete(s.open, path)
+		return nil, nil
+	case "textDocument/documentSymbol":
+		doc := s.document(path)
+		if doc == nil {
+			return []lspDocumentSymbol{}, nil
+		}
+		return documentSymbols(doc.Sections), nil
+	case "workspace/symbol":
+		return s.workspaceSymbols(params.Query), nil
+	case "textDocument/definition":
+		t, ok := s.targetAt(path, params.Position)
+		if !ok {
+			return nil, nil
+		}
+		return t.location(), nil
+	case "textDocument/references":
+		return s.references(path, params.Position, params.Context.IncludeDeclaration), nil
+	case "textDocument/hover":
+		return s.hover(path, params.Position), nil
+	}
+	if len(req.ID) == 0 {
+		// initialized, didSave, $/cancelRequest and friends need no action.
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func (s *lspServer) setRoot(root string) {
+	if root == "" {
+		root = "."
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	s.root = root
+	s.ws = newWatchState(root, true, s.opts)
+}
+
+func (s *lspServer) setBuffer(path, text string) {
+	if s.open == nil {
+		s.open = map[string]*lspBuffer{}
+	}
+	doc, err := parseContent(s.opts.Files.kind(path), text)
+	if err != nil {
+		// Keep answering from the last good parse while the buffer is
+		// mid-edit and temporarily invalid.
+		if old := s.open[path]; old != nil {
+			old.text = text
+			return
+		}
+		doc = &parser.Document{}
+	}
+	doc.Filename = s.displayName(path)
+	s.open[path] = &lspBuffer{text: text, doc: doc}
+}
+
+func (s *lspServer) displayName(path string) string {
+	if rel, err := filepath.Rel(s.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filepath.Base(path)
+}
+
+// lspFile is one parsed workspace file.
+type lspFile struct {
+	path string
+	doc  *parser.Document
+}
+
+// files returns every workspace file in walk order, preferring editor
+// buffers, followed by open buffers that live outside the workspace.
+func (s *lspServer) files() []lspFile {
+	if s.ws == nil {
+		s.setRoot(".")
+	}
+	s.ws.refresh()
+	var out []lspFile
+	seen := map[string]bool{}
+	for _, p := range s.ws.order {
+		seen[p] = true
+		if b := s.open[p]; b != nil {
+			out = append(out, lspFile{p, b.doc})
+		} else if doc := s.ws.docs[p]; doc != nil {
+			out = append(out, lspFile{p, doc})
+		}
+	}
+	var extra []string
+	for p := range s.open {
+		if !seen[p] {
+			extra = append(extra, p)
+		}
+	}
+	sort.Strings(extra)
+	for _, p := range extra {
+		out = append(out, lspFile{p, s.open[p].doc})
+	}
+	return out
+}
+
+// document returns the parsed document for path, or nil if it can't be read.
+func (s *lspServer) document(path string) *parser.Document {
+	if b := s.open[path]; b != nil {
+		return b.doc
+	}
+	if s.ws != nil {
+		if doc := s.ws.docs[path]; doc != nil {
+			if st, ok := s.ws.stamps[path]; ok && st == statStamp(path) {
+				return doc
+			}
+		}
+	}
+	doc, err := s.opts.parse(path)
+	if err != nil {
+		return nil
+	}
+	doc.Filename = s.displayName(path)
+	return doc
+}
+
+// statStamp returns path's current stamp, zero if it can't be stat'd.
+func statStamp(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+}
+
+// lines returns path's text split into lines, from the buffer when open.
+func (s *lspServer) lines(path string) []string {
+	if b := s.open[path]; b != nil {
+		return strings.Split(b.text, "\n")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
+
+func (s *lspServer) lineText(path string, line int) string {
+	lines := s.lines(path)
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[line], "\r")
+}
+
+// ---------- Symbols ----------
+
+// documentSymbols mirrors the Section tree as an outline.
+func documentSymbols(sections []*parser.Section) []lspDocumentSymbol {
+	out := []lspDocumentSymbol{}
+	for _, sec := range sections {
+		start := max(sec.LineStart-1, 0)
+		end := max(sec.LineEnd-1, start)
+		kind := lspSymbolString
+		if sec.Level <= 1 {
+			kind = lspSymbolNamespace
+		}
+		sym := lspDocumentSymbol{
+			Name:           symbolName(sec.Title),
+			Detail:         fmt.Sprintf("~%d tokens", sec.Tokens),
+			Kind:           kind,
+			Range:          lspRange{Start: lspPosition{Line: start}, End: lspPosition{Line: end + 1}},
+			SelectionRange: lspRange{Start: lspPosition{Line: start}, End: lspPosition{Line: start + 1}},
+		}
+		if len(sec.Children) > 0 {
+			sym.Children = documentSymbols(sec.Children)
+		}
+		out = append(out, sym)
+	}
+	return out
+}
+
+// symbolName keeps empty headings visible; clients reject empty names.
+func symbolName(title string) string {
+	if strings.TrimSpace(title) == "" {
+		return "(empty heading)"
+	}
+	return title
+}
+
+// lspMaxWorkspaceSymbols caps workspace/symbol results for huge trees.
+const lspMaxWorkspaceSymbols = 500
+
+// workspaceSymbols lists sections across the workspace whose title contains
+// query (case-insensitive).
+func (s *lspServer) workspaceSymbols(query string) []lspSymbolInformation {
+	query = strings.ToLower(query)
+	out := []lspSymbolInformation{}
+	for _, f := range s.files() {
+		for _, sec := range f.doc.GetAllSections() {
+			if sec.IsPreamble() || !strings.Contains(strings.ToLower(sec.Title), query) {
+				continue
+			}
+			container := f.doc.Filename
+			if sec.Parent != nil {
+				container += " > " + render.Breadcrumb(sec.Parent)
+			}
+			out = append(out, lspSymbolInformation{
+				Name:          symbolName(sec.Title),
+				Kind:          lspSymbolString,
+				Location:      lspTarget{path: f.path, line: sec.LineStart}.location(),
+				ContainerName: container,
+			})
+			if len(out) == lspMaxWorkspaceSymbols {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// ---------- Links ----------
+
+// lspLink is a link-like span on one line of source. Offsets are bytes.
+type lspLink struct {
+	kind   string // "wiki", "url", "footnote", or "label"
+	target string // wiki page, URL, footnote id, or reference label
+	anchor string // wiki #heading
+	start  int
+	end    int
+}
+
+var (
+	lspWikiRe       = regexp.MustCompile(`!?\[\[([^\]|#]*)(?:#\^?([^\]|]*))?(?:\|[^\]]*)?\]\]`)
+	lspFootnoteRe   = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	lspInlineLinkRe = regexp.MustCompile(`!?\[[^\]]*\]\(<?([^)\s>]*)>?(?:\s+"[^"]*")?\)`)
+	lspRefLinkRe    = regexp.MustCompile(`\[([^\]]+)\]\[([^\]]*)\]`)
+	lspShortcutRe   = regexp.MustCompile(`\[([^\]^][^\]]*)\]`)
+)
+
+// linksOnLine finds every link-like span in line. Spans matched by an
+// earlier pattern hide later matches that overlap them, so the [text] of
+// an inline link is never also reported as a shortcut reference.
+func linksOnLine(line string) []lspLink {
+	var out []lspLink
+	taken := func(start, end int) bool {
+		for _, l := range out {
+			if start < l.end && end > l.start {
+				return true
+			}
+		}
+		return false
+	}
+	for _, m := range lspWikiRe.FindAllStringSubmatchIndex(line, -1) {
+		l := lspLink{kind: "wiki", target: strings.TrimSpace(line[m[2]:m[3]]), start: m[0], end: m[1]}
+		if m[4] >= 0 {
+			l.anchor = strings.TrimSpace(line[m[4]:m[5]])
+		}
+		out = append(out, l)
+	}
+	for _, m := range lspFootnoteRe.FindAllStringSubmatchIndex(line, -1) {
+		if !taken(m[0], m[1]) {
+			out = append(out, lspLink{kind: "footnote", target: line[m[2]:m[3]], start: m[0], end: m[1]})
+		}
+	}
+	for _, m := range lspInlineLinkRe.FindAllStringSubmatchIndex(line, -1) {
+		if !taken(m[0], m[1]) {
+			out = append(out, lspLink{kind: "url", target: line[m[2]:m[3]], start: m[0], end: m[1]})
+		}
+	}
+	for _, m := range lspRefLinkRe.FindAllStringSubmatchIndex(line, -1) {
+		if taken(m[0], m[1]) {
+			continue
+		}
+		label := line[m[4]:m[5]]
+		if label == "" {
+			label = line[m[2]:m[3]]
+		}
+		out = append(out, lspLink{kind: "label", target: label, start: m[0], end: m[1]})
+	}
+	for _, m := range lspShortcutRe.FindAllStringSubmatchIndex(line, -1) {
+		if !taken(m[0], m[1]) {
+			out = append(out, lspLink{kind: "label", target: line[m[2]:m[3]], start: m[0], end: m[1]})
+		}
+	}
+	return out
+}
+
+// linkAt returns the link under the byte offset col.
+func linkAt(line string, col int) (lspLink, bool) {
+	for _, l := range linksOnLine(line) {
+		if col >= l.start && col < l.end {
+			return l, true
+		}
+	}
+	return lspLink{}, false
+}
+
+// linkLines returns the lines of doc that hold links or wiki links
+// according to the AST, so scans skip look-alikes inside code blocks.
+func linkLines(doc *parser.Document) map[int]bool {
+	lines := map[int]bool{}
+	for _, root := range doc.Nodes {
+		parser.Walk(root, func(n parser.Node) bool {
+			switch n.Kind() {
+			case parser.KindCodeBlock, parser.KindMathBlock, parser.KindHTMLBlock:
+				return false
+			case parser.KindLink, parser.KindWikiLink, parser.KindWikiEmbed, parser.KindFootnoteRef:
+				for l := n.LineStart(); l <= n.LineEnd(); l++ {
+					lines[l] = true
+				}
+			}
+			return true
+		})
+	}
+	return lines
+}
+
+// normalizeLabel applies CommonMark's case- and whitespace-insensitive
+// matching for reference labels.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// lspTarget is where a link points: a file, and a section or line in it.
+type lspTarget struct {
+	path    string
+	section *parser.Section
+	line    int // 1-indexed; used when section is nil
+}
+
+func (t lspTarget) location() lspLocation {
+	line := t.line
+	if t.section != nil {
+		line = t.section.LineStart
+	}
+	pos := lspPosition{Line: max(line-1, 0)}
+	return lspLocation{URI: pathToURI(t.path), Range: lspRange{Start: pos, End: pos}}
+}
+
+// same reports whether t points at want. A file-level want matches links
+// to any section of that file.
+func (t lspTarget) same(want lspTarget) bool {
+	if t.path != want.path {
+		return false
+	}
+	if want.section == nil {
+		return true
+	}
+	return t.section != nil && t.section.LineStart == want.section.LineStart
+}
+
+// targetAt resolves the link under the cursor.
+func (s *lspServer) targetAt(path string, pos lspPosition) (lspTarget, bool) {
+	line := s.lineText(path, pos.Line)
+	link, ok := linkAt(line, byteOffset(line, pos.Character))
+	if !ok {
+		return lspTarget{}, false
+	}
+	return s.resolve(path, link, nil)
+}
+
+// resolve finds the target of link found in the file at path. pages is a
+// snapshot of the workspace's paths for wiki links (see wikiPages); nil
+// takes a fresh one when needed.
+func (s *lspServer) resolve(path string, link lspLink, pages []string) (lspTarget, bool) {
+	switch link.kind {
+	case "wiki":
+		target := path
+		if link.target != "" {
+			if pages == nil {
+				pages = wikiPages(s.files())
+			}
+			if target = resolveWikiPage(link.target, s.root, pages); target == "" {
+				return lspTarget{}, false
+			}
+		}
+		t := lspTarget{path: target}
+		if link.anchor != "" {
+			if doc := s.document(target); doc != nil {
+				t.section = findHeading(doc, link.anchor)
+			}
+		}
+		return t, true
+
+	case "url":
+		if isExternalLink(link.target) {
+			return lspTarget{}, false
+		}
+		target, anchor := resolveLinkPath(path, s.root, link.target)
+		if target != path {
+			if _, err := os.Stat(target); err != nil && s.open[target] == nil {
+				return lspTarget{}, false
+			}
+		}
+		t := lspTarget{path: target}
+		if anchor != "" && s.opts.Files.kind(target) != "" {
+			if doc := s.document(target); doc != nil {
+				t.section = doc.SectionByAnchor(anchor)
+			}
+		}
+		return t, true
+
+	case "footnote", "label":
+		doc := s.document(path)
+		if doc == nil {
+			return lspTarget{}, false
+		}
+		line := definitionLine(doc, link)
+		if line == 0 {
+			return lspTarget{}, false
+		}
+		return lspTarget{path: path, line: line}, true
+	}
+	return lspTarget{}, false
+}
+
+// definitionLine finds the footnote or link reference definition link uses.
+func definitionLine(doc *parser.Document, link lspLink) int {
+	line := 0
+	for _, root := range doc.Nodes {
+		parser.Walk(root, func(n parser.Node) bool {
+			switch v := n.(type) {
+			case *parser.FootnoteDef:
+				if link.kind == "footnote" && v.ID == link.target && line == 0 {
+					line = v.LineStart()
+				}
+			case *parser.LinkRefDef:
+				if link.kind == "label" && normalizeLabel(v.Label) == normalizeLabel(link.target) && line == 0 {
+					line = v.LineStart()
+				}
+			}
+			return line == 0
+		})
+	}
+	return line
+}
+
+// wikiPages lists the paths of files, the candidates for Obsidian-style
+// page names. Requests that resolve many links take one snapshot up front
+// rather than walking the workspace per link.
+func wikiPages(files []lspFile) []string {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	return paths
+}
+
+// ---------- References ----------
+
+// references lists the places that link to whatever is under the cursor:
+// the link's target, the heading on the cursor's line, or the file itself.
+// Footnotes and reference labels list their uses within the file.
+func (s *lspServer) references(path string, pos lspPosition, includeDecl bool) []lspLocation {
+	line := s.lineText(path, pos.Line)
+	link, onLink := linkAt(line, byteOffset(line, pos.Character))
+	if onLink && (link.kind == "footnote" || link.kind == "label") {
+		return s.localReferences(path, link, includeDecl)
+	}
+
+	files := s.files()
+	pages := wikiPages(files)
+	want := lspTarget{path: path}
+	if onLink {
+		t, ok := s.resolve(path, link, pages)
+		if !ok {
+			return []lspLocation{}
+		}
+		want = t
+	} else if doc := s.document(path); doc != nil {
+		for _, sec := range doc.GetAllSections() {
+			if sec.LineStart == pos.Line+1 && !sec.IsPreamble() {
+				want.section = sec
+				break
+			}
+		}
+	}
+
+	out := []lspLocation{}
+	if includeDecl {
+		out = append(out, want.location())
+	}
+	for _, f := range files {
+		lines := s.lines(f.path)
+		candidates := linkLines(f.doc)
+		for i, text := range lines {
+			if !candidates[i+1] {
+				continue
+			}
+			text = strings.TrimRight(text, "\r")
+			for _, l := range linksOnLine(text) {
+				if l.kind != "wiki" && l.kind != "url" {
+					continue
+				}
+				if t, ok := s.resolve(f.path, l, pages); ok && t.same(want) {
+					out = append(out, spanLocation(f.path, i, text, l))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// localReferences finds every use of a footnote or reference label in path.
+func (s *lspServer) localReferences(path string, link lspLink, includeDecl bool) []lspLocation {
+	out := []lspLocation{}
+	doc := s.document(path)
+	if doc == nil {
+		return out
+	}
+	decl := definitionLine(doc, link)
+	for i, text := range s.lines(path) {
+		if i+1 == decl && !includeDecl {
+			continue
+		}
+		text = strings.TrimRight(text, "\r")
+		for _, l := range linksOnLine(text) {
+			if l.kind == link.kind && normalizeLabel(l.target) == normalizeLabel(link.target) {
+				out = append(out, spanLocation(path, i, text, l))
+			}
+		}
+	}
+	return out
+}
+
+func spanLocation(path string, line int, text string, l lspLink) lspLocation {
+	return lspLocation{URI: pathToURI(path), Range: lspRange{
+		Start: lspPosition{Line: line, Character: utf16Len(text[:l.start])},
+		End:   lspPosition{Line: line, Character: utf16Len(text[:l.end])},
+	}}
+}
+
+// ---------- Hover ----------
+
+// hover describes the link target under the cursor, or else the section
+// enclosing it, with token counts.
+func (s *lspServer) hover(path string, pos lspPosition) any {
+	if t, ok := s.targetAt(path, pos); ok {
+		if doc := s.document(t.path); doc != nil {
+			return markdownHover(targetSummary(doc, t))
+		}
+		return nil
+	}
+	doc := s.document(path)
+	if doc == nil {
+		return nil
+	}
+	sec := doc.SectionAt(pos.Line + 1)
+	if sec == nil {
+		return markdownHover(fmt.Sprintf("**%s**\n\n~%d tokens · %d sections",
+			doc.Filename, doc.TotalTokens, len(doc.GetAllSections())))
+	}
+	return markdownHover(sectionSummary(sec))
+}
+
+func markdownHover(text string) lspHover {
+	return lspHover{Contents: lspMarkupContent{Kind: "markdown", Value: text}}
+}
+
+func targetSummary(doc *parser.Document, t lspTarget) string {
+	switch {
+	case t.section != nil:
+		return fmt.Sprintf("**%s** › %s", doc.Filename, sectionSummary(t.section))
+	case t.line > 0:
+		sec := doc.SectionAt(t.line)
+		where := fmt.Sprintf("line %d", t.line)
+		if sec != nil {
+			where += " in " + render.Breadcrumb(sec)
+		}
+		return fmt.Sprintf("Defined at %s", where)
+	}
+	return fmt.Sprintf("**%s**\n\n~%d tokens · %d sections",
+		doc.Filename, doc.TotalTokens, len(doc.GetAllSections()))
+}
+
+// sectionSummary reports a section's total tokens and, when it has
+// subsections, how they split between its own content and its children.
+func sectionSummary(sec *parser.Section) string {
+	own := sec.Tokens
+	for _, c := range sec.Children {
+		own -= c.Tokens
+	}
+	text := fmt.Sprintf("**%s**\n\n~%d tokens · lines %d–%d", render.Breadcrumb(sec), sec.Tokens, sec.LineStart, sec.LineEnd)
+	if len(sec.Children) > 0 {
+		text += fmt.Sprintf(" · %d own, %d in %d subsection%s", own, sec.Tokens-own, len(sec.Children), plural(len(sec.Children)))
+	}
+	return text
+}
+
+// ---------- Positions and URIs ----------
+
+// utf16Len counts UTF-16 code units, the unit LSP positions are measured in.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// byteOffset converts a UTF-16 character offset within line to bytes.
+func byteOffset(line string, char int) int {
+	n := 0
+	for i, r := range line {
+		if n >= char {
+			return i
+		}
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return len(line)
+}
+
+func uriToPath(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLSPWorkspace creates a small linked markdown workspace.
+func writeLSPWorkspace(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide.md": "# Guide\n\nIntro with a note[^1] and [the api](api.md#auth-tokens).\n\n" +
+			"## Setup\n\nSee [[api#Auth Tokens]] and [docs][ref].\n\n" +
+			"```md\n[not a link](api.md)\n```\n\n" +
+			"[^1]: The footnote.\n[ref]: api.md\n",
+		"api.md":        "# API\n\n## Auth Tokens\n\nTokens 🔑 expire. Back to [guide](guide.md).\n",
+		"notes/todo.md": "# Todo\n\n- read [[guide]]\n- read [setup](../guide.md#setup)\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// lspSession frames requests, runs a fresh server over them, and returns
+// the responses keyed by id.
+func lspSession(t *testing.T, requests ...map[string]any) map[int]json.RawMessage {
+	t.Helper()
+	var in bytes.Buffer
+	for _, req := range requests {
+		req["jsonrpc"] = "2.0"
+		if err := writeLSPMessage(&in, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	srv := &lspServer{opts: parseOptions{Jobs: 1}}
+	if err := srv.serve(&in, &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	responses := map[int]json.RawMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readLSPMessage(r)
+		if err != nil {
+			break
+		}
+		var resp struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("bad response %s: %v", body, err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("request %d failed: %s", resp.ID, resp.Error.Message)
+		}
+		responses[resp.ID] = resp.Result
+	}
+	return responses
+}
+
+func lspInit(dir string) map[string]any {
+	return map[string]any{"id": 0, "method": "initialize", "params": map[string]any{"rootUri": pathToURI(dir)}}
+}
+
+func lspAt(id int, method, path string, line, char int) map[string]any {
+	return map[string]any{"id": id, "method": method, "params": map[string]any{
+		"textDocument": map[string]any{"uri": pathToURI(path)},
+		"position":     map[string]any{"line": line, "character": char},
+		"context":      map[string]any{"includeDeclaration": false},
+	}}
+}
+
+func decode[T any](t *testing.T, raw json.RawMessage) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("decoding %s: %v", raw, err)
+	}
+	return v
+}
+
+func TestLSPSymbols(t *testing.T) {
+	dir := writeLSPWorkspace(t)
+	guide := filepath.Join(dir, "guide.md")
+	responses := lspSession(t,
+		lspInit(dir),
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"id": 1, "method": "textDocument/documentSymbol", "params": map[string]any{
+			"textDocument": map[string]any{"uri": pathToURI(guide)}}},
+		map[string]any{"id": 2, "method": "workspace/symbol", "params": map[string]any{"query": "auth"}},
+		map[string]any{"id": 3, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+
+	symbols := decode[[]lspDocumentSymbol](t, responses[1])
+	if len(symbols) != 1 || symbols[0].Name != "Guide" || len(symbols[0].Children) != 1 {
+		t.Fatalf("unexpected outline: %+v", symbols)
+	}
+	if setup := symbols[0].Children[0]; setup.Name != "Setup" || setup.SelectionRange.Start.Line != 4 {
+		t.Errorf("expected Setup at line 4, got %+v", setup)
+	}
+
+	found := decode[[]lspSymbolInformation](t, responses[2])
+	if len(found) != 1 || found[0].Name != "Auth Tokens" || found[0].ContainerName != "api.md > API" {
+		t.Errorf("unexpected workspace symbols: %+v", found)
+	}
+	if string(responses[3]) != "null" {
+		t.Errorf("shutdown should return null, got %s", responses[3])
+	}
+}
+
+func TestLSPDefinition(t *testing.T) {
+	dir := writeLSPWorkspace(t)
+	guide := filepath.Join(dir, "guide.md")
+	todo := filepath.Join(dir, "notes", "todo.md")
+	responses := lspSession(t,
+		lspInit(dir),
+		lspAt(1, "textDocument/definition", guide, 2, 30), // [the api](api.md#auth-tokens)
+		lspAt(2, "textDocument/definition", guide, 6, 8),  // [[api#Auth Tokens]]
+		lspAt(3, "textDocument/definition", guide, 2, 18), // [^1]
+		lspAt(4, "textDocument/definition", guide, 6, 31), // [docs][ref]
+		lspAt(5, "textDocument/definition", todo, 2, 10),  // [[guide]]
+		lspAt(6, "textDocument/definition", guide, 0, 2),  // plain heading text
+	)
+
+	cases := []struct {
+		id   int
+		file string
+		line int
+	}{
+		{1, "api.md", 2},
+		{2, "api.md", 2},
+		{3, "guide.md", 12},
+		{4, "guide.md", 13},
+		{5, "guide.md", 0},
+	}
+	for _, c := range cases {
+		loc := decode[lspLocation](t, responses[c.id])
+		if loc.URI != pathToURI(filepath.Join(dir, c.file)) || loc.Range.Start.Line != c.line {
+			t.Errorf("definition %d: expected %s:%d, got %s:%d", c.id, c.file, c.line, loc.URI, loc.Range.Start.Line)
+		}
+	}
+	if string(responses[6]) != "null" {
+		t.Errorf("expected no definition off a link, got %s", responses[6])
+	}
+}
+
+func TestLSPReferences(t *testing.T) {
+	dir := writeLSPWorkspace(t)
+	guide := filepath.Join(dir, "guide.md")
+	api := filepath.Join(dir, "api.md")
+	responses := lspSession(t,
+		lspInit(dir),
+		lspAt(1, "textDocument/references", api, 2, 0),    // heading "Auth Tokens"
+		lspAt(2, "textDocument/references", guide, 0, 0),  // heading "Guide"
+		lspAt(3, "textDocument/references", guide, 2, 0),  // body text: backlinks to the file
+		lspAt(4, "textDocument/references", guide, 2, 18), // [^1]
+	)
+
+	// Two links point at api.md#auth-tokens; the one inside the code
+	// block must not count.
+	if refs := decode[[]lspLocation](t, responses[1]); len(refs) != 2 {
+		t.Errorf("expected 2 references to Auth Tokens, got %+v", refs)
+	}
+	// Links to guide.md without an anchor target the file, not the Guide
+	// heading; from body text, every link into guide.md is a backlink.
+	if refs := decode[[]lspLocation](t, responses[2]); len(refs) != 0 {
+		t.Errorf("expected no anchored references to Guide, got %+v", refs)
+	}
+	backlinks := decode[[]lspLocation](t, responses[3])
+	if len(backlinks) != 3 {
+		t.Fatalf("expected 3 backlinks to guide.md, got %+v", backlinks)
+	}
+	for _, loc := range backlinks {
+		if loc.URI == pathToURI(api) && (loc.Range.Start.Character != 26 || loc.Range.End.Character != 43) {
+			t.Errorf("expected UTF-16 span 26-43 on api.md link, got %+v", loc.Range)
+		}
+	}
+	// The footnote is used once; the definition is excluded.
+	if refs := decode[[]lspLocation](t, responses[4]); len(refs) != 1 {
+		t.Errorf("expected 1 footnote use, got %+v", refs)
+	}
+}
+
+func TestLSPHoverAndBuffers(t *testing.T) {
+	dir := writeLSPWorkspace(t)
+	guide := filepath.Join(dir, "guide.md")
+	edited := "# Guide\n\n## Renamed\n\nBody text here.\n"
+	responses := lspSession(t,
+		lspInit(dir),
+		lspAt(1, "textDocument/hover", guide, 4, 3),
+		lspAt(2, "textDocument/hover", guide, 2, 30),
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": pathToURI(guide), "text": edited}}},
+		map[string]any{"id": 3, "method": "workspace/symbol", "params": map[string]any{"query": "renamed"}},
+		lspAt(4, "textDocument/hover", guide, 4, 0),
+	)
+
+	hover := decode[lspHover](t, responses[1])
+	if !strings.Contains(hover.Contents.Value, "Guide > Setup") || !strings.Contains(hover.Contents.Value, "tokens") {
+		t.Errorf("unexpected section hover: %q", hover.Contents.Value)
+	}
+	hover = decode[lspHover](t, responses[2])
+	if !strings.HasPrefix(hover.Contents.Value, "**api.md** › **API > Auth Tokens**") {
+		t.Errorf("unexpected link hover: %q", hover.Contents.Value)
+	}
+	if found := decode[[]lspSymbolInformation](t, responses[3]); len(found) != 1 {
+		t.Errorf("expected open buffer to shadow disk, got %+v", found)
+	}
+	hover = decode[lspHover](t, responses[4])
+	if !strings.Contains(hover.Contents.Value, "Guide > Renamed") {
+		t.Errorf("hover should use the buffer, got %q", hover.Contents.Value)
+	}
+}
+
+func TestLinksOnLine(t *testing.T) {
+	line := "A [[Page|alias]], [x](a.md \"t\"), [y][lbl], [short], and [^note]."
+	var got []string
+	for _, l := range linksOnLine(line) {
+		got = append(got, fmt.Sprintf("%s:%s", l.kind, l.target))
+	}
+	want := "wiki:Page footnote:note url:a.md label:lbl label:short"
+	if strings.Join(got, " ") != want {
+		t.Errorf("linksOnLine = %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -60,6 +60,14 @@ type JSONSummary struct {
 	IssueRefs    int `json:"issue_refs,omitempty"`
 	CommitRefs   int `json:"commit_refs,omitempty"`
 	Emojis       int `json:"emojis,omitempty"`
+	Operations   int `json:"operations,omitempty"`
+	Anchors      int `json:"anchors,omitempty"`
+	Aliases      int `json:"aliases,omitempty"`
+	Resources    int `json:"resources,omitempty"`
+	Containers   int `json:"containers,omitempty"`
+	Services     int `json:"services,omitempty"`
+	Jobs         int `json:"jobs,omitempty"`
+	Steps        int `json:"steps,omitempty"`
 }
 
 type JSONSection struct {
@@ -77,26 +85,59 @@ type JSONSection struct {
 // the node type (e.g. "code_block", "callout"); remaining fields are
 // populated per kind. Agents can switch on Kind to deserialize.
 type JSONNode struct {
-	Kind      string     `json:"kind"`
-	LineStart int        `json:"line_start,omitempty"`
-	LineEnd   int        `json:"line_end,omitempty"`
-	Tokens    int        `json:"tokens,omitempty"`
-	Title     string     `json:"title,omitempty"`    // Heading
-	Level     int        `json:"level,omitempty"`    // Heading
-	Language  string     `json:"language,omitempty"` // CodeBlock
-	Code      string     `json:"code,omitempty"`     // CodeBlock
-	Variant   string     `json:"variant,omitempty"`  // Callout
-	Headers   []string   `json:"headers,omitempty"`  // Table
-	Aligns    []string   `json:"aligns,omitempty"`   // Table
-	TeX       string     `json:"tex,omitempty"`      // MathBlock / InlineMath
-	ID        string     `json:"id,omitempty"`       // FootnoteDef
-	Label     string     `json:"label,omitempty"`    // LinkRefDef
-	URL       string     `json:"url,omitempty"`      // LinkRefDef / Link
-	Checked   *bool      `json:"checked,omitempty"`  // TaskItem
-	Raw       string     `json:"raw,omitempty"`      // HTMLBlock / Frontmatter
-	Format    string     `json:"format,omitempty"`   // Frontmatter
-	Target    string     `json:"target,omitempty"`   // WikiLink / WikiEmbed
-	Children  []JSONNode `json:"children,omitempty"`
+	Kind      string           `json:"kind"`
+	LineStart int              `json:"line_start,omitempty"`
+	LineEnd   int              `json:"line_end,omitempty"`
+	Tokens    int              `json:"tokens,omitempty"`
+	Title     string           `json:"title,omitempty"`       // Heading / Operation
+	Level     int              `json:"level,omitempty"`       // Heading
+	Language  string           `json:"language,omitempty"`    // CodeBlock
+	Code      string           `json:"code,omitempty"`        // CodeBlock
+	Variant   string           `json:"variant,omitempty"`     // Callout
+	Headers   []string         `json:"headers,omitempty"`     // Table
+	Aligns    []string         `json:"aligns,omitempty"`      // Table
+	TeX       string           `json:"tex,omitempty"`         // MathBlock / InlineMath
+	ID        string           `json:"id,omitempty"`          // FootnoteDef
+	Label     string           `json:"label,omitempty"`       // LinkRefDef
+	URL       string           `json:"url,omitempty"`         // LinkRefDef / Link
+	Checked   *bool            `json:"checked,omitempty"`     // TaskItem
+	Raw       string           `json:"raw,omitempty"`         // HTMLBlock / Frontmatter
+	Format    string           `json:"format,omitempty"`      // Frontmatter
+	Fields    map[string]any   `json:"fields,omitempty"`      // Frontmatter, decoded
+	Target    string           `json:"target,omitempty"`      // WikiLink / WikiEmbed
+	Cell      int              `json:"cell,omitempty"`        // CodeBlock in a notebook
+	Outputs   *JSONCellOutputs `json:"outputs,omitempty"`     // CodeBlock in a notebook
+	Operation *JSONOperation   `json:"operation,omitempty"`   // Operation in an API spec
+	Name      string           `json:"name,omitempty"`        // YAML Anchor / Alias / ConfigItem
+	Uses      int              `json:"uses,omitempty"`        // YAML Anchor
+	AnchorAt  int              `json:"anchor_line,omitempty"` // YAML Alias
+	Profile   string           `json:"profile,omitempty"`     // ConfigItem: kubernetes, compose, actions
+	Image     string           `json:"image,omitempty"`       // ConfigItem
+	Ports     []string         `json:"ports,omitempty"`       // ConfigItem
+	Action    string           `json:"action,omitempty"`      // ConfigItem uses:
+	Detail    string           `json:"detail,omitempty"`      // ConfigItem
+	Children  []JSONNode       `json:"children,omitempty"`
+}
+
+// JSONCellOutputs summarizes a notebook code cell's saved outputs.
+type JSONCellOutputs struct {
+	Text   int `json:"text"`
+	Images int `json:"images"`
+	Errors int `json:"errors"`
+	Tokens int `json:"tokens"`
+}
+
+// JSONOperation describes an OpenAPI or AsyncAPI operation.
+type JSONOperation struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	OperationID string   `json:"operation_id,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Parameters  []string `json:"parameters,omitempty"`
+	Request     string   `json:"request,omitempty"`
+	Responses   []string `json:"responses,omitempty"`
+	Refs        []string `json:"refs,omitempty"`
 }
 
 type JSONRef struct {
@@ -125,76 +166,277 @@ func main() {
 		}
 	}
 
+	// Subcommands.
+	switch os.Args[1] {
+	case "cache":
+		runCache(os.Args[2:])
+		return
+	}
+
 	// Parse flags (scan all args for flags first)
-	var sectionFilter string
-	var expandSection string
-	var searchQuery string
-	var typeFilter string
-	var langFilter string
-	var kindFilter string
-	var atLine int
-	var sinceRef string
-	var showRefs bool
-	var jsonMode bool
+	var view viewOptions
 	var stdinMode bool
 	var target string
+	var opts parseOptions
+	var noCache bool
+	var watch bool
+	var addr string
+	var tokenizerName string
+	var budget int
+	var query string
+	var format string
+	var disable []string
+	var maxTokens int
+	var include, exclude []string
+	var positional []string
 
 	for i := 1; i < len(os.Args); i++ {
 		switch os.Args[i] {
 		case "--section", "-s":
 			if i+1 < len(os.Args) {
-				sectionFilter = os.Args[i+1]
+				view.sectionFilter = os.Args[i+1]
 				i++
 			}
 		case "--expand", "-e":
 			if i+1 < len(os.Args) {
-				expandSection = os.Args[i+1]
+				view.expandSection = os.Args[i+1]
+				i++
+			}
+		case "--section-index":
+			if i+1 < len(os.Args) {
+				view.sectionIndex = positiveFlag("--section-index", os.Args[i+1])
 				i++
 			}
 		case "--search":
 			if i+1 < len(os.Args) {
-				searchQuery = os.Args[i+1]
+				view.searchQuery = os.Args[i+1]
+				i++
+			}
+		case "--limit":
+			if i+1 < len(os.Args) {
+				view.search.Limit = positiveFlag("--limit", os.Args[i+1])
 				i++
 			}
+		case "--regex":
+			view.search.Regex = true
+		case "--case-sensitive":
+			view.search.CaseSensitive = true
+		case "--word":
+			view.search.Word = true
 		case "--type", "-t":
 			if i+1 < len(os.Args) {
-				typeFilter = os.Args[i+1]
+				view.typeFilter = os.Args[i+1]
 				i++
 			}
 		case "--lang":
 			if i+1 < len(os.Args) {
-				langFilter = os.Args[i+1]
+				view.langFilter = os.Args[i+1]
 				i++
 			}
 		case "--kind":
 			if i+1 < len(os.Args) {
-				kindFilter = os.Args[i+1]
+				view.kindFilter = os.Args[i+1]
 				i++
 			}
 		case "--at":
 			if i+1 < len(os.Args) {
-				n, err := strconv.Atoi(os.Args[i+1])
-				if err == nil {
-					atLine = n
-				}
+				view.at = os.Args[i+1]
 				i++
 			}
 		case "--since":
 			if i+1 < len(os.Args) {
-				sinceRef = os.Args[i+1]
+				view.sinceRef = os.Args[i+1]
+				i++
+			}
+		case "--extract", "-x":
+			if i+1 < len(os.Args) {
+				view.extractPath = os.Args[i+1]
+				i++
+			}
+		case "--with-children":
+			view.withChildren = true
+		case "--lines":
+			if i+1 < len(os.Args) {
+				view.lineRange = os.Args[i+1]
 				i++
 			}
 		case "--refs", "-r":
-			showRefs = true
+			view.showRefs = true
 		case "--json", "-j":
-			jsonMode = true
+			view.jsonMode = true
 		case "--stdin":
 			stdinMode = true
+		case "--jobs":
+			if i+1 < len(os.Args) {
+				opts.Jobs = positiveFlag("--jobs", os.Args[i+1])
+				i++
+			}
+		case "--timings":
+			opts.Timings = os.Stderr
+		case "--no-cache":
+			noCache = true
+		case "--watch", "-w":
+			watch = true
+		case "--tokenizer":
+			if i+1 < len(os.Args) {
+				tokenizerName = os.Args[i+1]
+				i++
+			}
+		case "--budget":
+			if i+1 < len(os.Args) {
+				budget = positiveFlag("--budget", os.Args[i+1])
+				i++
+			}
+		case "--query", "-q":
+			if i+1 < len(os.Args) {
+				query = os.Args[i+1]
+				i++
+			}
+		case "--addr":
+			if i+1 < len(os.Args) {
+				addr = os.Args[i+1]
+				i++
+			}
+		case "--format":
+			if i+1 < len(os.Args) {
+				format = os.Args[i+1]
+				i++
+			}
+		case "--disable":
+			if i+1 < len(os.Args) {
+				for _, id := range strings.Split(os.Args[i+1], ",") {
+					if id = strings.TrimSpace(id); id != "" {
+						disable = append(disable, id)
+					}
+				}
+				i++
+			}
+		case "--include":
+			if i+1 < len(os.Args) {
+				include = append(include, os.Args[i+1])
+				i++
+			}
+		case "--exclude":
+			if i+1 < len(os.Args) {
+				exclude = append(exclude, os.Args[i+1])
+				i++
+			}
+		case "--where":
+			if i+1 < len(os.Args) {
+				c, err := parseWhere(os.Args[i+1])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				view.where = append(view.where, c)
+				i++
+			}
+		case "--max-tokens":
+			if i+1 < len(os.Args) {
+				maxTokens = positiveFlag("--max-tokens", os.Args[i+1])
+				i++
+			}
 		default:
 			if target == "" {
 				target = os.Args[i]
 			}
+			positional = append(positional, os.Args[i])
+		}
+	}
+
+	// Project config, with flags taking precedence over it.
+	cfg, err := loadConfig(configTarget(positional, stdinMode))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if tokenizerName != "" {
+		cfg.Tokenizer = tokenizerName
+	}
+	cfg.setFileFlags(include, exclude)
+	if format == "" && view.jsonMode {
+		format = "json"
+	}
+	if format != "" {
+		if os.Args[1] == "lint" {
+			cfg.Lint.Format = format
+		} else {
+			cfg.Output = format
+		}
+	}
+	if disable != nil {
+		cfg.Lint.Disable = disable
+	}
+	if maxTokens > 0 {
+		cfg.Lint.MaxTokens = maxTokens
+	}
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	view.jsonMode = cfg.Output == "json"
+
+	if cfg.Tokenizer != "" {
+		tok, err := parser.NewTokenizer(cfg.Tokenizer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		parser.SetTokenizer(tok)
+	}
+	parser.SetCalloutVariants(cfg.Callouts)
+	opts.Files = cfg.fileRules()
+	if stdinMode {
+		// Manifest paths are relative to the manifest, not the config.
+		opts.Files.Base = ""
+	}
+
+	// Manifest files are written to a fresh temp directory on every run, so
+	// caching them would only fill the cache with entries nobody can hit.
+	if !noCache && !stdinMode {
+		opts.Cache = openCache(opts.Files)
+	}
+
+	// Subcommands that share the parse flags above.
+	switch os.Args[1] {
+	case "mcp":
+		runMCP(opts)
+		return
+	case "lsp":
+		runLSP(opts)
+		return
+	case "serve":
+		var dir string
+		if len(positional) > 1 {
+			dir = positional[1]
+		}
+		runServe(dir, addr, opts)
+		return
+	case "pack":
+		var packTarget string
+		if len(positional) > 1 {
+			packTarget = positional[1]
+		}
+		runPack(packTarget, budget, query, opts, view.jsonMode)
+		return
+	case "check":
+		runCheck(positional[1:], opts, view.jsonMode)
+		return
+	case "lint":
+		var lintTarget string
+		if len(positional) > 1 {
+			lintTarget = positional[1]
+		}
+		runLint(lintTarget, cfg.lintFormat(), cfg.lintConfig(), opts)
+		return
+	case "config":
+		runConfig(positional[1:], cfg, view.jsonMode)
+		return
+	}
+
+	if watch && stdinMode {
+		fmt.Fprintf(os.Stderr, "Error: --watch can't be combined with --stdin\n")
+		os.Exit(1)
 	}
 
 	// Handle --stdin mode
@@ -237,21 +479,13 @@ func main() {
 		}
 
 		// Parse the temp directory
-		docs := parseDirectory(tmpDir)
+		docs := parseDirectory(tmpDir, opts)
 		if len(docs) == 0 {
-			fmt.Println("No markdown, PDF, or YAML files found")
+			fmt.Println(noFilesMessage)
 			os.Exit(1)
 		}
 
-		if jsonMode {
-			outputJSON(docs, manifest.Root)
-		} else if searchQuery != "" {
-			render.SearchResults(docs, searchQuery)
-		} else if showRefs {
-			render.RefsTree(docs, manifest.Root)
-		} else {
-			render.MultiTree(docs, manifest.Root)
-		}
+		exitOnError(renderDirectory(docs, tmpDir, manifest.Root, manifest.Root, view))
 		return
 	}
 
@@ -267,175 +501,253 @@ func main() {
 		os.Exit(1)
 	}
 
+	if watch {
+		runWatch(target, info.IsDir(), opts, view)
+		return
+	}
+
 	if info.IsDir() {
 		// Multi-file mode: find all .md files
-		docs := parseDirectory(target)
+		docs := parseDirectory(target, opts)
 		if len(docs) == 0 {
-			fmt.Println("No markdown, PDF, or YAML files found")
+			fmt.Println(noFilesMessage)
 			os.Exit(1)
 		}
-		if jsonMode {
-			absPath, _ := filepath.Abs(target)
-			outputJSON(docs, absPath)
-		} else if searchQuery != "" {
-			render.SearchResults(docs, searchQuery)
-		} else if showRefs {
-			render.RefsTree(docs, target)
-		} else {
-			render.MultiTree(docs, target)
-		}
+		absPath, _ := filepath.Abs(target)
+		exitOnError(renderDirectory(docs, target, target, absPath, view))
 	} else {
 		// Single file mode
-		var doc *parser.Document
-
-		lower := strings.ToLower(target)
-		if strings.HasSuffix(lower, ".pdf") {
-			// PDF file
-			var err error
-			doc, err = parser.ParsePDF(target)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing PDF: %v\n", err)
-				os.Exit(1)
-			}
-		} else if strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".yml") {
-			// YAML file
-			content, err := os.ReadFile(target)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-				os.Exit(1)
-			}
-			doc, err = parser.ParseYAML(string(content))
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing YAML: %v\n", err)
-				os.Exit(1)
-			}
-		} else {
-			// Markdown file
-			content, err := os.ReadFile(target)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-				os.Exit(1)
-			}
-			doc = parser.Parse(string(content))
+		doc, err := opts.parse(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error %v\n", err)
+			os.Exit(1)
 		}
 
 		parts := strings.Split(target, "/")
 		doc.Filename = parts[len(parts)-1]
 
-		if jsonMode {
-			absPath, _ := filepath.Abs(target)
-			outputJSON([]*parser.Document{doc}, absPath)
-		} else if searchQuery != "" {
-			render.SearchResults([]*parser.Document{doc}, searchQuery)
-		} else if sinceRef != "" {
-			changed, _ := parser.ChangedLines(target, sinceRef)
-			render.ChangedSince(doc, changed, sinceRef)
-		} else if atLine > 0 {
-			render.AtLine(doc, atLine)
-		} else if typeFilter != "" {
-			render.TypeFilterFiltered(doc, typeFilter, langFilter, kindFilter)
-		} else if expandSection != "" {
-			render.ExpandSection(doc, expandSection)
-		} else if sectionFilter != "" {
-			render.FilteredTree(doc, sectionFilter)
-		} else {
-			render.Tree(doc)
-		}
+		exitOnError(renderFile(doc, target, view))
 	}
 }
 
-func parseDirectory(dir string) []*parser.Document {
-	var docs []*parser.Document
+// viewOptions holds the flags that select which view is rendered.
+type viewOptions struct {
+	sectionFilter string
+	expandSection string
+	sectionIndex  int // picks among ambiguous --section/--expand/--extract matches
+	searchQuery   string
+	search        render.SearchOptions
+	typeFilter    string
+	langFilter    string
+	kindFilter    string
+	at            string // a line, or cell:line in notebooks
+	sinceRef      string
+	extractPath   string
+	withChildren  bool
+	lineRange     string
+	showRefs      bool
+	jsonMode      bool
+	where         []whereClause // directory maps only
+}
 
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// checkDirectory reports flags that only make sense for a single file.
+func (v viewOptions) checkDirectory() error {
+	switch {
+	case v.extractPath != "":
+		return fmt.Errorf("--extract needs a file target, not a directory")
+	case v.lineRange != "":
+		return fmt.Errorf("--lines needs a file target, not a directory")
+	}
+	return nil
+}
+
+// renderDirectory renders the multi-file view selected by v. dir is where
+// the documents were read from, root the name shown in headers, and
+// jsonRoot the root reported in JSON output.
+func renderDirectory(docs []*parser.Document, dir, root, jsonRoot string, v viewOptions) error {
+	if err := v.checkDirectory(); err != nil {
+		return err
+	}
+	docs = filterWhere(docs, v.where)
+	if len(docs) == 0 && !v.jsonMode {
+		fmt.Println("No documents match the --where filters")
+		return nil
+	}
+	if v.searchQuery != "" {
+		return search(docs, sourceLines(dir, true), v)
+	} else if v.jsonMode {
+		outputJSON(docs, jsonRoot)
+	} else if v.showRefs {
+		render.RefsTree(docs, root)
+	} else {
+		render.MultiTree(docs, root)
+	}
+	return nil
+}
+
+// renderFile renders the single-file view selected by v. target is the
+// path the document was read from, used for JSON roots and git lookups.
+func renderFile(doc *parser.Document, target string, v viewOptions) error {
+	if v.extractPath != "" || v.lineRange != "" {
+		return extract(doc, target, v)
+	} else if v.searchQuery != "" {
+		return search([]*parser.Document{doc}, sourceLines(target, false), v)
+	} else if v.jsonMode {
+		absPath, _ := filepath.Abs(target)
+		outputJSON([]*parser.Document{doc}, absPath)
+	} else if v.sinceRef != "" {
+		changed, err := parser.ChangedLines(target, v.sinceRef)
 		if err != nil {
-			return nil
+			return err
 		}
-		if info.IsDir() {
-			return nil
+		render.ChangedSince(doc, changed, v.sinceRef)
+	} else if v.at != "" {
+		line, err := resolveAt(doc, v.at)
+		if err != nil {
+			return err
 		}
-
-		lowerPath := strings.ToLower(path)
-		isMd := strings.HasSuffix(lowerPath, ".md")
-		isPdf := strings.HasSuffix(lowerPath, ".pdf")
-		isYaml := strings.HasSuffix(lowerPath, ".yaml") || strings.HasSuffix(lowerPath, ".yml")
-
-		if !isMd && !isPdf && !isYaml {
-			return nil
+		render.AtLine(doc, line)
+	} else if v.typeFilter != "" {
+		render.TypeFilterFiltered(doc, v.typeFilter, v.langFilter, v.kindFilter)
+	} else if v.expandSection != "" {
+		s, err := resolveSection(doc, v.expandSection, v.sectionIndex)
+		if err != nil {
+			return err
 		}
+		render.ExpandSection(s)
+	} else if v.sectionFilter != "" {
+		s, err := resolveSection(doc, v.sectionFilter, v.sectionIndex)
+		if err != nil {
+			return err
+		}
+		render.FilteredTree(s)
+	} else {
+		render.Tree(doc)
+	}
+	return nil
+}
 
-		// Skip hidden files
-		base := filepath.Base(path)
-		if strings.HasPrefix(base, ".") {
-			return nil
+// search renders the ranked --search results, as JSON in JSON mode.
+func search(docs []*parser.Document, linesOf func(*parser.Document) []string, v viewOptions) error {
+	if v.jsonMode {
+		res, err := querySearch(docs, v.searchQuery, v.search, linesOf)
+		if err != nil {
+			return err
 		}
+		json.NewEncoder(os.Stdout).Encode(res)
+		return nil
+	}
+	q, err := render.ParseSearchQuery(v.searchQuery, v.search)
+	if err != nil {
+		return err
+	}
+	render.SearchResults(docs, q, linesOf)
+	return nil
+}
 
-		var doc *parser.Document
+// exitOnError prints err and exits when it's non-nil.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-		if isPdf {
-			var err error
-			doc, err = parser.ParsePDF(path)
-			if err != nil {
-				// Skip PDFs that can't be parsed
-				return nil
-			}
-		} else if isYaml {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return nil
-			}
-			doc, err = parser.ParseYAML(string(content))
-			if err != nil {
-				// Skip YAML files that can't be parsed
-				return nil
-			}
-		} else {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return nil
-			}
-			doc = parser.Parse(string(content))
-		}
+// positiveFlag parses the value of a flag that takes a count, exiting
+// with an error unless it's a positive integer.
+func positiveFlag(name, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s wants a positive number, got %q\n", name, value)
+		os.Exit(1)
+	}
+	return n
+}
 
-		// Get relative path from dir
-		relPath, _ := filepath.Rel(dir, path)
-		doc.Filename = relPath
+// resolveAt turns an --at argument into a line number: a plain line, or
+// "cell:line" (both 1-based) for notebooks.
+func resolveAt(doc *parser.Document, at string) (int, error) {
+	cell, line, isCell := strings.Cut(at, ":")
+	if !isCell {
+		n, err := strconv.Atoi(at)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid --at %q: want a line number", at)
+		}
+		return n, nil
+	}
+	if len(doc.Cells) == 0 {
+		return 0, fmt.Errorf("--at %s: cell:line only applies to notebooks", at)
+	}
+	c, err1 := strconv.Atoi(cell)
+	n, err2 := strconv.Atoi(line)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid --at %q: want cell:line", at)
+	}
+	resolved, ok := doc.LineOf(c, n)
+	if !ok {
+		return 0, fmt.Errorf("--at %s: no such cell line (notebook has %d cells)", at, len(doc.Cells))
+	}
+	return resolved, nil
+}
 
-		docs = append(docs, doc)
+// extract prints the verbatim source selected by --extract or --lines,
+// without decoration so it can be piped, or as JSON with --json.
+func extract(doc *parser.Document, target string, v viewOptions) error {
+	lines := readLines(target)
+	var res JSONExtractResult
+	var err error
+	if v.lineRange != "" {
+		res, err = queryLines(doc, lines, v.lineRange)
+	} else {
+		res, err = queryExtract(doc, lines, v.extractPath, v.sectionIndex, v.withChildren)
+	}
+	if err != nil {
+		return err
+	}
+	if v.jsonMode {
+		json.NewEncoder(os.Stdout).Encode(res)
 		return nil
-	})
-
-	return docs
+	}
+	fmt.Println(res.Content)
+	return nil
 }
 
 func outputJSON(docs []*parser.Document, root string) {
+	json.NewEncoder(os.Stdout).Encode(buildJSONOutput(docs, root))
+}
+
+// buildJSONOutput converts parsed documents into the --json output shape.
+func buildJSONOutput(docs []*parser.Document, root string) JSONOutput {
 	output := JSONOutput{
 		Root:      root,
 		TotalDocs: len(docs),
 	}
 
 	for _, doc := range docs {
-		jsonDoc := JSONDocument{
-			Filename: doc.Filename,
-			Tokens:   doc.TotalTokens,
-			Summary:  convertSummary(doc.Summary()),
-			Sections: convertSections(doc.Sections),
-			Nodes:    convertNodeList(doc.Nodes),
-		}
-
-		for _, ref := range doc.References {
-			jsonDoc.References = append(jsonDoc.References, JSONRef{
-				Text:   ref.Text,
-				Target: ref.Target,
-				Line:   ref.Line,
-			})
-		}
-
-		output.Documents = append(output.Documents, jsonDoc)
+		output.Documents = append(output.Documents, convertDocument(doc))
 		output.TotalTokens += doc.TotalTokens
 	}
+	return output
+}
+
+// convertDocument serializes one parsed document for JSON consumers.
+func convertDocument(doc *parser.Document) JSONDocument {
+	jsonDoc := JSONDocument{
+		Filename: doc.Filename,
+		Tokens:   doc.TotalTokens,
+		Summary:  convertSummary(doc.Summary()),
+		Sections: convertSections(doc.Sections),
+		Nodes:    convertNodeList(doc.Nodes),
+	}
 
-	json.NewEncoder(os.Stdout).Encode(output)
+	for _, ref := range doc.References {
+		jsonDoc.References = append(jsonDoc.References, JSONRef{
+			Text:   ref.Text,
+			Target: ref.Target,
+			Line:   ref.Line,
+		})
+	}
+	return jsonDoc
 }
 
 func convertSummary(s parser.ContentSummary) JSONSummary {
@@ -456,6 +768,14 @@ func convertSummary(s parser.ContentSummary) JSONSummary {
 		IssueRefs:    s.IssueRefs,
 		CommitRefs:   s.CommitRefs,
 		Emojis:       s.Emojis,
+		Operations:   s.Operations,
+		Anchors:      s.Anchors,
+		Aliases:      s.Aliases,
+		Resources:    s.Resources,
+		Containers:   s.Containers,
+		Services:     s.Services,
+		Jobs:         s.Jobs,
+		Steps:        s.Steps,
 	}
 }
 
@@ -502,6 +822,10 @@ func convertNode(n parser.Node) JSONNode {
 	case *parser.CodeBlock:
 		j.Language = v.Language
 		j.Code = v.Code
+		j.Cell = v.Cell
+		if v.Outputs != nil {
+			j.Outputs = &JSONCellOutputs{Text: v.Outputs.Text, Images: v.Outputs.Images, Errors: v.Outputs.Errors, Tokens: v.Outputs.Tokens}
+		}
 	case *parser.Callout:
 		j.Variant = string(v.Variant)
 	case *parser.Table:
@@ -529,10 +853,30 @@ func convertNode(n parser.Node) JSONNode {
 	case *parser.Frontmatter:
 		j.Raw = v.Raw
 		j.Format = string(v.Format)
+		j.Fields = v.Fields
 	case *parser.WikiLink:
 		j.Target = v.Target
 	case *parser.WikiEmbed:
 		j.Target = v.Target
+	case *parser.Operation:
+		j.Title = v.Title()
+		j.Operation = &JSONOperation{
+			Method: v.Method, Path: v.Path, OperationID: v.OperationID, Summary: v.Summary, Tags: v.Tags,
+			Parameters: v.Parameters, Request: v.Request, Responses: v.Responses, Refs: v.Refs,
+		}
+	case *parser.Anchor:
+		j.Name = v.Name
+		j.Uses = v.Uses
+	case *parser.Alias:
+		j.Name = v.Name
+		j.AnchorAt = v.AnchorLine
+	case *parser.ConfigItem:
+		j.Name = v.Name
+		j.Profile = v.Profile
+		j.Image = v.Image
+		j.Ports = v.Ports
+		j.Action = v.Uses
+		j.Detail = v.Detail
 	}
 	// Recurse into children for container nodes so the JSON tree mirrors
 	// the in-memory AST.
@@ -546,35 +890,93 @@ func printUsage() {
 	fmt.Println(`docmap - instant documentation structure for LLMs and humans
 
 Usage:
-  docmap <file.md|file.pdf|file.yaml|dir> [flags]
+  docmap <file.md|file.rst|file.adoc|file.ipynb|file.html|file.pdf|file.yaml|dir> [flags]
   docmap --stdin [flags] < manifest.json
+  docmap cache <prune|clear|dir>
+  docmap mcp
+  docmap lsp
+  docmap serve [--addr host:port] [dir]
+  docmap pack <dir|file> --budget <tokens> [--query <text>]
+  docmap check links [dir|file]
+  docmap lint [dir|file] [--format human|json|sarif]
+  docmap config show [dir|file]
 
 Examples:
-  docmap .                          # All markdown, PDF, and YAML files
+  docmap .                          # All markdown, RST, AsciiDoc, PDF, YAML
   docmap README.md                  # Single markdown file deep dive
   docmap report.pdf                 # Single PDF file structure
   docmap config.yaml                # Single YAML file structure
+  docmap openapi.json -t operation  # Every operation in an API spec
+  docmap docs/index.rst             # Single reStructuredText file
+  docmap guide.adoc                 # Single AsciiDoc file
+  docmap eda.ipynb --at 4:2         # Jupyter notebook, cell 4 line 2
+  docmap export/page.html           # Exported Sphinx/Confluence page
   docmap docs/                      # Specific folder
   docmap README.md --section "API"  # Filter to section
   docmap README.md --expand "API"   # Show section content
+  docmap README.md -x "Usage > API" # Exact source lines of a section
   docmap . --refs                   # Show cross-references between docs
+  docmap . --where status=draft     # Only docs whose frontmatter matches
   docmap docs/ --search "auth"     # Search across all files
   docmap --stdin --json < manifest.json  # Parse files from JSON manifest
+  docmap mcp                        # Serve docmap tools over MCP (stdio)
+  docmap lsp                        # Language server for editors (stdio)
+  docmap serve --addr :8080 docs/   # HTTP JSON API over docs/
+  docmap pack docs/ --budget 8000 --query auth  # Best sections in 8k tokens
+  docmap check links docs/          # Broken links, anchors, images (exit 1)
+  docmap lint docs/ --format sarif  # Structural lint as a SARIF log
+  docmap config show                # Effective .docmap.yaml settings
 
 Flags:
   --stdin                Read JSON file manifest from stdin (no filesystem access needed)
-  --search <query>       Search sections across all files
+  --search <query>       Search sections across all files, ranked by BM25
+                         score; prefix with title:,
+                         code:[lang=go], callout:[variant] or table:[header=X]
+                         to search one field
+  --regex                Treat the --search text as a regular expression
+  --case-sensitive       Match --search case-sensitively
+  --word                 Match --search on whole words only
+  --limit <n>            Show only the n best-scoring --search sections
   -s, --section <name>   Filter to a specific section
   -e, --expand <name>    Show full content of a section
+                         (exact titles win, then partial, then typo-tolerant
+                         matches; ambiguous names list every candidate)
+  --section-index <n>    Pick the nth candidate of an ambiguous --section,
+                         --expand or --extract; or append @line to the name
+                         ("Config@42")
+  -x, --extract <path>   Print a section's source lines by breadcrumb path
+                         (e.g. "Guide > Install > Linux"), untruncated
+  --with-children        Include subsections with --extract
+  --lines <from-to>      Print raw source lines (e.g. 120-180, 120-)
   -t, --type <kind>      Drill into one construct: code, callout, table, math,
                          footnote, deflist, linkref, html, task, wiki, embed,
-                         mention, issue, sha, emoji
+                         mention, issue, sha, emoji, operation, anchor, alias,
+                         resource, container, service, job, step
   --lang <name>          Sub-filter for --type code (e.g. --type code --lang python)
   --kind <name>          Sub-filter for --type callout (e.g. --kind warning)
   --at <line>            Show what construct lives at a specific line number
+                         (cell:line in notebooks)
   --since <ref>          Show constructs on lines changed since a git ref
   -r, --refs             Show cross-references between markdown files
   -j, --json             Output JSON format
+  --jobs <n>             Parse directory files with n workers (default: CPU count)
+  --timings              Print per-file parse times to stderr
+  --no-cache             Don't read or write the on-disk parse cache
+  --include <glob>       Only map matching files (repeatable; e.g. 'docs/**')
+  --exclude <glob>       Skip matching files or directories (repeatable)
+  --where <expr>         Only map files whose frontmatter matches (repeatable):
+                         key=value, key!=value, key~=value (contains; lists
+                         match any item); dotted keys reach nested tables
+  --tokenizer <name>     Count tokens with estimate (bytes/4, default),
+                         cl100k, or o200k (embedded BPE vocabularies)
+  -w, --watch            Re-render whenever files change (NDJSON events with --json)
+  --budget <tokens>      Token budget for 'docmap pack'
+  -q, --query <text>     Rank sections for 'docmap pack' by relevance to text
+  --addr <host:port>     Listen address for 'docmap serve' (default: localhost:8080)
+  --format <name>        Output mode: tree (default) or json; for 'docmap lint',
+                         human (default), json, or sarif
+  --disable <rules>      Comma-separated lint rules to skip (e.g. fence-language)
+  --max-tokens <n>       Token threshold for the section-tokens lint rule (default: 2000)
   -v, --version          Print version
   -h, --help             Show this help
 
@@ -582,9 +984,57 @@ PDF Support:
   PDFs with outlines show document structure; tokens are estimated.
   PDFs without outlines fall back to page-by-page structure.
 
+Cache:
+  Parsed documents are cached under $DOCMAP_CACHE_DIR (default: the user
+  cache directory, e.g. ~/.cache/docmap), keyed by path, size, mtime,
+  content hash, docmap version and parse settings. 'docmap cache prune'
+  removes entries for changed or deleted files and other docmap versions;
+  'docmap cache clear' removes everything.
+
+reStructuredText Support:
+  .rst titles are levelled by the order adornment styles first appear.
+  code-block and :: literal blocks, admonitions, list-tables and grid tables
+  map to code, callouts and tables; :doc:, :ref: and toctree entries are refs.
+
+AsciiDoc Support:
+  .adoc = titles are headings; [source,lang] listings, NOTE:/[WARNING]====
+  admonitions and |=== tables map to code, callouts and tables; xref: and
+  <<id>> are refs, and include:: directives count as embeds.
+
+Notebook Support:
+  .ipynb markdown cells are parsed as markdown; code cells become code
+  blocks in the kernel language with output counts (text/image/error) and
+  token sizes. Line numbers refer to a markdown rendering of the notebook
+  (see --lines); --at also takes cell:line.
+
+HTML Support:
+  .html/.htm pages map h1-h6 to sections, pre/code (language-* classes) to
+  code, tables to tables and aside/.admonition to callouts; relative a href
+  links are refs. nav, script, style and similar chrome are skipped.
+
 YAML Support:
   Maps keys to sections with nested children. Sequences use name/id/title
   fields for titles when available, falling back to key: value or [N].
+  ---separated streams get one section per document (Kind/name for
+  manifests); &anchors and *aliases show as notables (--type anchor|alias).
+  Kubernetes manifests, docker compose files and GitHub Actions workflows
+  are recognized: --type resource|container, service, or job|step.
+
+API Specs:
+  OpenAPI/Swagger and AsyncAPI documents (.yaml, .yml, or .json) are mapped
+  as tags -> operations ("GET /users/{id}") with operationId, parameters,
+  request and response schemas; $ref targets are refs. JSON files that
+  aren't API specs are skipped.
+
+Config:
+  Defaults come from the nearest .docmap.yaml at or above the target
+  (include/exclude globs, ignore_dirs, types, tokenizer, output, callouts,
+  lint). Flags override it; 'docmap config show' prints the merged result.
+
+Ignore files:
+  Directory walks skip hidden files and directories and honour .gitignore,
+  .git/info/exclude, and .docmapignore (gitignore syntax, including !negation
+  and /anchored patterns).
 
 More info: https://github.com/JordanCoin/docmap`)
 }
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
 )
 
 func TestParseDirectory(t *testing.T) {
 	// Test with current directory (should find README.md at minimum)
-	docs := parseDirectory(".")
+	docs := parseDirectory(".", parseOptions{})
 
 	if len(docs) == 0 {
 		t.Error("expected to find at least one markdown file")
@@ -25,3 +31,148 @@ func TestParseDirectory(t *testing.T) {
 		t.Error("expected to find README.md")
 	}
 }
+
+func TestParseDirectoryOrderIndependentOfJobs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.md":          "# A\n\nalpha\n",
+		"b/b.md":        "# B\n\n## B1\n",
+		"b/c.yaml":      "name: c\n",
+		"d.md":          "# D\n",
+		"z/y/x.md":      "# X\n",
+		"z/notes.txt":   "ignored",
+		"z/.hidden.md":  "# Hidden\n",
+		"z/y/bad.yaml":  "key: [unclosed\n",
+		"z/y/good.yml":  "list:\n  - one\n",
+		"z/y/x/deep.md": "# Deep\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serial := parseDirectory(dir, parseOptions{Jobs: 1})
+	want := []string{"a.md", "b/b.md", "b/c.yaml", "d.md", "z/y/good.yml", "z/y/x/deep.md", "z/y/x.md"}
+	if got := docNames(serial); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("serial order = %v, want %v", got, want)
+	}
+
+	for _, jobs := range []int{2, 4, 16} {
+		got := docNames(parseDirectory(dir, parseOptions{Jobs: jobs}))
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("jobs=%d order = %v, want %v", jobs, got, want)
+		}
+	}
+}
+
+func TestParseDirectoryTimings(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("# A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "x"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	parseDirectory(dir, parseOptions{Jobs: 2, Timings: &buf})
+	if !strings.Contains(buf.String(), "a.md") {
+		t.Errorf("expected timing line for a.md, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "package.json") {
+		t.Errorf("JSON that isn't an API spec isn't a document, got %q", buf.String())
+	}
+}
+
+func docNames(docs []*parser.Document) []string {
+	var names []string
+	for _, d := range docs {
+		names = append(names, filepath.ToSlash(d.Filename))
+	}
+	return names
+}
+
+func TestParseDirectoryWithTokenizer(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 8; i++ {
+		content := strings.Repeat("# Heading\n\nSome prose, `code`, and 東京.\n\n", i+1)
+		if err := os.WriteFile(filepath.Join(dir, string(rune('a'+i))+".md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tok, err := parser.NewTokenizer("cl100k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := parser.ActiveTokenizer()
+	parser.SetTokenizer(tok)
+	defer parser.SetTokenizer(prev)
+
+	serial := parseDirectory(dir, parseOptions{Jobs: 1})
+	parallel := parseDirectory(dir, parseOptions{Jobs: 8})
+	for i := range serial {
+		if serial[i].TotalTokens != parallel[i].TotalTokens {
+			t.Errorf("%s: %d tokens serially, %d in parallel", serial[i].Filename, serial[i].TotalTokens, parallel[i].TotalTokens)
+		}
+	}
+}
+
+func TestResolveAt(t *testing.T) {
+	nb := &parser.Document{Cells: []parser.Cell{
+		{Index: 1, Type: "markdown", LineStart: 1, LineEnd: 3, SourceLine: 1, Lines: 3},
+		{Index: 2, Type: "code", LineStart: 5, LineEnd: 8, SourceLine: 6, Lines: 2},
+	}}
+	for _, tc := range []struct {
+		doc  *parser.Document
+		at   string
+		want int
+		err  string
+	}{
+		{&parser.Document{}, "12", 12, ""},
+		{&parser.Document{}, "x", 0, "want a line number"},
+		{&parser.Document{}, "2:1", 0, "only applies to notebooks"},
+		{nb, "2:1", 6, ""},
+		{nb, "2:2", 7, ""},
+		{nb, "2:4", 0, "no such cell line"},
+		{nb, "3:1", 0, "no such cell line"},
+	} {
+		got, err := resolveAt(tc.doc, tc.at)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("resolveAt(%q) error = %v, want %q", tc.at, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("resolveAt(%q) = %d, %v; want %d", tc.at, got, err, tc.want)
+		}
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	for _, tc := range []struct {
+		v    viewOptions
+		want string
+	}{
+		{viewOptions{}, ""},
+		{viewOptions{searchQuery: "auth"}, ""},
+		{viewOptions{extractPath: "Guide > Install"}, "--extract needs a file target"},
+		{viewOptions{lineRange: "10-20"}, "--lines needs a file target"},
+	} {
+		err := tc.v.checkDirectory()
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tc.v, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: error = %v, want %q", tc.v, err, tc.want)
+		}
+	}
+}
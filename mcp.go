@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/JordanCoin/docmap/parser"
+	"github.com/JordanCoin/docmap/render"
+)
+
+// docmap mcp speaks the Model Context Protocol over stdio: newline-delimited
+// JSON-RPC 2.0 messages on stdin/stdout. Each tool maps onto one CLI entry
+// point and returns the same typed structures as --json, so agents never
+// have to scrape terminal output.
+
+// mcpProtocolVersion is the newest protocol revision this server speaks.
+const mcpProtocolVersion = "2025-06-18"
+
+// mcpSupportedVersions lists every revision the server accepts from a
+// client's initialize request.
+var mcpSupportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse and rpcErrorResponse are split because JSON-RPC requires
+// exactly one of result and error, and an empty result ({}) is valid.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type rpcErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpTool describes one tool in the tools/list response.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// mcpToolResult is the tools/call result. The typed result is returned as
+// structuredContent and mirrored as JSON text for clients that predate
+// structured output.
+type mcpToolResult struct {
+	Content           []mcpContent `json:"content"`
+	StructuredContent any          `json:"structuredContent,omitempty"`
+	IsError           bool         `json:"isError,omitempty"`
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpToolArgs is the union of every tool's arguments.
+type mcpToolArgs struct {
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Expand bool   `json:"expand"`
+	Index  int    `json:"section_index"`
+	Type   string `json:"type"`
+	Lang   string `json:"lang"`
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Ref    string `json:"ref"`
+	Query  string `json:"query"`
+
+	Regex         bool `json:"regex"`
+	CaseSensitive bool `json:"case_sensitive"`
+	Word          bool `json:"word"`
+	Limit         int  `json:"limit"`
+}
+
+// mcpServer handles MCP requests. opts controls how targets are parsed.
+type mcpServer struct {
+	opts parseOptions
+}
+
+// runMCP implements `docmap mcp`.
+func runMCP(opts parseOptions) {
+	srv := &mcpServer{opts: opts}
+	if err := srv.serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve reads requests from r until EOF and writes responses to w.
+// Notifications (requests without an id) never get a response.
+func (s *mcpServer) serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			enc.Encode(rpcErrorResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		result, rerr := s.handle(req)
+		if len(req.ID) == 0 {
+			continue
+		}
+		var resp any = rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if rerr != nil {
+			resp = rpcErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *mcpServer) handle(req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0"`}
+	}
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		protocol := mcpProtocolVersion
+		if mcpSupportedVersions[params.ProtocolVersion] {
+			protocol = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": protocol,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "docmap", "version": version},
+			"instructions": "docmap maps documentation structure. Call docmap_tree first " +
+				"to see files and sections, then drill in with the other tools instead of reading whole files.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": mcpTools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		var args mcpToolArgs
+		if len(params.Arguments) > 0 {
+			if err := json.Unmarshal(params.Arguments, &args); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		if !mcpToolExists(params.Name) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+		return s.callTool(params.Name, args), nil
+	}
+	if len(req.ID) == 0 {
+		// Unknown notifications (notifications/initialized, cancellations)
+		// need no action.
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// callTool runs one tool. Tool failures are reported in the result with
+// isError set, as the protocol asks, rather than as JSON-RPC errors.
+func (s *mcpServer) callTool(name string, args mcpToolArgs) mcpToolResult {
+	value, err := s.runTool(name, args)
+	if err != nil {
+		return mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}
+	}
+	return mcpToolResult{
+		Content:           []mcpContent{{Type: "text", Text: string(data)}},
+		StructuredContent: value,
+	}
+}
+
+func (s *mcpServer) runTool(name string, args mcpToolArgs) (any, error) {
+	if args.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	docs, isDir, err := loadTarget(args.Path, s.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "docmap_tree":
+		root, _ := filepath.Abs(args.Path)
+		return buildJSONOutput(docs, root), nil
+	case "docmap_search":
+		if args.Query == "" {
+			return nil, fmt.Errorf("query is required")
+		}
+		opts := render.SearchOptions{Regex: args.Regex, CaseSensitive: args.CaseSensitive, Word: args.Word, Limit: args.Limit}
+		return querySearch(docs, args.Query, opts, sourceLines(args.Path, isDir))
+	case "docmap_refs":
+		return queryRefs(docs), nil
+	case "docmap_type":
+		if args.Type == "" {
+			return nil, fmt.Errorf("type is required")
+		}
+		return queryType(docs, args.Type, args.Lang, args.Kind)
+	}
+
+	// The remaining tools address positions inside a single document.
+	if isDir {
+		return nil, fmt.Errorf("%s needs a file path, not a directory", name)
+	}
+	doc := docs[0]
+	switch name {
+	case "docmap_section":
+		if args.Name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		return querySection(doc, args.Name, args.Index, args.Expand)
+	case "docmap_at":
+		if args.Line <= 0 {
+			return nil, fmt.Errorf("line must be a positive line number")
+		}
+		return queryAt(doc, args.Line), nil
+	case "docmap_since":
+		if args.Ref == "" {
+			return nil, fmt.Errorf("ref is required")
+		}
+		changed, err := parser.ChangedLines(args.Path, args.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return querySince(doc, changed, args.Ref), nil
+	}
+	return nil, fmt.Errorf("unknown tool %q", name)
+}
+
+func mcpToolExists(name string) bool {
+	for _, t := range mcpTools() {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mcpTools lists the exposed tools with their JSON Schema inputs.
+func mcpTools() []mcpTool {
+	path := map[string]any{"type": "string", "description": "File or directory to map (markdown, reStructuredText, AsciiDoc, notebooks, HTML, PDF, YAML, and OpenAPI/AsyncAPI JSON)."}
+	file := map[string]any{"type": "string", "description": "Single file to inspect."}
+	schema := func(required []string, props map[string]any) map[string]any {
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	return []mcpTool{
+		{
+			Name:        "docmap_tree",
+			Description: "Full structure of a file or directory: sections with token counts, line ranges, notables, and the typed AST. Same shape as `docmap --json`.",
+			InputSchema: schema([]string{"path"}, map[string]any{"path": path}),
+		},
+		{
+			Name:        "docmap_section",
+			Description: "Find a section by title: an exact (case-insensitive) title wins, then partial matches, then titles within a typo or two. When several sections match equally, the error lists them numbered with breadcrumbs and lines; pick one with section_index or a name@line suffix. With expand, include the section's raw content.",
+			InputSchema: schema([]string{"path", "name"}, map[string]any{
+				"path":          file,
+				"name":          map[string]any{"type": "string", "description": "Section title, part of it, or a breadcrumb path; append @line to pick the section around that line."},
+				"section_index": map[string]any{"type": "integer", "description": "1-based number of the candidate to pick when the name is ambiguous."},
+				"expand":        map[string]any{"type": "boolean", "description": "Include the section's content."},
+			}),
+		},
+		{
+			Name:        "docmap_type",
+			Description: "Every construct of one kind, grouped by section: code, callout, table, math, footnote, deflist, linkref, html, task, wiki, embed, mention, issue, sha, emoji, operation, anchor, alias, resource, container, service, job, step.",
+			InputSchema: schema([]string{"path", "type"}, map[string]any{
+				"path": path,
+				"type": map[string]any{"type": "string", "description": "Construct kind, e.g. code or callout."},
+				"lang": map[string]any{"type": "string", "description": "For type=code: only this language."},
+				"kind": map[string]any{"type": "string", "description": "For type=callout: only this variant (note, tip, warning, ...)."},
+			}),
+		},
+		{
+			Name:        "docmap_at",
+			Description: "What construct and section live at a 1-indexed line number.",
+			InputSchema: schema([]string{"path", "line"}, map[string]any{
+				"path": file,
+				"line": map[string]any{"type": "integer", "minimum": 1},
+			}),
+		},
+		{
+			Name:        "docmap_since",
+			Description: "Sections and constructs on lines changed since a git ref (uses git diff).",
+			InputSchema: schema([]string{"path", "ref"}, map[string]any{
+				"path": file,
+				"ref":  map[string]any{"type": "string", "description": "Git ref, e.g. HEAD~5 or main."},
+			}),
+		},
+		{
+			Name:        "docmap_search",
+			Description: "Sections whose title, content, or notables (code, callouts, table headers, ...) match the query, best BM25 score first, with the matching lines. Prefix the query with title:, code:[lang=go], callout:[variant] or table:[header=Name] to search one field.",
+			InputSchema: schema([]string{"path", "query"}, map[string]any{
+				"path":           path,
+				"query":          map[string]any{"type": "string"},
+				"regex":          map[string]any{"type": "boolean", "description": "Treat the query text as a regular expression."},
+				"case_sensitive": map[string]any{"type": "boolean"},
+				"word":           map[string]any{"type": "boolean", "description": "Match whole words only."},
+				"limit":          map[string]any{"type": "integer", "description": "Return only the top N sections."},
+			}),
+		},
+		{
+			Name:        "docmap_refs",
+			Description: "Cross-references between markdown files, with the most-linked hub files.",
+			InputSchema: schema([]string{"path"}, map[string]any{"path": path}),
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mcpSession feeds requests to a fresh server and returns one decoded
+// response per line of output.
+func mcpSession(t *testing.T, requests ...string) []map[string]any {
+	t.Helper()
+	srv := &mcpServer{opts: parseOptions{Jobs: 1}}
+	var out bytes.Buffer
+	if err := srv.serve(strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	var responses []map[string]any
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("bad response %q: %v", scanner.Text(), err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func toolCall(id int, name string, args map[string]any) string {
+	data, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	return string(data)
+}
+
+func writeMCPFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	content := "# Guide\n\n## Install\n\n```go\nfmt.Println(1)\n```\n\n> [!WARNING]\n> Careful.\n\nSee [api](api.md).\n"
+	if err := os.WriteFile(filepath.Join(dir, "guide.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestMCPInitializeAndList(t *testing.T) {
+	responses := mcpSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"bogus"}`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses (notification gets none), got %d", len(responses))
+	}
+
+	init := responses[0]["result"].(map[string]any)
+	if init["protocolVersion"] != "2025-03-26" {
+		t.Errorf("expected negotiated protocol 2025-03-26, got %v", init["protocolVersion"])
+	}
+
+	tools := responses[1]["result"].(map[string]any)["tools"].([]any)
+	names := map[string]bool{}
+	for _, tool := range tools {
+		names[tool.(map[string]any)["name"].(string)] = true
+	}
+	for _, want := range []string{"docmap_tree", "docmap_section", "docmap_type", "docmap_at", "docmap_since", "docmap_search", "docmap_refs"} {
+		if !names[want] {
+			t.Errorf("tools/list missing %s", want)
+		}
+	}
+
+	if _, ok := responses[2]["result"]; !ok {
+		t.Error("ping should return an empty result object")
+	}
+	if errObj, ok := responses[3]["error"].(map[string]any); !ok || errObj["code"].(float64) != rpcMethodNotFound {
+		t.Errorf("expected method-not-found error, got %v", responses[3])
+	}
+}
+
+func TestMCPToolCalls(t *testing.T) {
+	dir := writeMCPFixture(t)
+	file := filepath.Join(dir, "guide.md")
+	responses := mcpSession(t,
+		toolCall(1, "docmap_tree", map[string]any{"path": dir}),
+		toolCall(2, "docmap_type", map[string]any{"path": file, "type": "code", "lang": "go"}),
+		toolCall(3, "docmap_at", map[string]any{"path": file, "line": 6}),
+		toolCall(4, "docmap_search", map[string]any{"path": dir, "query": "careful"}),
+		toolCall(5, "docmap_section", map[string]any{"path": file, "name": "install", "expand": true}),
+		toolCall(6, "docmap_refs", map[string]any{"path": dir}),
+		toolCall(7, "docmap_at", map[string]any{"path": dir, "line": 1}),
+		toolCall(8, "docmap_since", map[string]any{"path": file, "ref": "--output=" + filepath.Join(dir, "pwned.txt")}),
+	)
+	structured := func(i int) map[string]any {
+		t.Helper()
+		result := responses[i]["result"].(map[string]any)
+		if result["isError"] == true {
+			t.Fatalf("call %d failed: %v", i+1, result["content"])
+		}
+		return result["structuredContent"].(map[string]any)
+	}
+
+	tree := structured(0)
+	if tree["total_docs"].(float64) != 1 {
+		t.Errorf("tree: expected 1 document, got %v", tree["total_docs"])
+	}
+
+	typ := structured(1)
+	if typ["total"].(float64) != 1 {
+		t.Errorf("type: expected 1 go code block, got %v", typ["total"])
+	}
+
+	at := structured(2)
+	if at["breadcrumb"] != "Guide > Install" || at["node"].(map[string]any)["kind"] != "code_block" {
+		t.Errorf("at: unexpected result %v", at)
+	}
+
+	search := structured(3)
+	if len(search["matches"].([]any)) != 1 {
+		t.Errorf("search: expected 1 match, got %v", search["matches"])
+	}
+
+	section := structured(4)
+	if !strings.Contains(section["content"].(string), "fmt.Println") {
+		t.Errorf("section: expected expanded content, got %v", section["content"])
+	}
+
+	refs := structured(5)
+	if len(refs["references"].([]any)) != 1 {
+		t.Errorf("refs: expected 1 reference, got %v", refs["references"])
+	}
+
+	if result := responses[6]["result"].(map[string]any); result["isError"] != true {
+		t.Error("at on a directory should be a tool error")
+	}
+	if result := responses[7]["result"].(map[string]any); result["isError"] != true {
+		t.Error("since with an option-like ref should be a tool error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned.txt")); !os.IsNotExist(err) {
+		t.Errorf("since ref was passed to git as an option: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+	"github.com/JordanCoin/docmap/render"
+)
+
+// docmap pack fills a token budget with sections and prints them as one
+// markdown bundle. Each section contributes only its own content (its
+// subsections are candidates of their own), so any mix of parents and
+// children can be packed without duplicating text.
+
+// JSONPackResult is the answer to `docmap pack --json`.
+type JSONPackResult struct {
+	Budget   int             `json:"budget"`
+	Used     int             `json:"used"`
+	Query    string          `json:"query,omitempty"`
+	Included []JSONPackEntry `json:"included"`
+	Dropped  []JSONPackEntry `json:"dropped"`
+	Bundle   string          `json:"bundle"`
+}
+
+// JSONPackEntry is one section considered for the bundle.
+type JSONPackEntry struct {
+	Filename   string  `json:"filename"`
+	Breadcrumb string  `json:"breadcrumb"`
+	LineStart  int     `json:"line_start,omitempty"`
+	LineEnd    int     `json:"line_end,omitempty"`
+	Tokens     int     `json:"tokens"`
+	Score      float64 `json:"score,omitempty"`
+}
+
+// packCandidate is one section's own content, rendered with its
+// provenance header and counted with the active tokenizer.
+type packCandidate struct {
+	filename  string
+	section   *parser.Section
+	lineStart int
+	lineEnd   int
+	order     int
+	score     float64
+	text      string
+	tokens    int
+}
+
+type packResult struct {
+	budget   int
+	used     int
+	query    string
+	included []*packCandidate // document order
+	dropped  []*packCandidate // relevance order
+}
+
+// runPack implements `docmap pack <dir|file> --budget N [--query q]`.
+func runPack(target string, budget int, query string, opts parseOptions, jsonMode bool) {
+	if target == "" || budget <= 0 {
+		fmt.Fprintln(os.Stderr, "Usage: docmap pack <dir|file> --budget <tokens> [--query <text>] [--json]")
+		os.Exit(1)
+	}
+	docs, isDir, err := loadTarget(target, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	sourceOf := func(doc *parser.Document) string {
+		if isDir {
+			return filepath.Join(target, doc.Filename)
+		}
+		return target
+	}
+
+	res, err := pack(docs, sourceOf, opts.Files, budget, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonMode {
+		json.NewEncoder(os.Stdout).Encode(res.toJSON())
+		return
+	}
+	res.writeBundle(os.Stdout)
+	res.writeReport(os.Stderr)
+}
+
+// pack ranks sections against query the way --search does (BM25 over the
+// section index, field scopes included), then greedily takes the most
+// relevant ones that still fit in budget. Without a query every section is
+// equally relevant and the bundle fills in document order. sourceOf maps a
+// document to the file its verbatim lines are read from, and rules give
+// that file's kind.
+func pack(docs []*parser.Document, sourceOf func(*parser.Document) string, rules fileRules, budget int, query string) (packResult, error) {
+	var scores map[*parser.Section]float64
+	if query != "" {
+		q, err := render.ParseSearchQuery(query, render.SearchOptions{})
+		if err != nil {
+			return packResult{}, err
+		}
+		scores = map[*parser.Section]float64{}
+		for _, r := range render.Search(docs, q, func(doc *parser.Document) []string { return readLines(sourceOf(doc)) }) {
+			scores[r.Section] = r.Score
+		}
+	}
+
+	var candidates []*packCandidate
+	for _, doc := range docs {
+		source := sourceOf(doc)
+		lines := readLines(source)
+		fence := packFences[rules.kind(source)]
+		for _, s := range doc.GetAllSections() {
+			c := newPackCandidate(doc.Filename, s, lines, fence)
+			if c == nil {
+				continue
+			}
+			c.order = len(candidates)
+			if scores != nil {
+				score, ok := scores[s]
+				if !ok {
+					continue
+				}
+				c.score = score
+			}
+			candidates = append(candidates, c)
+		}
+	}
+
+	ranked := append([]*packCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	res := packResult{budget: budget, query: query}
+	for _, c := range ranked {
+		if res.used+c.tokens > budget {
+			res.dropped = append(res.dropped, c)
+			continue
+		}
+		res.used += c.tokens
+		res.included = append(res.included, c)
+	}
+	sort.Slice(res.included, func(i, j int) bool {
+		return res.included[i].order < res.included[j].order
+	})
+	return res, nil
+}
+
+// packFences are the code fence languages of sources that aren't markdown.
+// Notebooks are read as the markdown they render to and need none.
+var packFences = map[string]string{
+	kindYAML:     "yaml",
+	kindJSON:     "json",
+	kindHTML:     "html",
+	kindRST:      "rst",
+	kindAsciiDoc: "asciidoc",
+}
+
+// newPackCandidate renders the section's own lines — its heading through
+// the line before its first subsection — or nil if it has no content
+// beyond the heading. Non-markdown sources are wrapped in a fence block.
+func newPackCandidate(filename string, s *parser.Section, lines []string, fence string) *packCandidate {
+	end := s.OwnLineEnd()
+	if s.LineStart > 0 && end < s.LineStart {
+		// Every line belongs to a subsection, as with an API tag.
+		return nil
+	}
+
+	var body string
+	if s.LineStart > 0 && end <= len(lines) && end >= s.LineStart {
+		body = strings.TrimRight(strings.Join(lines[s.LineStart-1:end], "\n"), "\n ")
+		if fence != "" {
+			// Go by the parsed content: a YAML key and its scalar value
+			// share a line, and RST titles carry an underline.
+			if strings.TrimSpace(s.Content) == "" {
+				return nil
+			}
+			body = "```" + fence + "\n" + body + "\n```"
+		} else if !s.IsPreamble() && strings.TrimSpace(strings.Join(lines[s.LineStart:end], "\n")) == "" {
+			return nil
+		}
+	} else {
+		// No usable source lines (PDF outlines): rebuild from the parse.
+		if strings.TrimSpace(s.Content) == "" {
+			return nil
+		}
+		body = strings.Repeat("#", max(s.Level, 1)) + " " + s.Title + "\n\n" + s.Content
+		end = s.LineEnd
+	}
+
+	header := fmt.Sprintf("<!-- %s › %s", filename, render.Breadcrumb(s))
+	if s.LineStart > 0 {
+		header += fmt.Sprintf(" (lines %d-%d)", s.LineStart, end)
+	}
+	header += " -->"
+	text := header + "\n" + body + "\n\n"
+	return &packCandidate{
+		filename:  filename,
+		section:   s,
+		lineStart: s.LineStart,
+		lineEnd:   end,
+		text:      text,
+		tokens:    parser.ActiveTokenizer().Count(text),
+	}
+}
+
+func (r packResult) bundle() string {
+	var b strings.Builder
+	for _, c := range r.included {
+		b.WriteString(c.text)
+	}
+	return b.String()
+}
+
+func (r packResult) writeBundle(w io.Writer) {
+	io.WriteString(w, r.bundle())
+}
+
+// writeReport summarizes the bundle and lists what didn't fit.
+func (r packResult) writeReport(w io.Writer) {
+	fmt.Fprintf(w, "packed %d section%s · %d / %d tokens\n", len(r.included), plural(len(r.included)), r.used, r.budget)
+	if len(r.dropped) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "dropped %d section%s over budget:\n", len(r.dropped), plural(len(r.dropped)))
+	for _, c := range r.dropped {
+		fmt.Fprintf(w, "  %6d  %s › %s\n", c.tokens, c.filename, render.Breadcrumb(c.section))
+	}
+}
+
+func (r packResult) toJSON() JSONPackResult {
+	out := JSONPackResult{
+		Budget:   r.budget,
+		Used:     r.used,
+		Query:    r.query,
+		Included: []JSONPackEntry{},
+		Dropped:  []JSONPackEntry{},
+		Bundle:   r.bundle(),
+	}
+	for _, c := range r.included {
+		out.Included = append(out.Included, c.entry())
+	}
+	for _, c := range r.dropped {
+		out.Dropped = append(out.Dropped, c.entry())
+	}
+	return out
+}
+
+func (c *packCandidate) entry() JSONPackEntry {
+	return JSONPackEntry{
+		Filename:   c.filename,
+		Breadcrumb: render.Breadcrumb(c.section),
+		LineStart:  c.lineStart,
+		LineEnd:    c.lineEnd,
+		Tokens:     c.tokens,
+		Score:      c.score,
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+func writePackFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide.md": "# Guide\n\nIntro paragraph.\n\n## Auth\n\nAuth uses tokens. Tokens expire.\n\n" +
+			"### Rotation\n\nRotate auth keys monthly.\n\n## Install\n\n" + strings.Repeat("Install steps. ", 40) + "\n",
+		"api.md":      "# API\n\n## Endpoints\n\nNothing about security here.\n",
+		"config.yaml": "auth:\n  provider: oidc\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func packDir(t *testing.T, dir string, budget int, query string) packResult {
+	t.Helper()
+	docs := parseDirectory(dir, parseOptions{Jobs: 1})
+	res, err := pack(docs, func(doc *parser.Document) string {
+		return filepath.Join(dir, doc.Filename)
+	}, fileRules{}, budget, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func breadcrumbs(cands []*packCandidate) []string {
+	var out []string
+	for _, c := range cands {
+		out = append(out, c.entry().Breadcrumb)
+	}
+	return out
+}
+
+func TestPackQuery(t *testing.T) {
+	dir := writePackFixture(t)
+	res := packDir(t, dir, 10000, "auth")
+	got := strings.Join(breadcrumbs(res.included), ", ")
+	if got != "Guide > Auth, Guide > Auth > Rotation" {
+		t.Errorf("unexpected selection: %s", got)
+	}
+	if len(res.dropped) != 0 {
+		t.Errorf("nothing should be dropped with a large budget, got %v", breadcrumbs(res.dropped))
+	}
+
+	bundle := res.bundle()
+	for _, want := range []string{
+		"<!-- guide.md › Guide > Auth (lines 5-8) -->\n## Auth\n\nAuth uses tokens.",
+		"<!-- guide.md › Guide > Auth > Rotation (lines 9-11) -->\n### Rotation",
+	} {
+		if !strings.Contains(bundle, want) {
+			t.Errorf("bundle missing %q:\n%s", want, bundle)
+		}
+	}
+	if strings.Contains(bundle, "Install steps") {
+		t.Error("irrelevant sections should not be packed")
+	}
+
+	// Ranking and scopes are the same as --search.
+	if got := breadcrumbs(packDir(t, dir, 10000, "title:rotation").included); strings.Join(got, ", ") != "Guide > Auth > Rotation" {
+		t.Errorf("title: scope should match titles only, got %v", got)
+	}
+	oidc := packDir(t, dir, 10000, "oidc").bundle()
+	if !strings.Contains(oidc, "<!-- config.yaml › auth > provider (lines 2-2) -->\n```yaml\n  provider: oidc\n```") {
+		t.Errorf("YAML hit should be fenced:\n%s", oidc)
+	}
+	// With room for one section only, the best BM25 hit wins.
+	if got := breadcrumbs(packDir(t, dir, 30, "auth tokens").included); len(got) != 1 || got[0] != "Guide > Auth" {
+		t.Errorf("expected the best-scoring section first, got %v", got)
+	}
+}
+
+func TestPackBudget(t *testing.T) {
+	dir := writePackFixture(t)
+	all := packDir(t, dir, 100000, "")
+	if len(all.dropped) != 0 || len(all.included) != 6 {
+		t.Fatalf("expected every non-empty section without a query, got %v", breadcrumbs(all.included))
+	}
+
+	// A budget that can't fit the long Install section drops it and keeps
+	// packing the smaller sections after it.
+	res := packDir(t, dir, 120, "")
+	if res.used > 120 {
+		t.Errorf("used %d tokens, over the 120 budget", res.used)
+	}
+	if dropped := strings.Join(breadcrumbs(res.dropped), ", "); dropped != "Guide > Install" {
+		t.Errorf("expected Install to be dropped, got %q", dropped)
+	}
+	if n := len(res.included); n != 5 {
+		t.Errorf("expected the other 5 sections to fit, got %v", breadcrumbs(res.included))
+	}
+	sum := 0
+	for _, c := range res.included {
+		sum += c.tokens
+	}
+	if sum != res.used {
+		t.Errorf("reported %d tokens, included sections add up to %d", res.used, sum)
+	}
+}
+
+func TestPackSpecOperations(t *testing.T) {
+	dir := t.TempDir()
+	spec := "openapi: 3.0.0\ntags:\n  - name: users\n    description: User operations\npaths:\n  /users/{id}:\n    get:\n      tags: [users]\n      summary: Fetch a user\n      responses:\n        '200':\n          description: ok\n    delete:\n      tags: [users]\n      responses:\n        '204':\n          description: gone\n"
+	if err := os.WriteFile(filepath.Join(dir, "spec.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res := packDir(t, dir, 100000, "")
+	got := strings.Join(breadcrumbs(res.included), ", ")
+	if got != "openapi, users > GET /users/{id}, users > DELETE /users/{id}" {
+		t.Errorf("operations without a summary should still be packed, got %s", got)
+	}
+	if strings.Count(res.bundle(), "summary: Fetch a user") != 1 {
+		t.Errorf("each operation's lines should appear once:\n%s", res.bundle())
+	}
+}
+
+func TestPackFencesNonMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.html": "<h1>Title</h1>\n<p>Hello there.</p>\n",
+		"b.rst":  "Guide\n=====\n\nIntro text.\n",
+		"c.md":   "# Notes\n\nPlain markdown.\n",
+	})
+	bundle := packDir(t, dir, 100000, "").bundle()
+	for _, want := range []string{
+		"```html\n<h1>Title</h1>\n<p>Hello there.</p>\n```",
+		"```rst\nGuide\n=====\n\nIntro text.\n```",
+		"-->\n# Notes\n\nPlain markdown.\n\n",
+	} {
+		if !strings.Contains(bundle, want) {
+			t.Errorf("bundle missing %q:\n%s", want, bundle)
+		}
+	}
+}
@@ -0,0 +1,773 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseAsciiDoc parses AsciiDoc into a Document. Section titles (= to
+// ======), listing and literal blocks, admonitions, |=== tables, lists,
+// and cross references map onto the node types the markdown parser
+// produces; include:: directives become WikiEmbed nodes so they're counted
+// and listed like Obsidian embeds.
+func ParseAsciiDoc(content string) *Document {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	p := &adocParser{}
+	doc := &Document{}
+	doc.Nodes = p.parse(lines, 0, true)
+	doc.Sections, doc.TotalTokens = sectionsFromNodes(doc.Nodes)
+	doc.References = p.refs
+	return doc
+}
+
+var (
+	adocTitleRe      = regexp.MustCompile(`^(={1,6})\s+(.+?)(?:\s+=+)?\s*$`)
+	adocAnchorRe     = regexp.MustCompile(`^\[\[([^\],]+)(?:,[^\]]*)?\]\]$`)
+	adocAttrListRe   = regexp.MustCompile(`^\[([^\[\]]*)\]$`)
+	adocBlockTitleRe = regexp.MustCompile(`^\.[^.\s]`)
+	adocAttrEntryRe  = regexp.MustCompile(`^:!?[\w-]+!?:`)
+	adocAdmonitionRe = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	adocMacroRe      = regexp.MustCompile(`^(include|image)::([^\[]*)\[(.*)\]$`)
+	adocListRe       = regexp.MustCompile(`^\s*(\*{1,5}|-|\.{1,5}|\d+\.)\s+(.*)$`)
+	adocCheckRe      = regexp.MustCompile(`^\[([ xX*])\]\s+`)
+	adocDefRe        = regexp.MustCompile(`^(\S.*?)(:{2,4}|;;)(?:\s+(.*))?$`)
+	adocDelimiterRe  = regexp.MustCompile("^(-{4,}|\\.{4,}|={4,}|\\*{4,}|_{4,}|\\+{4,}|/{4,}|--|```.*)$")
+	adocShorthandRe  = regexp.MustCompile(`[#.%][^#.%]*`)
+	adocCellSpecRe   = regexp.MustCompile(`^(?:(\d+)\+|\d+\*)?(?:\.?[<^>]){0,2}[adehlmsv]?$`)
+
+	// adocInlineRe finds xref:target[text], <<id,text>>, link:url[text],
+	// url[text], and `monospace`.
+	adocInlineRe = regexp.MustCompile("xref:([^\\[\\s]+)\\[([^\\]]*)\\]|<<([^>,]+)(?:,\\s*([^>]+))?>>|link:([^\\[\\s]+)\\[([^\\]]*)\\]|((?:https?|ftp)://[^\\[\\s]+)\\[([^\\]]*)\\]|`([^`]+)`")
+)
+
+// adocAdmonitions maps admonition labels to callout variants.
+var adocAdmonitions = map[string]CalloutKind{
+	"NOTE":      CalloutNote,
+	"TIP":       CalloutTip,
+	"IMPORTANT": CalloutImportant,
+	"WARNING":   CalloutWarning,
+	"CAUTION":   CalloutCaution,
+}
+
+type adocParser struct {
+	refs []Reference
+}
+
+// adocAttrs holds a block attribute list: [style,positional,...,name=value].
+type adocAttrs struct {
+	style      string
+	positional []string
+	named      map[string]string
+	options    map[string]bool
+	id         string
+}
+
+// parseAdocAttrs parses the inside of a block attribute line. The first
+// positional attribute may carry #id, .role, and %option shorthands.
+func parseAdocAttrs(s string) adocAttrs {
+	a := adocAttrs{named: map[string]string{}, options: map[string]bool{}}
+	for k, part := range splitAdocAttrs(s) {
+		if name, value, ok := strings.Cut(part, "="); ok && !strings.ContainsAny(name, " \"") {
+			value = strings.Trim(value, `"`)
+			a.named[name] = value
+			if name == "options" || name == "opts" {
+				for _, o := range strings.Split(value, ",") {
+					a.options[strings.TrimSpace(o)] = true
+				}
+			}
+			if name == "id" {
+				a.id = value
+			}
+			continue
+		}
+		if k == 0 {
+			// [style#id.role%option]
+			rest := part
+			if cut := strings.IndexAny(rest, "#.%"); cut >= 0 {
+				rest, part = part[:cut], part[cut:]
+				for _, f := range adocShorthandRe.FindAllString(part, -1) {
+					switch f[0] {
+					case '#':
+						a.id = f[1:]
+					case '%':
+						a.options[f[1:]] = true
+					}
+				}
+			}
+			a.style = rest
+			continue
+		}
+		a.positional = append(a.positional, part)
+	}
+	return a
+}
+
+// splitAdocAttrs splits on commas outside double quotes.
+func splitAdocAttrs(s string) []string {
+	var parts []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case r == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(cur.String()))
+}
+
+// parse turns lines into block nodes. offset is the 0-based file line of
+// lines[0]; section titles are only recognized at the top level.
+func (p *adocParser) parse(lines []string, offset int, top bool) []Node {
+	var nodes []Node
+	var attrs *adocAttrs
+	anchor := ""
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " ")
+		start := offset + i + 1
+		if line == "" {
+			i++
+			continue
+		}
+
+		// Lines that only annotate the next block.
+		switch {
+		case strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "////"):
+			i++
+			continue
+		case adocAnchorRe.MatchString(line):
+			anchor = adocAnchorRe.FindStringSubmatch(line)[1]
+			i++
+			continue
+		case adocAttrListRe.MatchString(line):
+			a := parseAdocAttrs(adocAttrListRe.FindStringSubmatch(line)[1])
+			attrs = &a
+			if a.id != "" {
+				anchor = a.id
+			}
+			i++
+			continue
+		case adocBlockTitleRe.MatchString(line) && i+1 < len(lines) && !isBlankLine(lines[i+1]):
+			i++
+			continue
+		case adocAttrEntryRe.MatchString(line):
+			i++
+			continue
+		}
+		a := adocAttrs{named: map[string]string{}, options: map[string]bool{}}
+		if attrs != nil {
+			a = *attrs
+		}
+		attrs = nil
+
+		if m := adocTitleRe.FindStringSubmatch(line); m != nil && top {
+			title := adocInlineText(m[2])
+			nodes = append(nodes, &Heading{
+				BaseNode: BaseNode{NKind: KindHeading, Start: start, End: start, TokCount: estimateTokens(title)},
+				Level:    len(m[1]),
+				Title:    title,
+				RawTitle: m[2],
+				ID:       anchor,
+			})
+			anchor = ""
+			i++
+			continue
+		}
+		anchor = ""
+
+		if adocDelimiterRe.MatchString(line) || line == "|===" {
+			closing := line
+			if strings.HasPrefix(line, "```") {
+				closing = "```"
+			}
+			end := i + 1
+			for end < len(lines) && strings.TrimRight(lines[end], " ") != closing {
+				end++
+			}
+			body := lines[i+1 : min(end, len(lines))]
+			if n := p.delimited(line, a, body, offset+i+1, start, offset+min(end+1, len(lines))); n != nil {
+				nodes = append(nodes, n)
+			}
+			i = end + 1
+			continue
+		}
+
+		switch {
+		case line == "'''" || line == "---" || line == "***":
+			nodes = append(nodes, &ThematicBreak{BaseNode: BaseNode{NKind: KindThematicBreak, Start: start, End: start}})
+			i++
+			continue
+		case line == "<<<":
+			i++
+			continue
+		case adocMacroRe.MatchString(line):
+			nodes = append(nodes, p.macro(line, start))
+			i++
+			continue
+		case adocListRe.MatchString(line):
+			list, end := p.list(lines, offset, i)
+			nodes = append(nodes, list)
+			i = end
+			continue
+		case adocDefRe.MatchString(line):
+			dl, end := p.definitionList(lines, offset, i)
+			nodes = append(nodes, dl)
+			i = end
+			continue
+		}
+
+		// Paragraph: lines up to the next blank line or block delimiter.
+		end := i + 1
+		for end < len(lines) && !isBlankLine(lines[end]) && !adocDelimiterRe.MatchString(strings.TrimRight(lines[end], " ")) {
+			end++
+		}
+		para := lines[i:end]
+		raw := strings.Join(trimLines(para), "\n")
+		i = end
+
+		if m := adocAdmonitionRe.FindStringSubmatch(strings.TrimSpace(para[0])); m != nil {
+			rest := strings.SplitN(raw, "\n", 2)
+			rest[0] = m[2]
+			nodes = append(nodes, p.callout(adocAdmonitions[m[1]], []Node{p.paragraph(strings.Join(rest, "\n"), start, offset+end)}, raw, start, offset+end))
+			continue
+		}
+		switch {
+		case a.style == "source" || a.style == "listing" || a.style == "literal" || rstIndent(para[0]) > 0:
+			code := strings.Join(dedent(para), "\n")
+			nodes = append(nodes, &CodeBlock{
+				BaseNode: BaseNode{NKind: KindCodeBlock, Start: start, End: offset + end, TokCount: estimateTokens(code)},
+				Language: adocLanguage(a),
+				Code:     code,
+			})
+		case adocAdmonitions[a.style] != "":
+			nodes = append(nodes, p.callout(adocAdmonitions[a.style], []Node{p.paragraph(raw, start, offset+end)}, raw, start, offset+end))
+		case a.style == "quote" || a.style == "verse":
+			q := p.paragraph(raw, start, offset+end)
+			nodes = append(nodes, &Blockquote{BaseNode: BaseNode{NKind: KindBlockquote, Start: start, End: offset + end, TokCount: q.Tokens(), Kids: []Node{q}}})
+		default:
+			nodes = append(nodes, p.paragraph(raw, start, offset+end))
+		}
+	}
+	return nodes
+}
+
+// delimited converts a delimited block. body starts at file line
+// bodyOffset (0-based); start and end are the block's 1-based line range.
+func (p *adocParser) delimited(delim string, a adocAttrs, body []string, bodyOffset, start, end int) Node {
+	text := strings.Join(body, "\n")
+	base := BaseNode{Start: start, End: end, TokCount: estimateTokens(text)}
+	switch {
+	case delim == "|===":
+		return p.table(body, bodyOffset, a, base)
+	case strings.HasPrefix(delim, "```"):
+		base.NKind = KindCodeBlock
+		lang := strings.TrimSpace(strings.TrimPrefix(delim, "```"))
+		return &CodeBlock{BaseNode: base, Language: firstField(lang), Info: lang, Fenced: true, Code: text}
+	case delim[0] == '-' && delim != "--", delim[0] == '.':
+		base.NKind = KindCodeBlock
+		return &CodeBlock{BaseNode: base, Language: adocLanguage(a), Info: strings.Join(append([]string{a.style}, a.positional...), ","), Fenced: true, Code: text}
+	case delim[0] == '/':
+		return nil
+	case delim[0] == '+':
+		base.NKind = KindHTMLBlock
+		return &HTMLBlock{BaseNode: base, Raw: text}
+	}
+
+	kids := p.parse(body, bodyOffset, false)
+	if variant, ok := adocAdmonitions[a.style]; ok {
+		return p.callout(variant, kids, text, start, end)
+	}
+	// Quote, example, sidebar, and open blocks all nest their content.
+	base.NKind = KindBlockquote
+	base.Kids = kids
+	return &Blockquote{BaseNode: base}
+}
+
+func (p *adocParser) callout(variant CalloutKind, kids []Node, raw string, start, end int) *Callout {
+	return &Callout{
+		BaseNode: BaseNode{NKind: KindCallout, Start: start, End: end, TokCount: estimateTokens(raw), Kids: kids},
+		Variant:  variant,
+	}
+}
+
+// macro converts a block macro line: include:: becomes an embed, image::
+// an image, each wrapped in a paragraph like their markdown counterparts.
+func (p *adocParser) macro(line string, start int) *Paragraph {
+	m := adocMacroRe.FindStringSubmatch(line)
+	span := BaseNode{Start: start, End: start}
+	para := &Paragraph{BaseNode: withKind(span, KindParagraph, line), Text: line, Raw: line}
+	if m[1] == "include" {
+		para.Kids = []Node{&WikiEmbed{BaseNode: withKind(span, KindWikiEmbed, m[2]), Target: m[2]}}
+	} else {
+		alt, _, _ := strings.Cut(m[3], ",")
+		para.Kids = []Node{&Image{BaseNode: withKind(span, KindImage, alt), URL: m[2], Alt: alt}}
+	}
+	return para
+}
+
+// paragraph builds a Paragraph from raw AsciiDoc text, extracting cross
+// references, links, and monospace spans as child nodes.
+func (p *adocParser) paragraph(raw string, start, end int) *Paragraph {
+	para := &Paragraph{
+		BaseNode: BaseNode{NKind: KindParagraph, Start: start, End: end, TokCount: estimateTokens(raw)},
+		Text:     adocInlineText(raw),
+		Raw:      raw,
+	}
+	span := BaseNode{Start: start, End: end}
+	text := func(s string) {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			para.Kids = append(para.Kids, &Text{BaseNode: withKind(span, KindText, s), Value: s})
+		}
+	}
+	link := func(url, title string) {
+		if title == "" {
+			title = url
+		}
+		para.Kids = append(para.Kids, &Link{BaseNode: withKind(span, KindLink, title), URL: url, Text: title})
+	}
+	last := 0
+	for _, m := range adocInlineRe.FindAllStringSubmatchIndex(raw, -1) {
+		text(raw[last:m[0]])
+		last = m[1]
+		group := func(k int) string {
+			if m[2*k] < 0 {
+				return ""
+			}
+			return raw[m[2*k]:m[2*k+1]]
+		}
+		switch {
+		case m[2] >= 0, m[6] >= 0:
+			target, title := group(1), group(2)
+			if m[6] >= 0 {
+				target, title = group(3), strings.TrimSpace(group(4))
+			}
+			target = adocXrefTarget(target)
+			if title == "" {
+				title = strings.TrimPrefix(target, "#")
+			}
+			link(target, title)
+			p.refs = append(p.refs, Reference{Text: title, Target: target, Line: start})
+		case m[10] >= 0:
+			link(group(5), group(6))
+		case m[14] >= 0:
+			link(group(7), group(8))
+		default:
+			code := group(9)
+			para.Kids = append(para.Kids, &InlineCode{BaseNode: withKind(span, KindInlineCode, code), Code: code})
+		}
+	}
+	text(raw[last:])
+	return para
+}
+
+// adocXrefTarget normalizes a cross reference: "other.adoc#id" and
+// "other#id" point at other.adoc; a bare id points within the document.
+func adocXrefTarget(target string) string {
+	file, _, hasAnchor := strings.Cut(target, "#")
+	switch {
+	case strings.HasSuffix(file, ".adoc"):
+		return file
+	case hasAnchor && file != "":
+		return file + ".adoc"
+	case hasAnchor:
+		return target
+	}
+	return "#" + target
+}
+
+// adocInlineText renders AsciiDoc inline markup as plain text.
+func adocInlineText(raw string) string {
+	s := adocInlineRe.ReplaceAllStringFunc(raw, func(m string) string {
+		sub := adocInlineRe.FindStringSubmatch(m)
+		// Each construct's display text, falling back to its target.
+		for _, k := range []int{1, 3, 5, 7} {
+			if sub[k] != "" {
+				if title := strings.TrimSpace(sub[k+1]); title != "" {
+					return title
+				}
+				return strings.TrimPrefix(adocXrefTarget(sub[k]), "#")
+			}
+		}
+		return sub[9]
+	})
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// adocLanguage is the language of a [source,lang] block.
+func adocLanguage(a adocAttrs) string {
+	if lang := a.named["language"]; lang != "" {
+		return lang
+	}
+	if a.style == "source" && len(a.positional) > 0 {
+		return a.positional[0]
+	}
+	return ""
+}
+
+type adocItem struct {
+	marker  string
+	line    int // index into lines
+	end     int
+	checked *bool
+	body    []string
+}
+
+// list parses a run of list items starting at lines[i]. Marker styles
+// nest in the order they first appear (* then ** then ., for example);
+// "+" lines attach the following block to the current item.
+func (p *adocParser) list(lines []string, offset, i int) (*List, int) {
+	var items []*adocItem
+	end := i
+	for end < len(lines) {
+		line := strings.TrimRight(lines[end], " ")
+		if m := adocListRe.FindStringSubmatch(line); m != nil {
+			it := &adocItem{marker: m[1], line: end, end: end + 1}
+			if c := adocCheckRe.FindStringSubmatch(m[2]); c != nil && (m[1][0] == '*' || m[1] == "-") {
+				checked := c[1] != " "
+				it.checked = &checked
+				m[2] = m[2][len(c[0]):]
+			}
+			it.body = []string{m[2]}
+			items = append(items, it)
+			end++
+			continue
+		}
+		if len(items) == 0 {
+			break
+		}
+		cur := items[len(items)-1]
+		switch {
+		case line == "+":
+			// Attach the next block: a delimited block, or a paragraph.
+			j := end + 1
+			if j < len(lines) && adocDelimiterRe.MatchString(strings.TrimRight(lines[j], " ")) {
+				closing := strings.TrimRight(lines[j], " ")
+				k := j + 1
+				for k < len(lines) && strings.TrimRight(lines[k], " ") != closing {
+					k++
+				}
+				j = min(k+1, len(lines))
+			} else {
+				for j < len(lines) && !isBlankLine(lines[j]) && !adocListRe.MatchString(lines[j]) && strings.TrimSpace(lines[j]) != "+" {
+					j++
+				}
+			}
+			cur.body = append(append(cur.body, ""), lines[end+1:j]...)
+			cur.end = j
+			end = j
+			continue
+		case line == "":
+			k := end
+			for k < len(lines) && isBlankLine(lines[k]) {
+				k++
+			}
+			if k < len(lines) && adocListRe.MatchString(lines[k]) {
+				end = k
+				continue
+			}
+		case !adocDelimiterRe.MatchString(line) && !adocAttrListRe.MatchString(line):
+			cur.body[0] += "\n" + strings.TrimSpace(line)
+			cur.end = end + 1
+			end++
+			continue
+		}
+		break
+	}
+
+	var styles []string
+	depth := func(marker string) int {
+		for k, s := range styles {
+			if s == marker {
+				return k
+			}
+		}
+		styles = append(styles, marker)
+		return len(styles) - 1
+	}
+	newList := func(it *adocItem) *List {
+		ordered := it.marker[0] == '.' || (it.marker[0] >= '0' && it.marker[0] <= '9')
+		l := &List{BaseNode: BaseNode{NKind: KindList, Start: offset + it.line + 1}, Ordered: ordered, Tight: true, Marker: rune(it.marker[0])}
+		if ordered {
+			l.Start = 1
+			if n, err := strconv.Atoi(strings.TrimSuffix(it.marker, ".")); err == nil {
+				l.Start = n
+			}
+		}
+		return l
+	}
+
+	// Build the tree with a stack of open lists, one per depth.
+	type open struct {
+		list  *List
+		depth int
+	}
+	root := newList(items[0])
+	stack := []open{{root, depth(normalizeAdocMarker(items[0].marker))}}
+	var last Node
+	for _, it := range items {
+		d := depth(normalizeAdocMarker(it.marker))
+		for len(stack) > 1 && d < stack[len(stack)-1].depth {
+			stack = stack[:len(stack)-1]
+		}
+		if d > stack[len(stack)-1].depth && last != nil {
+			sub := newList(it)
+			attachKid(last, sub)
+			stack = append(stack, open{sub, d})
+		}
+		item := p.item(it, offset)
+		l := stack[len(stack)-1].list
+		l.Kids = append(l.Kids, item)
+		last = item
+	}
+	finishAdocList(root)
+	return root, end
+}
+
+// normalizeAdocMarker maps numbered markers onto a single style.
+func normalizeAdocMarker(m string) string {
+	if m[0] >= '0' && m[0] <= '9' {
+		return "1."
+	}
+	return m
+}
+
+func (p *adocParser) item(it *adocItem, offset int) Node {
+	base := BaseNode{Start: offset + it.line + 1, End: offset + it.end}
+	base.Kids = p.parse(it.body, offset+it.line, false)
+	if it.checked != nil {
+		base.NKind = KindTaskItem
+		return &TaskItem{BaseNode: base, Checked: *it.checked}
+	}
+	base.NKind = KindListItem
+	return &ListItem{BaseNode: base}
+}
+
+func attachKid(n Node, kid Node) {
+	switch v := n.(type) {
+	case *ListItem:
+		v.Kids = append(v.Kids, kid)
+	case *TaskItem:
+		v.Kids = append(v.Kids, kid)
+	}
+}
+
+// finishAdocList fills in token counts and end lines bottom-up once the
+// nesting is known.
+func finishAdocList(l *List) {
+	for _, item := range l.Kids {
+		var kids []Node
+		var b *BaseNode
+		switch v := item.(type) {
+		case *ListItem:
+			kids, b = v.Kids, &v.BaseNode
+		case *TaskItem:
+			kids, b = v.Kids, &v.BaseNode
+		}
+		for _, k := range kids {
+			if sub, ok := k.(*List); ok {
+				finishAdocList(sub)
+				b.End = max(b.End, sub.End)
+			}
+		}
+		b.TokCount = sumTokens(kids)
+		l.TokCount += b.TokCount
+		l.End = max(l.End, b.End)
+	}
+}
+
+// definitionList parses "term:: definition" entries.
+func (p *adocParser) definitionList(lines []string, offset, i int) (*DefinitionList, int) {
+	dl := &DefinitionList{BaseNode: BaseNode{NKind: KindDefinitionList, Start: offset + i + 1}}
+	end := i
+	for end < len(lines) {
+		m := adocDefRe.FindStringSubmatch(strings.TrimRight(lines[end], " "))
+		if m == nil || adocMacroRe.MatchString(lines[end]) {
+			break
+		}
+		term := adocInlineText(m[1])
+		dl.Kids = append(dl.Kids, &DefinitionTerm{
+			BaseNode: BaseNode{NKind: KindDefTerm, Start: offset + end + 1, End: offset + end + 1, TokCount: estimateTokens(term)},
+			Term:     term,
+		})
+		defStart := end
+		body := []string{}
+		if m[3] != "" {
+			body = append(body, m[3])
+		}
+		end++
+		// The description may start on the following lines.
+		for end < len(lines) && isBlankLine(lines[end]) && len(body) == 0 {
+			end++
+		}
+		for end < len(lines) && !isBlankLine(lines[end]) && !adocDefRe.MatchString(strings.TrimRight(lines[end], " ")) {
+			body = append(body, strings.TrimSpace(lines[end]))
+			end++
+		}
+		def := &Definition{BaseNode: BaseNode{NKind: KindDefinition, Start: offset + defStart + 1, End: offset + end}}
+		if len(body) > 0 {
+			def.Kids = []Node{p.paragraph(strings.Join(body, "\n"), offset+defStart+1, offset+end)}
+		}
+		def.TokCount = sumTokens(def.Kids)
+		dl.Kids = append(dl.Kids, def)
+		dl.End = def.End
+
+		k := end
+		for k < len(lines) && isBlankLine(lines[k]) {
+			k++
+		}
+		if k >= len(lines) || !adocDefRe.MatchString(strings.TrimRight(lines[k], " ")) || adocMacroRe.MatchString(lines[k]) {
+			break
+		}
+		end = k
+	}
+	dl.TokCount = sumTokens(dl.Kids)
+	return dl, end
+}
+
+type adocCell struct {
+	text string
+	span int
+	line int
+}
+
+// table parses the body of a |=== table. The column count comes from the
+// cols attribute or the first line's cells; the first row is a header
+// when the header option is set or, implicitly, when the first line holds
+// a whole row and is followed by a blank line.
+func (p *adocParser) table(body []string, offset int, a adocAttrs, base BaseNode) *Table {
+	base.NKind = KindTable
+	t := &Table{BaseNode: base}
+
+	var cells []adocCell
+	firstLineCells, firstLine := 0, -1
+	for k, line := range body {
+		if isBlankLine(line) {
+			continue
+		}
+		lineCells := splitAdocCells(line, offset+k+1)
+		if !strings.HasPrefix(strings.TrimSpace(line), "|") && !adocCellStart(line) && len(cells) > 0 {
+			// Continuation of the previous cell.
+			lead, rest, _ := strings.Cut(line, "|")
+			cells[len(cells)-1].text = strings.TrimSpace(cells[len(cells)-1].text + " " + strings.TrimSpace(lead))
+			if rest == "" {
+				continue
+			}
+			lineCells = splitAdocCells("|"+rest, offset+k+1)
+		}
+		if firstLine < 0 {
+			firstLine, firstLineCells = k, len(lineCells)
+		}
+		cells = append(cells, lineCells...)
+	}
+
+	cols := adocColumns(a.named["cols"])
+	if cols == 0 {
+		cols = firstLineCells
+	}
+	header := a.options["header"]
+	if !header && !a.options["noheader"] && firstLine >= 0 && firstLine+1 < len(body) && isBlankLine(body[firstLine+1]) && firstLineCells == cols {
+		header = true
+	}
+
+	var row *TableRow
+	width := 0
+	for _, c := range cells {
+		if row == nil {
+			row = &TableRow{BaseNode: BaseNode{NKind: KindTableRow, Start: c.line, End: c.line}, IsHeader: header && len(t.Kids) == 0}
+		}
+		row.Kids = append(row.Kids, tableCell(c.text, c.line, c.line))
+		row.End = c.line
+		row.TokCount += estimateTokens(c.text)
+		width += c.span
+		if cols > 0 && width >= cols {
+			t.Kids = append(t.Kids, row)
+			row, width = nil, 0
+		}
+	}
+	if row != nil {
+		t.Kids = append(t.Kids, row)
+	}
+	t.Headers = tableHeaders(t)
+	return t
+}
+
+// adocCellStart reports whether line begins with a cell specifier such as
+// "2+|" or "a|".
+func adocCellStart(line string) bool {
+	spec, _, ok := strings.Cut(strings.TrimSpace(line), "|")
+	return ok && spec != "" && !strings.Contains(spec, " ") && adocCellSpecRe.MatchString(spec)
+}
+
+// splitAdocCells splits one table line into cells at unescaped pipes,
+// peeling off cell specifiers that directly precede a pipe.
+func splitAdocCells(line string, lineNo int) []adocCell {
+	parts := strings.Split(strings.ReplaceAll(line, `\|`, "\x00"), "|")
+	var cells []adocCell
+	span := 1
+	for k, part := range parts {
+		part = strings.ReplaceAll(part, "\x00", "|")
+		if k > 0 {
+			text := part
+			nextSpan := 1
+			if k+1 < len(parts) {
+				// A trailing "2+" or "a" glued to the next pipe is its spec.
+				if cut := strings.LastIndexAny(text, " \t"); cut >= 0 {
+					if spec := text[cut+1:]; spec != "" && adocCellSpecRe.MatchString(spec) {
+						text = text[:cut+1]
+						nextSpan = adocSpan(spec)
+					}
+				}
+			}
+			cells = append(cells, adocCell{text: strings.TrimSpace(text), span: span, line: lineNo})
+			span = nextSpan
+			continue
+		}
+		if spec := strings.TrimSpace(part); spec != "" && adocCellSpecRe.MatchString(spec) {
+			span = adocSpan(spec)
+		}
+	}
+	return cells
+}
+
+func adocSpan(spec string) int {
+	if m := adocCellSpecRe.FindStringSubmatch(spec); m != nil && m[1] != "" {
+		n, _ := strconv.Atoi(m[1])
+		return max(n, 1)
+	}
+	return 1
+}
+
+// adocColumns counts the columns a cols attribute declares: "3", or a list
+// like "1,2,3" where "2*" repeats an entry.
+func adocColumns(cols string) int {
+	cols = strings.TrimSpace(cols)
+	if cols == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(cols); err == nil {
+		return n
+	}
+	total := 0
+	for _, c := range strings.FieldsFunc(cols, func(r rune) bool { return r == ',' || r == ';' }) {
+		if n, _, ok := strings.Cut(strings.TrimSpace(c), "*"); ok {
+			if k, err := strconv.Atoi(n); err == nil {
+				total += k
+				continue
+			}
+		}
+		total++
+	}
+	return total
+}
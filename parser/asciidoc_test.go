@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const adocSample = `= Platform Guide
+:toc:
+
+Intro with ` + "`kubectl`" + ` and xref:install.adoc#prereqs[the prerequisites].
+
+[[setup]]
+== Setup
+
+NOTE: Requires Go 1.22.
+
+[source,go]
+----
+package main
+----
+
+[WARNING]
+====
+Back up first.
+====
+
+include::partials/env.adoc[]
+
+=== Options
+
+[cols="1,2",options="header"]
+|===
+|Flag |Meaning
+|-v |verbose
+|-q |quiet
+|===
+
+|===
+| Name | Value
+
+| a | 1
+|===
+
+See <<setup>> and <<install.adoc#usage,Usage>>.
+
+== Usage
+
+* one
+** nested
+* two
+* [x] done
+
+term:: definition
+`
+
+func TestParseAsciiDocSections(t *testing.T) {
+	doc := ParseAsciiDoc(adocSample)
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Platform Guide" {
+		t.Fatalf("expected a single document title, got %+v", doc.Sections)
+	}
+	guide := doc.Sections[0]
+	if len(guide.Children) != 2 || guide.Children[0].Title != "Setup" || guide.Children[1].Title != "Usage" {
+		t.Fatalf("unexpected children: %+v", guide.Children)
+	}
+	setup := guide.Children[0]
+	if setup.Level != 2 || setup.LineStart != 7 {
+		t.Errorf("Setup: level %d line %d, want 2 and 7", setup.Level, setup.LineStart)
+	}
+	if len(setup.Children) != 1 || setup.Children[0].Title != "Options" || setup.Children[0].Level != 3 {
+		t.Errorf("expected Options under Setup, got %+v", setup.Children)
+	}
+	for _, n := range doc.Nodes {
+		if h, ok := n.(*Heading); ok && h.Title == "Setup" && h.ID != "setup" {
+			t.Errorf("Setup should take its [[setup]] anchor, got %q", h.ID)
+		}
+	}
+}
+
+func TestParseAsciiDocBlocks(t *testing.T) {
+	doc := ParseAsciiDoc(adocSample)
+	setup := doc.Sections[0].Children[0]
+
+	var variants []string
+	var codes []*CodeBlock
+	for _, n := range setup.Notables {
+		switch v := n.(type) {
+		case *Callout:
+			variants = append(variants, string(v.Variant))
+		case *CodeBlock:
+			codes = append(codes, v)
+		}
+	}
+	if got := strings.Join(variants, ","); got != "note,warning" {
+		t.Errorf("callouts = %s, want note,warning", got)
+	}
+	if len(codes) != 1 || codes[0].Language != "go" || codes[0].Code != "package main" {
+		t.Errorf("unexpected code blocks: %+v", codes)
+	}
+	if setup.Stats.WikiEmbeds != 1 {
+		t.Errorf("include:: should count as an embed, got %d", setup.Stats.WikiEmbeds)
+	}
+
+	var tables []*Table
+	for _, n := range setup.Children[0].Notables {
+		if tbl, ok := n.(*Table); ok {
+			tables = append(tables, tbl)
+		}
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(tables))
+	}
+	if got := strings.Join(tables[0].Headers, "|"); got != "Flag|Meaning" || len(tables[0].Kids) != 3 {
+		t.Errorf("explicit header table: headers %s, %d rows", got, len(tables[0].Kids))
+	}
+	if got := strings.Join(tables[1].Headers, "|"); got != "Name|Value" || !tables[1].Kids[0].(*TableRow).IsHeader {
+		t.Errorf("implicit header table: headers %s", got)
+	}
+
+	usage := doc.Sections[0].Children[1]
+	var list *List
+	for _, n := range doc.Nodes {
+		if l, ok := n.(*List); ok {
+			list = l
+		}
+	}
+	if list == nil || len(list.Kids) != 3 || list.LineStart() < usage.LineStart {
+		t.Fatalf("expected a 3-item list in Usage, got %+v", list)
+	}
+	if _, ok := list.Kids[0].Children()[1].(*List); !ok {
+		t.Errorf("** item should nest under the first item")
+	}
+	if task, ok := list.Kids[2].(*TaskItem); !ok || !task.Checked {
+		t.Errorf("[x] item should be a checked task")
+	}
+}
+
+func TestParseAsciiDocReferences(t *testing.T) {
+	doc := ParseAsciiDoc(adocSample)
+	var got []string
+	for _, r := range doc.References {
+		got = append(got, r.Target+"="+r.Text)
+	}
+	want := "install.adoc=the prerequisites,#setup=setup,install.adoc=Usage"
+	if strings.Join(got, ",") != want {
+		t.Errorf("references = %v, want %s", got, want)
+	}
+}
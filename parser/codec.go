@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// Every concrete Node type must be registered so gob can encode the Node
+// interface values held in Document.Nodes, BaseNode.Kids, and
+// Section.Notables.
+func init() {
+	for _, n := range []Node{
+		&Frontmatter{}, &Heading{}, &Paragraph{}, &Blockquote{}, &Callout{},
+		&List{}, &ListItem{}, &TaskItem{}, &Table{}, &TableRow{}, &TableCell{},
+		&CodeBlock{}, &MathBlock{}, &ThematicBreak{}, &HTMLBlock{},
+		&DefinitionList{}, &DefinitionTerm{}, &Definition{}, &FootnoteDef{},
+		&LinkRefDef{}, &Text{}, &Emphasis{}, &Strong{}, &Delete{}, &InlineCode{},
+		&Link{}, &AutoLink{}, &Image{}, &WikiLink{}, &WikiEmbed{}, &FootnoteRef{},
+		&Mention{}, &IssueRef{}, &CommitRef{}, &Emoji{}, &LineBreak{}, &Entity{},
+		&InlineMath{}, &InlineHTML{}, &Operation{},
+		&Anchor{}, &Alias{}, &ConfigItem{},
+	} {
+		gob.Register(n)
+	}
+	// Decoded frontmatter values.
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// encodedDocument is the on-disk shape of a Document. Sections are stored
+// without Parent pointers (gob cannot encode cycles); DecodeDocument
+// restores them.
+type encodedDocument struct {
+	Filename    string
+	TotalTokens int
+	Sections    []*encodedSection
+	References  []Reference
+	Nodes       []Node
+	Cells       []Cell
+	Index       *SearchIndex
+}
+
+type encodedSection struct {
+	Level     int
+	Title     string
+	Content   string
+	Tokens    int
+	KeyTerms  []string
+	Children  []*encodedSection
+	LineStart int
+	LineEnd   int
+	Notables  []Node
+	Stats     NotableStats
+}
+
+// EncodeDocument writes doc to w in a compact binary form suitable for
+// caching. The encoding round-trips through DecodeDocument.
+func EncodeDocument(w io.Writer, doc *Document) error {
+	enc := encodedDocument{
+		Filename:    doc.Filename,
+		TotalTokens: doc.TotalTokens,
+		Sections:    encodeSections(doc.Sections),
+		References:  doc.References,
+		Nodes:       doc.Nodes,
+		Cells:       doc.Cells,
+		Index:       doc.Index,
+	}
+	return gob.NewEncoder(w).Encode(&enc)
+}
+
+// DecodeDocument reads a Document previously written by EncodeDocument.
+func DecodeDocument(r io.Reader) (*Document, error) {
+	var enc encodedDocument
+	if err := gob.NewDecoder(r).Decode(&enc); err != nil {
+		return nil, err
+	}
+	return &Document{
+		Filename:    enc.Filename,
+		TotalTokens: enc.TotalTokens,
+		Sections:    decodeSections(enc.Sections, nil),
+		References:  enc.References,
+		Nodes:       enc.Nodes,
+		Cells:       enc.Cells,
+		Index:       enc.Index,
+	}, nil
+}
+
+func encodeSections(sections []*Section) []*encodedSection {
+	var out []*encodedSection
+	for _, s := range sections {
+		out = append(out, &encodedSection{
+			Level:     s.Level,
+			Title:     s.Title,
+			Content:   s.Content,
+			Tokens:    s.Tokens,
+			KeyTerms:  s.KeyTerms,
+			Children:  encodeSections(s.Children),
+			LineStart: s.LineStart,
+			LineEnd:   s.LineEnd,
+			Notables:  s.Notables,
+			Stats:     s.Stats,
+		})
+	}
+	return out
+}
+
+func decodeSections(sections []*encodedSection, parent *Section) []*Section {
+	var out []*Section
+	for _, e := range sections {
+		s := &Section{
+			Level:     e.Level,
+			Title:     e.Title,
+			Content:   e.Content,
+			Tokens:    e.Tokens,
+			KeyTerms:  e.KeyTerms,
+			Parent:    parent,
+			LineStart: e.LineStart,
+			LineEnd:   e.LineEnd,
+			Notables:  e.Notables,
+			Stats:     e.Stats,
+		}
+		s.Children = decodeSections(e.Children, s)
+		out = append(out, s)
+	}
+	return out
+}
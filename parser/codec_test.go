@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecodeDocumentRoundTrip(t *testing.T) {
+	doc := parseFixture(t)
+	doc.Filename = "kitchen_sink.md"
+	doc.BuildIndex()
+
+	var buf bytes.Buffer
+	if err := EncodeDocument(&buf, doc); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got, err := DecodeDocument(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got.Filename != doc.Filename || got.TotalTokens != doc.TotalTokens {
+		t.Errorf("header mismatch: got (%q, %d), want (%q, %d)",
+			got.Filename, got.TotalTokens, doc.Filename, doc.TotalTokens)
+	}
+	if !reflect.DeepEqual(got.Nodes, doc.Nodes) {
+		t.Error("typed AST did not round-trip")
+	}
+	if !reflect.DeepEqual(got.References, doc.References) {
+		t.Error("references did not round-trip")
+	}
+	if !reflect.DeepEqual(got.Index, doc.Index) {
+		t.Error("search index did not round-trip")
+	}
+
+	want := doc.GetAllSections()
+	all := got.GetAllSections()
+	if len(all) != len(want) {
+		t.Fatalf("expected %d sections, got %d", len(want), len(all))
+	}
+	for i := range all {
+		if all[i].Title != want[i].Title || all[i].LineEnd != want[i].LineEnd {
+			t.Errorf("section %d: got %q L%d, want %q L%d",
+				i, all[i].Title, all[i].LineEnd, want[i].Title, want[i].LineEnd)
+		}
+		if (all[i].Parent == nil) != (want[i].Parent == nil) {
+			t.Errorf("section %q: parent link not restored", all[i].Title)
+		}
+		if all[i].Parent != nil && all[i].Parent.Title != want[i].Parent.Title {
+			t.Errorf("section %q: parent %q, want %q", all[i].Title, all[i].Parent.Title, want[i].Parent.Title)
+		}
+	}
+}
+
+func TestEncodeDecodeYAMLDocument(t *testing.T) {
+	doc, err := ParseYAML("server:\n  host: localhost\n  port: 8080\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeDocument(&buf, doc); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got, err := DecodeDocument(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Sections) != 1 || len(got.Sections[0].Children) != 2 {
+		t.Fatalf("unexpected section shape after round-trip")
+	}
+	if got.Sections[0].Children[0].Parent != got.Sections[0] {
+		t.Error("expected child Parent to point at decoded parent")
+	}
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontmatter peels a frontmatter block off the top of source: YAML
+// between `---` fences, TOML between `+++` fences, or a JSON object whose
+// opening brace is the first byte of the file. It returns the Frontmatter
+// node, the remaining body, and how many lines were consumed (so downstream
+// line numbers can be shifted back).
+func splitFrontmatter(source []byte) (Node, []byte, int) {
+	var format FrontmatterFormat
+	var raw string
+	var end int // offset just past the frontmatter's last line
+	switch {
+	case bytes.HasPrefix(source, []byte("---")):
+		format = FrontmatterYAML
+		raw, end = fencedFrontmatter(source, "---")
+	case bytes.HasPrefix(source, []byte("+++")):
+		format = FrontmatterTOML
+		raw, end = fencedFrontmatter(source, "+++")
+	case bytes.HasPrefix(source, []byte("{")):
+		format = FrontmatterJSON
+		raw, end = jsonFrontmatter(source)
+	}
+	if end == 0 {
+		return nil, source, 0
+	}
+	consumedLines := bytes.Count(source[:end], []byte{'\n'})
+	if end == len(source) && !bytes.HasSuffix(source, []byte{'\n'}) {
+		consumedLines++
+	}
+
+	// Replace the consumed prefix with blank lines so line numbers in the
+	// remaining body stay stable relative to the original source.
+	body := append(bytes.Repeat([]byte{'\n'}, consumedLines), source[end:]...)
+
+	fm := &Frontmatter{
+		BaseNode: BaseNode{
+			NKind:    KindFrontmatter,
+			Start:    1,
+			End:      consumedLines,
+			TokCount: estimateTokens(raw),
+		},
+		Format: format,
+		Raw:    raw,
+	}
+	fm.Fields, _ = decodeFrontmatter(format, raw)
+	return fm, body, consumedLines
+}
+
+// fencedFrontmatter finds a block opened by fence on the first line and
+// closed by fence at the start of a later line. It returns the text between
+// the fences and the offset past the closing fence's line, or 0 if source
+// doesn't open with a complete block.
+func fencedFrontmatter(source []byte, fence string) (string, int) {
+	start := len(fence)
+	switch {
+	case bytes.HasPrefix(source[start:], []byte("\n")):
+		start++
+	case bytes.HasPrefix(source[start:], []byte("\r\n")):
+		start += 2
+	default:
+		return "", 0
+	}
+	rest := source[start:]
+	closing := []byte("\n" + fence)
+	for offset := 0; ; {
+		idx := bytes.Index(rest[offset:], closing)
+		if idx < 0 {
+			return "", 0
+		}
+		idx += offset
+		// The closing fence must be followed by a newline or EOF.
+		after := idx + len(closing)
+		if after == len(rest) || rest[after] == '\n' || rest[after] == '\r' {
+			return string(bytes.TrimSuffix(rest[:idx], []byte{'\r'})), start + lineEndAt(rest, after)
+		}
+		offset = after
+	}
+}
+
+// jsonFrontmatter decodes the JSON object at the top of source. It must be
+// the only thing on its last line.
+func jsonFrontmatter(source []byte) (string, int) {
+	dec := json.NewDecoder(bytes.NewReader(source))
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return "", 0
+	}
+	objEnd := int(dec.InputOffset())
+	end := lineEndAt(source, objEnd)
+	if len(bytes.TrimSpace(source[objEnd:end])) > 0 {
+		return "", 0
+	}
+	return string(source[:objEnd]), end
+}
+
+// lineEndAt returns the offset just past the newline ending the line that
+// holds offset, or len(b) on the last line.
+func lineEndAt(b []byte, offset int) int {
+	if i := bytes.IndexByte(b[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(b)
+}
+
+// decodeFrontmatter unmarshals raw into a field map. Dates become strings
+// and nested maps become map[string]any, so every format yields the same
+// shapes and the map survives the gob cache.
+func decodeFrontmatter(format FrontmatterFormat, raw string) (map[string]any, error) {
+	fields := map[string]any{}
+	var err error
+	switch format {
+	case FrontmatterYAML:
+		err = yaml.Unmarshal([]byte(raw), &fields)
+	case FrontmatterTOML:
+		_, err = toml.Decode(raw, &fields)
+	case FrontmatterJSON:
+		err = json.Unmarshal([]byte(raw), &fields)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return normalizeField(fields).(map[string]any), nil
+}
+
+func normalizeField(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			v[k] = normalizeField(val)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalizeField(val)
+		}
+		return m
+	case []any:
+		for i, val := range v {
+			v[i] = normalizeField(val)
+		}
+		return v
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = normalizeField(val)
+		}
+		return out
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(time.RFC3339)
+	case fmt.Stringer: // toml.LocalDate, LocalTime, LocalDateTime
+		return v.String()
+	}
+	return v
+}
+
+// Frontmatter returns the document's frontmatter block, or nil.
+func (d *Document) Frontmatter() *Frontmatter {
+	if len(d.Nodes) > 0 {
+		if fm, ok := d.Nodes[0].(*Frontmatter); ok {
+			return fm
+		}
+	}
+	return nil
+}
+
+// Field looks up a frontmatter field by key. Dotted keys ("params.author")
+// reach into nested tables.
+func (f *Frontmatter) Field(key string) (any, bool) {
+	var v any = f.Fields
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
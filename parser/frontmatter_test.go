@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrontmatterFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		format FrontmatterFormat
+		end    int
+	}{
+		{"yaml", "---\ntitle: Guide\ntags: [api, auth]\ndate: 2024-05-01\nparams:\n  author: ana\n---\n\n# Guide\n", FrontmatterYAML, 7},
+		{"toml", "+++\ntitle = \"Guide\"\ntags = [\"api\", \"auth\"]\ndate = 2024-05-01\n[params]\nauthor = \"ana\"\n+++\n\n# Guide\n", FrontmatterTOML, 7},
+		{"json", "{\n  \"title\": \"Guide\",\n  \"tags\": [\"api\", \"auth\"],\n  \"date\": \"2024-05-01\",\n  \"params\": {\"author\": \"ana\"}\n}\n\n# Guide\n", FrontmatterJSON, 6},
+	}
+	want := map[string]any{
+		"title":  "Guide",
+		"tags":   []any{"api", "auth"},
+		"date":   "2024-05-01",
+		"params": map[string]any{"author": "ana"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(tt.src)
+			fm := doc.Frontmatter()
+			if fm == nil {
+				t.Fatal("no frontmatter found")
+			}
+			if fm.Format != tt.format || fm.LineEnd() != tt.end {
+				t.Errorf("got %s L1-%d, want %s L1-%d", fm.Format, fm.LineEnd(), tt.format, tt.end)
+			}
+			if !reflect.DeepEqual(fm.Fields, want) {
+				t.Errorf("fields = %#v", fm.Fields)
+			}
+			if v, ok := fm.Field("params.author"); !ok || v != "ana" {
+				t.Errorf("params.author = %v, %v", v, ok)
+			}
+			if len(doc.Sections) != 1 || doc.Sections[0].Title != "Guide" || doc.Sections[0].LineStart != tt.end+2 {
+				t.Errorf("body lines shifted: %+v", doc.Sections)
+			}
+		})
+	}
+}
+
+func TestFrontmatterNotDetected(t *testing.T) {
+	for _, src := range []string{
+		"---\n\n# Title\n",                  // thematic break, never closed
+		"+++ plus\n+++\n",                   // fence with trailing text
+		"{{< shortcode >}}\n\n# Title\n",    // template, not JSON
+		"{\"a\": 1} trailing\n\n# Title\n",  // object not alone on its line
+		"# Title\n\n---\nkey: value\n---\n", // not at the top
+	} {
+		if fm := Parse(src).Frontmatter(); fm != nil {
+			t.Errorf("%q: unexpected %s frontmatter %q", src, fm.Format, fm.Raw)
+		}
+	}
+}
+
+func TestFrontmatterMalformedKeepsRaw(t *testing.T) {
+	fm := Parse("+++\ntitle = \n+++\n").Frontmatter()
+	if fm == nil || fm.Raw != "title = " || fm.Fields != nil {
+		t.Errorf("expected raw text without fields, got %+v", fm)
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"fmt"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -15,8 +16,13 @@ import (
 //
 // If the file isn't tracked by git, the ref doesn't exist, or git isn't
 // available, ChangedLines returns an empty set and a nil error — callers
-// should treat "nothing changed" as the fall-through behavior.
+// should treat "nothing changed" as the fall-through behavior. A ref that
+// starts with "-" is rejected with an error, since git would read it as an
+// option rather than a revision.
 func ChangedLines(file, ref string) (map[int]bool, error) {
+	if strings.HasPrefix(ref, "-") {
+		return map[int]bool{}, fmt.Errorf("invalid git ref %q", ref)
+	}
 	cmd := exec.Command("git", "diff", "--unified=0", ref, "--", file)
 	out, err := cmd.Output()
 	if err != nil {
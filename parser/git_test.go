@@ -1,6 +1,10 @@
 package parser
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestParseHunkLines(t *testing.T) {
 	diff := `diff --git a/foo.md b/foo.md
@@ -64,3 +68,13 @@ func TestParseHunkLinesPureDeletion(t *testing.T) {
 		t.Error("expected anchor line 5 to be marked for pure deletion hunk")
 	}
 }
+
+func TestChangedLinesRejectsOptionRefs(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "x")
+	if _, err := ChangedLines("doc.md", "--output="+out); err == nil {
+		t.Error("expected an error for a ref starting with -")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("git wrote %s: %v", out, err)
+	}
+}
@@ -0,0 +1,603 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ParseHTML parses an HTML page into a Document. Sections come from h1–h6;
+// pre blocks, tables, admonitions (aside, .admonition, Confluence
+// information macros), lists, and block quotes map onto the markdown node
+// types. Navigation, scripts, styles, and similar page chrome are dropped.
+// The tokenizer is driven directly, rather than building a DOM, so every
+// node keeps the source lines it came from.
+func ParseHTML(content string) *Document {
+	p := &htmlParser{line: 1}
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break // io.EOF, or input the tokenizer can't continue past
+		}
+		raw := z.Raw()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			p.start(tok, tt == html.SelfClosingTagToken)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			p.end(string(name))
+		case html.TextToken:
+			p.text(string(z.Text()))
+		}
+		p.line += bytes.Count(raw, []byte("\n"))
+	}
+	p.closeTo(0)
+	p.flush()
+
+	doc := &Document{Nodes: p.nodes}
+	doc.Sections, doc.TotalTokens = sectionsFromNodes(doc.Nodes)
+	doc.References = p.refs
+	return doc
+}
+
+// htmlChrome are elements whose content is never part of the document.
+var htmlChrome = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "footer": true, "svg": true, "iframe": true, "form": true, "button": true,
+}
+
+// htmlChromeClasses mark navigation and decoration in Sphinx and
+// Confluence exports.
+var htmlChromeClasses = []string{
+	"headerlink", "sphinxsidebar", "related", "breadcrumb", "footer", "navigation",
+	"admonition-title", "toc-backref",
+}
+
+// htmlVoid elements never have an end tag.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlBlocks end the current paragraph when they open or close.
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "header": true,
+	"aside": true, "blockquote": true, "ul": true, "ol": true, "li": true, "dl": true, "dt": true,
+	"dd": true, "pre": true, "table": true, "figure": true, "figcaption": true, "details": true,
+	"summary": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "body": true, "center": true, "address": true,
+}
+
+// htmlCalloutClasses maps admonition classes to callout variants. Sphinx
+// uses docutils names; Confluence uses information/tip/note/warning macros
+// where "note" is the yellow caution box.
+var htmlCalloutClasses = map[string]CalloutKind{
+	"note": CalloutNote, "seealso": CalloutNote, "information": CalloutNote, "info": CalloutNote,
+	"tip": CalloutTip, "hint": CalloutTip,
+	"important": CalloutImportant,
+	"warning":   CalloutWarning, "attention": CalloutWarning,
+	"caution": CalloutCaution, "danger": CalloutCaution, "error": CalloutCaution,
+}
+
+type htmlElem struct {
+	tag   string
+	skip  bool   // chrome: ignore everything inside
+	frame bool   // pushed a container frame
+	href  string // for <a>
+	code  bool   // inline <code> outside pre
+	bold  bool   // <strong> or <b> in a paragraph
+}
+
+// htmlFrame is an open container node that collects child blocks.
+type htmlFrame struct {
+	node Node
+	base *BaseNode
+}
+
+type htmlTable struct {
+	start   int
+	rows    []*TableRow
+	row     *TableRow
+	inHead  bool
+	header  bool // current cell is a th
+	cellBuf *strings.Builder
+}
+
+type htmlParser struct {
+	line   int
+	stack  []htmlElem
+	skip   int // open chrome elements
+	nodes  []Node
+	frames []htmlFrame
+	refs   []Reference
+
+	// Paragraph under construction.
+	para     *Paragraph
+	run      strings.Builder // plain text of the current inline run
+	raw      strings.Builder // text with `code` and **strong** markers
+	paraText strings.Builder
+	linkBuf  *strings.Builder
+	codeBuf  *strings.Builder
+
+	// Captures for headings, pre blocks, and table cells.
+	heading   *Heading
+	headBuf   *strings.Builder
+	term      *DefinitionTerm
+	termBuf   *strings.Builder
+	pre       *CodeBlock
+	preBuf    *strings.Builder
+	table     *htmlTable
+	pendingID string
+	langHint  string // language from a Sphinx highlight-* wrapper
+}
+
+func (p *htmlParser) start(tok html.Token, selfClosing bool) {
+	tag := tok.Data
+	attr := func(name string) string {
+		for _, a := range tok.Attr {
+			if a.Key == name {
+				return a.Val
+			}
+		}
+		return ""
+	}
+	classes := strings.Fields(attr("class"))
+	void := htmlVoid[tag] || selfClosing
+
+	e := htmlElem{tag: tag}
+	role := attr("role")
+	if htmlChrome[tag] || role == "navigation" || role == "banner" || role == "contentinfo" || role == "search" || hasAnyClass(classes, htmlChromeClasses) {
+		e.skip = true
+	}
+	if p.skip > 0 || e.skip {
+		if !void {
+			if e.skip {
+				p.skip++
+			}
+			p.stack = append(p.stack, e)
+		}
+		return
+	}
+
+	// Optional end tags: a new <p>, <li>, <dt>/<dd>, <tr>, or cell closes
+	// its open sibling.
+	switch tag {
+	case "p":
+		p.closeOpen("p", "div", "section", "blockquote", "li", "dd", "aside", "td", "th")
+	case "li":
+		p.closeOpen("li", "ul", "ol")
+	case "dt", "dd":
+		p.closeOpen("dt", "dl")
+		p.closeOpen("dd", "dl")
+	case "tr":
+		p.closeOpen("tr", "table")
+	case "td", "th":
+		p.closeOpen("td", "tr")
+		p.closeOpen("th", "tr")
+	}
+
+	if htmlBlocks[tag] && p.pre == nil && p.table == nil {
+		p.flush()
+	}
+	if id := attr("id"); id != "" && (tag == "section" || tag == "div") {
+		p.pendingID = id
+	}
+	for _, c := range classes {
+		if lang, ok := strings.CutPrefix(c, "highlight-"); ok && lang != "default" {
+			p.langHint = lang
+		}
+	}
+
+	switch {
+	case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' && p.table == nil:
+		id := attr("id")
+		if id == "" {
+			id = p.pendingID
+		}
+		p.heading = &Heading{BaseNode: BaseNode{NKind: KindHeading, Start: p.line}, Level: int(tag[1] - '0'), ID: id}
+		p.headBuf = &strings.Builder{}
+		p.pendingID = ""
+	case tag == "pre" && p.table == nil:
+		p.pre = &CodeBlock{BaseNode: BaseNode{NKind: KindCodeBlock, Start: p.line}, Fenced: true, Language: htmlLanguage(classes)}
+		p.preBuf = &strings.Builder{}
+	case tag == "code" && p.pre != nil:
+		if lang := htmlLanguage(classes); lang != "" {
+			p.pre.Language = lang
+		}
+	case tag == "code" && p.heading == nil && p.table == nil && p.codeBuf == nil:
+		e.code = true
+		p.flushRun()
+		p.codeBuf = &strings.Builder{}
+	case tag == "table" && p.table == nil:
+		p.table = &htmlTable{start: p.line}
+	case p.table != nil:
+		p.tableStart(tag)
+	case (tag == "strong" || tag == "b") && p.heading == nil && p.pre == nil && p.linkBuf == nil && p.codeBuf == nil:
+		e.bold = true
+		p.flushRun()
+		p.raw.WriteString("**")
+	case tag == "a" && attr("href") != "" && p.heading == nil && p.pre == nil && p.linkBuf == nil:
+		e.href = attr("href")
+		p.flushRun()
+		p.linkBuf = &strings.Builder{}
+	case tag == "aside" || hasAnyClass(classes, []string{"admonition", "confluence-information-macro", "callout", "alert"}):
+		c := &Callout{BaseNode: BaseNode{NKind: KindCallout, Start: p.line}, Variant: htmlCalloutVariant(classes)}
+		p.push(&e, c, &c.BaseNode)
+	case tag == "blockquote":
+		b := &Blockquote{BaseNode: BaseNode{NKind: KindBlockquote, Start: p.line}}
+		p.push(&e, b, &b.BaseNode)
+	case tag == "ul" || tag == "ol":
+		l := &List{BaseNode: BaseNode{NKind: KindList, Start: p.line}, Ordered: tag == "ol", Tight: true, Marker: '-'}
+		if tag == "ol" {
+			l.Marker, l.Start = '.', 1
+		}
+		p.push(&e, l, &l.BaseNode)
+	case tag == "li":
+		li := &ListItem{BaseNode: BaseNode{NKind: KindListItem, Start: p.line}}
+		p.push(&e, li, &li.BaseNode)
+	case tag == "dl":
+		dl := &DefinitionList{BaseNode: BaseNode{NKind: KindDefinitionList, Start: p.line}}
+		p.push(&e, dl, &dl.BaseNode)
+	case tag == "dt":
+		p.term = &DefinitionTerm{BaseNode: BaseNode{NKind: KindDefTerm, Start: p.line}}
+		p.termBuf = &strings.Builder{}
+	case tag == "dd":
+		d := &Definition{BaseNode: BaseNode{NKind: KindDefinition, Start: p.line}}
+		p.push(&e, d, &d.BaseNode)
+	case tag == "hr":
+		p.emit(&ThematicBreak{BaseNode: BaseNode{NKind: KindThematicBreak, Start: p.line, End: p.line}})
+	case tag == "br" && p.pre != nil:
+		p.preBuf.WriteString("\n")
+	case tag == "img" && p.heading == nil && p.pre == nil:
+		alt := attr("alt")
+		img := &Image{BaseNode: BaseNode{NKind: KindImage, Start: p.line, End: p.line, TokCount: estimateTokens(alt)}, URL: attr("src"), Alt: alt}
+		p.flushRun()
+		p.openPara()
+		p.para.Kids = append(p.para.Kids, img)
+	}
+	if !void {
+		p.stack = append(p.stack, e)
+	}
+}
+
+func (p *htmlParser) end(tag string) {
+	for k := len(p.stack) - 1; k >= 0; k-- {
+		if p.stack[k].tag == tag {
+			p.closeTo(k)
+			return
+		}
+	}
+}
+
+// closeOpen closes the innermost open tag unless one of the stop tags is
+// open inside it.
+func (p *htmlParser) closeOpen(tag string, stops ...string) {
+	for k := len(p.stack) - 1; k >= 0; k-- {
+		t := p.stack[k].tag
+		if t == tag {
+			p.closeTo(k)
+			return
+		}
+		for _, s := range stops {
+			if t == s {
+				return
+			}
+		}
+	}
+}
+
+// closeTo pops the stack down to length n, finishing each element.
+func (p *htmlParser) closeTo(n int) {
+	for len(p.stack) > n {
+		e := p.stack[len(p.stack)-1]
+		p.stack = p.stack[:len(p.stack)-1]
+		p.finish(e)
+	}
+}
+
+func (p *htmlParser) finish(e htmlElem) {
+	if e.skip {
+		p.skip--
+		return
+	}
+	if p.skip > 0 {
+		return
+	}
+	tag := e.tag
+	switch {
+	case e.href != "":
+		p.finishLink(e.href)
+		return
+	case e.bold:
+		p.flushRun()
+		p.raw.WriteString("**")
+		return
+	case e.code:
+		if p.codeBuf == nil {
+			return
+		}
+		code := strings.TrimSpace(p.codeBuf.String())
+		p.codeBuf = nil
+		if code != "" {
+			p.openPara()
+			p.para.Kids = append(p.para.Kids, &InlineCode{BaseNode: BaseNode{NKind: KindInlineCode, Start: p.line, End: p.line, TokCount: estimateTokens(code)}, Code: code})
+			p.paraText.WriteString(code)
+			p.raw.WriteString("`" + code + "`")
+		}
+		return
+	case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' && p.heading != nil:
+		h := p.heading
+		h.Title = collapseSpace(p.headBuf.String())
+		h.RawTitle = h.Title
+		h.End = p.line
+		h.TokCount = estimateTokens(h.Title)
+		p.heading, p.headBuf = nil, nil
+		if h.Title != "" {
+			p.emit(h)
+		}
+		return
+	case tag == "pre" && p.pre != nil:
+		cb := p.pre
+		cb.Code = strings.TrimSuffix(strings.TrimPrefix(p.preBuf.String(), "\n"), "\n")
+		cb.End = p.line
+		cb.TokCount = estimateTokens(cb.Code)
+		if cb.Language == "" {
+			cb.Language = p.langHint
+		}
+		p.pre, p.preBuf, p.langHint = nil, nil, ""
+		p.emit(cb)
+		return
+	case p.table != nil:
+		p.tableEnd(tag)
+		return
+	}
+
+	if tag == "dt" && p.term != nil {
+		t := p.term
+		t.Term = collapseSpace(p.termBuf.String())
+		t.End = p.line
+		t.TokCount = estimateTokens(t.Term)
+		p.term, p.termBuf = nil, nil
+		p.emit(t)
+		return
+	}
+	if htmlBlocks[tag] {
+		p.flush()
+	}
+	if e.frame {
+		f := p.frames[len(p.frames)-1]
+		p.frames = p.frames[:len(p.frames)-1]
+		f.base.End = max(p.line, f.base.Start)
+		f.base.TokCount = sumTokens(f.base.Kids)
+		if len(f.base.Kids) > 0 || f.base.NKind != KindListItem {
+			p.emit(f.node)
+		}
+	}
+}
+
+func (p *htmlParser) text(s string) {
+	if p.skip > 0 {
+		return
+	}
+	switch {
+	case p.pre != nil:
+		p.preBuf.WriteString(s)
+		return
+	case p.heading != nil:
+		p.headBuf.WriteString(s)
+		return
+	case p.term != nil:
+		p.termBuf.WriteString(s)
+		return
+	case p.table != nil:
+		if p.table.cellBuf != nil {
+			p.table.cellBuf.WriteString(s)
+		}
+		return
+	}
+	if strings.TrimSpace(s) == "" && p.para == nil {
+		return
+	}
+	if p.linkBuf != nil {
+		p.linkBuf.WriteString(s)
+		return
+	}
+	if p.codeBuf != nil {
+		p.codeBuf.WriteString(s)
+		return
+	}
+	p.openPara()
+	p.run.WriteString(s)
+}
+
+func (p *htmlParser) openPara() {
+	if p.para == nil {
+		p.para = &Paragraph{BaseNode: BaseNode{NKind: KindParagraph, Start: p.line}}
+	}
+}
+
+// flushRun turns pending plain text into a Text child of the paragraph.
+func (p *htmlParser) flushRun() {
+	s := p.run.String()
+	p.run.Reset()
+	if strings.TrimSpace(s) == "" {
+		if s != "" {
+			p.paraText.WriteString(" ")
+			p.raw.WriteString(" ")
+		}
+		return
+	}
+	p.openPara()
+	v := collapseSpace(s)
+	p.para.Kids = append(p.para.Kids, &Text{BaseNode: BaseNode{NKind: KindText, Start: p.line, End: p.line, TokCount: estimateTokens(v)}, Value: v})
+	p.paraText.WriteString(s)
+	p.raw.WriteString(s)
+}
+
+func (p *htmlParser) finishLink(href string) {
+	if p.linkBuf == nil {
+		return
+	}
+	text := collapseSpace(p.linkBuf.String())
+	p.linkBuf = nil
+	if text == "" {
+		return
+	}
+	p.openPara()
+	p.para.Kids = append(p.para.Kids, &Link{BaseNode: BaseNode{NKind: KindLink, Start: p.line, End: p.line, TokCount: estimateTokens(text)}, URL: href, Text: text})
+	p.paraText.WriteString(text)
+	p.raw.WriteString(text)
+	if target := htmlRefTarget(href); target != "" {
+		p.refs = append(p.refs, Reference{Text: text, Target: target, Line: p.line})
+	}
+}
+
+// flush emits the paragraph under construction.
+func (p *htmlParser) flush() {
+	if p.linkBuf != nil || p.codeBuf != nil {
+		return
+	}
+	p.flushRun()
+	para := p.para
+	text, raw := collapseSpace(p.paraText.String()), collapseSpace(p.raw.String())
+	p.para = nil
+	p.paraText.Reset()
+	p.raw.Reset()
+	if para == nil || (text == "" && len(para.Kids) == 0) {
+		return
+	}
+	para.Text, para.Raw = text, raw
+	para.End = p.line
+	para.TokCount = estimateTokens(text)
+	p.emit(para)
+}
+
+// emit appends n to the innermost open container, or the document.
+func (p *htmlParser) emit(n Node) {
+	if len(p.frames) > 0 {
+		f := p.frames[len(p.frames)-1]
+		f.base.Kids = append(f.base.Kids, n)
+		return
+	}
+	p.nodes = append(p.nodes, n)
+}
+
+func (p *htmlParser) push(e *htmlElem, n Node, base *BaseNode) {
+	e.frame = true
+	p.frames = append(p.frames, htmlFrame{node: n, base: base})
+}
+
+func (p *htmlParser) tableStart(tag string) {
+	t := p.table
+	switch tag {
+	case "thead":
+		t.inHead = true
+	case "tr":
+		t.row = &TableRow{BaseNode: BaseNode{NKind: KindTableRow, Start: p.line}, IsHeader: t.inHead}
+	case "th", "td":
+		if t.row == nil {
+			t.row = &TableRow{BaseNode: BaseNode{NKind: KindTableRow, Start: p.line}, IsHeader: t.inHead}
+		}
+		t.header = tag == "th"
+		t.cellBuf = &strings.Builder{}
+	}
+}
+
+func (p *htmlParser) tableEnd(tag string) {
+	t := p.table
+	switch tag {
+	case "thead":
+		t.inHead = false
+	case "th", "td":
+		if t.cellBuf == nil || t.row == nil {
+			return
+		}
+		text := collapseSpace(t.cellBuf.String())
+		t.cellBuf = nil
+		t.row.Kids = append(t.row.Kids, tableCell(text, p.line, p.line))
+		t.row.TokCount += estimateTokens(text)
+		if !t.header {
+			t.row.IsHeader = t.inHead
+		} else if len(t.row.Kids) == 1 {
+			t.row.IsHeader = true
+		}
+	case "tr":
+		if t.row != nil && len(t.row.Kids) > 0 {
+			t.row.End = p.line
+			t.rows = append(t.rows, t.row)
+		}
+		t.row = nil
+	case "table":
+		tbl := &Table{BaseNode: BaseNode{NKind: KindTable, Start: t.start, End: p.line}}
+		for _, r := range t.rows {
+			tbl.Kids = append(tbl.Kids, r)
+			tbl.TokCount += r.TokCount
+		}
+		tbl.Headers = tableHeaders(tbl)
+		p.table = nil
+		if len(tbl.Kids) > 0 {
+			p.emit(tbl)
+		}
+	}
+}
+
+// htmlLanguage finds a language-* or lang-* class, as emitted by most
+// highlighters and by markdown-to-HTML converters.
+func htmlLanguage(classes []string) string {
+	for _, c := range classes {
+		for _, prefix := range []string{"language-", "lang-", "highlight-"} {
+			if lang, ok := strings.CutPrefix(c, prefix); ok && lang != "" && lang != "default" {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// htmlCalloutVariant picks the callout variant from an admonition's
+// classes, trying registered custom variants first.
+func htmlCalloutVariant(classes []string) CalloutKind {
+	for _, c := range classes {
+		name := strings.ToLower(c[strings.LastIndex(c, "-")+1:])
+		if customCallouts[name] {
+			return CalloutKind(name)
+		}
+		if v, ok := htmlCalloutClasses[name]; ok {
+			return v
+		}
+	}
+	return CalloutNote
+}
+
+// htmlRefTarget returns the document an href points at, or "" for
+// external links, in-page anchors, and non-document schemes.
+func htmlRefTarget(href string) string {
+	if strings.Contains(href, "://") || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "//") {
+		return ""
+	}
+	if scheme, _, ok := strings.Cut(href, ":"); ok && !strings.ContainsAny(scheme, "/.?#") {
+		return "" // mailto:, javascript:, tel:
+	}
+	if idx := strings.IndexAny(href, "?#"); idx >= 0 {
+		href = href[:idx]
+	}
+	return href
+}
+
+func hasAnyClass(classes, want []string) bool {
+	for _, c := range classes {
+		for _, w := range want {
+			if c == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const htmlSample = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Guide</title>
+  <style>body { color: red; }</style>
+  <script>var x = "<h1>not a heading</h1>";</script>
+</head>
+<body>
+<nav><ul><li><a href="other.html">Other</a></li></ul></nav>
+<div class="sphinxsidebar"><h3>Navigation</h3></div>
+<section id="install">
+<h1>Install<a class="headerlink" href="#install">¶</a></h1>
+<p>Read the <a href="setup.html#prereqs">setup guide</a> and run <code>make</code>.
+It is <strong>fast</strong>. See <a href="https://example.com">the site</a>.</p>
+<div class="admonition warning">
+<p class="admonition-title">Warning</p>
+<p>Back up first.</p>
+</div>
+<div class="highlight-python notranslate"><div class="highlight"><pre><span></span>import os
+print(os.getcwd())
+</pre></div></div>
+<h2 id="config">Config</h2>
+<table>
+<thead><tr><th>Key</th><th>Default</th></tr></thead>
+<tbody>
+<tr><td>port</td><td>8080</td></tr>
+</tbody>
+</table>
+<pre><code class="language-go">package main
+</code></pre>
+<aside><p>Side note.</p></aside>
+<ul>
+<li>one
+<li>two
+</ul>
+</section>
+<footer><a href="legal.html">Legal</a></footer>
+</body>
+</html>
+`
+
+func TestParseHTMLSections(t *testing.T) {
+	doc := ParseHTML(htmlSample)
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Install" {
+		t.Fatalf("expected only Install at the top level, got %+v", doc.Sections)
+	}
+	install := doc.Sections[0]
+	if install.LineStart != 12 {
+		t.Errorf("Install starts on line %d, want 12", install.LineStart)
+	}
+	if len(install.Children) != 1 || install.Children[0].Title != "Config" || install.Children[0].LineStart != 22 {
+		t.Errorf("expected Config on line 22 under Install, got %+v", install.Children)
+	}
+	for _, n := range doc.Nodes {
+		if h, ok := n.(*Heading); ok && h.Title == "Install" && h.ID != "install" {
+			t.Errorf("Install should take its section id, got %q", h.ID)
+		}
+	}
+
+	var para *Paragraph
+	for _, n := range doc.Nodes {
+		if p, ok := n.(*Paragraph); ok && para == nil {
+			para = p
+		}
+	}
+	if para == nil || para.LineStart() != 13 || para.LineEnd() != 14 {
+		t.Fatalf("expected the intro paragraph on lines 13-14, got %+v", para)
+	}
+	if want := "Read the setup guide and run `make`. It is **fast**. See the site."; para.Raw != want {
+		t.Errorf("paragraph raw = %q, want %q", para.Raw, want)
+	}
+}
+
+func TestParseHTMLBlocks(t *testing.T) {
+	doc := ParseHTML(htmlSample)
+	install := doc.Sections[0]
+
+	var variants []string
+	var codes []*CodeBlock
+	for _, n := range append(install.Notables, install.Children[0].Notables...) {
+		switch v := n.(type) {
+		case *Callout:
+			variants = append(variants, string(v.Variant))
+		case *CodeBlock:
+			codes = append(codes, v)
+		}
+	}
+	if got := strings.Join(variants, ","); got != "warning,note" {
+		t.Errorf("callouts = %s, want warning,note", got)
+	}
+	if len(codes) != 2 {
+		t.Fatalf("expected 2 code blocks, got %d", len(codes))
+	}
+	if codes[0].Language != "python" || codes[0].Code != "import os\nprint(os.getcwd())" || codes[0].LineStart() != 19 {
+		t.Errorf("highlight-python block: %+v", codes[0])
+	}
+	if codes[1].Language != "go" || codes[1].Code != "package main" {
+		t.Errorf("language-go block: %+v", codes[1])
+	}
+
+	var table *Table
+	for _, n := range install.Children[0].Notables {
+		if tbl, ok := n.(*Table); ok {
+			table = tbl
+		}
+	}
+	if table == nil || strings.Join(table.Headers, "|") != "Key|Default" || len(table.Kids) != 2 {
+		t.Fatalf("unexpected table: %+v", table)
+	}
+	if table.LineStart() != 23 || table.LineEnd() != 28 {
+		t.Errorf("table spans %d-%d, want 23-28", table.LineStart(), table.LineEnd())
+	}
+
+	var list *List
+	for _, n := range doc.Nodes {
+		if l, ok := n.(*List); ok {
+			list = l
+		}
+	}
+	if list == nil || len(list.Kids) != 2 {
+		t.Errorf("unclosed <li> tags should still give two items, got %+v", list)
+	}
+}
+
+func TestParseHTMLReferences(t *testing.T) {
+	doc := ParseHTML(htmlSample)
+	if len(doc.References) != 1 {
+		t.Fatalf("expected one reference (nav, footer and external links skipped), got %+v", doc.References)
+	}
+	if r := doc.References[0]; r.Target != "setup.html" || r.Text != "setup guide" || r.Line != 13 {
+		t.Errorf("reference = %+v", r)
+	}
+}
+
+func TestParseHTMLNestedInline(t *testing.T) {
+	doc := ParseHTML("<h1>T</h1>\n<p><a href=\"x.html\"><a href=\"y.html\">link</p>\n<p><code>a<code>b</code></code> done</p>\n")
+	if len(doc.References) != 1 || doc.References[0].Target != "x.html" || doc.References[0].Text != "link" {
+		t.Errorf("nested link references = %+v", doc.References)
+	}
+	if c := doc.Sections[0].Content; !strings.Contains(c, "`ab`") {
+		t.Errorf("nested code content = %q", c)
+	}
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// IndexField is one of the separately weighted parts of a section in the
+// search index.
+type IndexField int
+
+const (
+	FieldTitle IndexField = iota
+	FieldBody
+	FieldCode
+	FieldCallout
+	NumIndexFields
+)
+
+// fieldWeights boosts a term occurrence by the field it appears in.
+var fieldWeights = [NumIndexFields]float64{
+	FieldTitle:   3,
+	FieldBody:    1,
+	FieldCode:    1,
+	FieldCallout: 1.5,
+}
+
+// BM25 parameters: term-frequency saturation and length normalization.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// SearchIndex is an inverted index over a document's sections, numbered in
+// GetAllSections order. It's built once per parse and cached with the
+// Document, so ranked search only has to combine the per-document
+// statistics.
+type SearchIndex struct {
+	Postings map[string][]Posting
+	Lengths  [][NumIndexFields]int // terms in each field, per section
+}
+
+// Posting records how often a term occurs in each field of one section.
+type Posting struct {
+	Section int
+	Freq    [NumIndexFields]int
+}
+
+// IndexTerms splits text into lowercase index terms: runs of letters,
+// digits and underscores, at least two characters long.
+func IndexTerms(text string) []string {
+	var terms []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(f) > 1 {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// BuildIndex (re)builds the document's search index.
+func (d *Document) BuildIndex() {
+	idx := &SearchIndex{Postings: map[string][]Posting{}}
+	for i, s := range d.GetAllSections() {
+		var freqs map[string]*[NumIndexFields]int
+		var lengths [NumIndexFields]int
+		add := func(f IndexField, text string) {
+			for _, t := range IndexTerms(text) {
+				if freqs == nil {
+					freqs = map[string]*[NumIndexFields]int{}
+				}
+				if freqs[t] == nil {
+					freqs[t] = new([NumIndexFields]int)
+				}
+				freqs[t][f]++
+				lengths[f]++
+			}
+		}
+		add(FieldTitle, s.Title)
+		var code, callouts []string
+		for _, n := range s.Notables {
+			switch v := n.(type) {
+			case *CodeBlock:
+				code = append(code, v.Language, v.Code)
+			case *Callout:
+				callouts = append(callouts, string(v.Variant), nodeRaw(v))
+			}
+		}
+		add(FieldCode, strings.Join(code, "\n"))
+		add(FieldCallout, strings.Join(callouts, "\n"))
+		add(FieldBody, s.Content)
+		// Content holds the code and callout text too; count it once, in
+		// its own field.
+		for _, fr := range freqs {
+			dup := min(fr[FieldBody], fr[FieldCode]+fr[FieldCallout])
+			fr[FieldBody] -= dup
+			lengths[FieldBody] -= dup
+		}
+		for t, fr := range freqs {
+			idx.Postings[t] = append(idx.Postings[t], Posting{Section: i, Freq: *fr})
+		}
+		idx.Lengths = append(idx.Lengths, lengths)
+	}
+	d.Index = idx
+}
+
+// SearchIndex returns the document's index, building it if the parse
+// didn't.
+func (d *Document) SearchIndex() *SearchIndex {
+	if d.Index == nil {
+		d.BuildIndex()
+	}
+	return d.Index
+}
+
+// ScoreSections ranks the sections of docs against the query terms with
+// BM25F: each field's term frequency is length-normalized and weighted
+// before saturation, and IDF comes from the sections of all docs. With
+// prefix set, a term also matches longer index terms it begins ("auth"
+// finds "authentication") at half weight. Each query term scores a section
+// once, by its best expansion there, so prefix hits never add to an exact
+// one. fields limits scoring to those fields (all when empty). Sections
+// without any term are left out.
+func ScoreSections(docs []*Document, terms []string, prefix bool, fields ...IndexField) map[*Section]float64 {
+	scores := map[*Section]float64{}
+	if len(terms) == 0 {
+		return scores
+	}
+	var use [NumIndexFields]bool
+	for f := range use {
+		use[f] = len(fields) == 0
+	}
+	for _, f := range fields {
+		use[f] = true
+	}
+
+	// Corpus statistics: section count and average field lengths.
+	var n int
+	var avg [NumIndexFields]float64
+	sections := make([][]*Section, len(docs))
+	for i, d := range docs {
+		sections[i] = d.GetAllSections()
+		for _, l := range d.SearchIndex().Lengths {
+			n++
+			for f := range l {
+				avg[f] += float64(l[f])
+			}
+		}
+	}
+	if n == 0 {
+		return scores
+	}
+	for f := range avg {
+		avg[f] /= float64(n)
+	}
+
+	for _, q := range terms {
+		// Expand the query term to the index terms it matches.
+		expanded := map[string]float64{}
+		for _, d := range docs {
+			for t := range d.Index.Postings {
+				switch {
+				case t == q:
+					expanded[t] = 1
+				case prefix && strings.HasPrefix(t, q):
+					expanded[t] = 0.5
+				}
+			}
+		}
+
+		// The query term's weighted frequency in each section is that of
+		// its best expansion, and its IDF counts every section holding any.
+		best := map[*Section]float64{}
+		for t, w := range expanded {
+			for i, d := range docs {
+				for _, p := range d.Index.Postings[t] {
+					if p.Section >= len(sections[i]) {
+						continue
+					}
+					tf := 0.0
+					for f := IndexField(0); f < NumIndexFields; f++ {
+						if !use[f] || p.Freq[f] == 0 {
+							continue
+						}
+						norm := 1.0
+						if avg[f] > 0 {
+							norm = 1 - bm25B + bm25B*float64(d.Index.Lengths[p.Section][f])/avg[f]
+						}
+						tf += fieldWeights[f] * float64(p.Freq[f]) / norm
+					}
+					if tf > 0 {
+						s := sections[i][p.Section]
+						best[s] = max(best[s], w*tf)
+					}
+				}
+			}
+		}
+		df := float64(len(best))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+		for s, tf := range best {
+			scores[s] += idf * tf * (bm25K1 + 1) / (bm25K1 + tf)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndexTerms(t *testing.T) {
+	got := IndexTerms("Rotate the JWT-signing key (v2), a_b x")
+	want := []string{"rotate", "the", "jwt", "signing", "key", "v2", "a_b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBuildIndexFields(t *testing.T) {
+	doc := Parse("# Tokens\n\nTokens expire.\n\n```go\ntoken := jwt.New()\n```\n\n> [!WARNING]\n> Rotate the jwt key.\n")
+	idx := doc.SearchIndex()
+
+	freq := func(term string) [NumIndexFields]int {
+		t.Helper()
+		ps := idx.Postings[term]
+		if len(ps) != 1 {
+			t.Fatalf("%q: %d postings, want 1", term, len(ps))
+		}
+		return ps[0].Freq
+	}
+	if f := freq("tokens"); f[FieldTitle] != 1 || f[FieldBody] != 1 {
+		t.Errorf("tokens: %v", f)
+	}
+	// Code and callout text is counted in its own field, not again as body.
+	if f := freq("jwt"); f[FieldCode] != 1 || f[FieldCallout] != 1 || f[FieldBody] != 0 {
+		t.Errorf("jwt: %v", f)
+	}
+	if f := freq("warning"); f[FieldCallout] == 0 {
+		t.Errorf("callout variant not indexed: %v", f)
+	}
+}
+
+func TestScoreSections(t *testing.T) {
+	doc := Parse("# Guide\n\nIntro.\n\n## Setup\n\nMention of authentication once, among many other words here.\n\n## Authentication\n\nLog in first.\n\n## Other\n\nNothing relevant.\n")
+	byTitle := map[string]*Section{}
+	for _, s := range doc.GetAllSections() {
+		byTitle[s.Title] = s
+	}
+
+	scores := ScoreSections([]*Document{doc}, []string{"authentication"}, false)
+	if len(scores) != 2 {
+		t.Fatalf("scored %d sections, want 2", len(scores))
+	}
+	if scores[byTitle["Authentication"]] <= scores[byTitle["Setup"]] {
+		t.Errorf("title hit %.3f should outrank body hit %.3f",
+			scores[byTitle["Authentication"]], scores[byTitle["Setup"]])
+	}
+
+	if got := ScoreSections([]*Document{doc}, []string{"auth"}, false); len(got) != 0 {
+		t.Errorf("exact terms matched a prefix: %v", got)
+	}
+	prefix := ScoreSections([]*Document{doc}, []string{"auth"}, true)
+	if prefix[byTitle["Authentication"]] >= scores[byTitle["Authentication"]] {
+		t.Error("prefix match should score below an exact match")
+	}
+}
@@ -27,9 +27,11 @@ func Parse(content string) *Document {
 // sectionsFromNodes flattens the top-level AST in document order, turning
 // every Heading into a Section and attaching the blocks that follow it
 // (until the next heading) as that section's content, notables, and stats.
+// Blocks before the first heading, other than frontmatter, go to a level-0
+// preamble section at the front of the tree.
 func sectionsFromNodes(nodes []Node) ([]*Section, int) {
 	var all []*Section
-	var current *Section
+	var current, preamble *Section
 	var contentBuf strings.Builder
 
 	finalize := func(endLine int) {
@@ -58,9 +60,11 @@ func sectionsFromNodes(nodes []Node) ([]*Section, int) {
 			continue
 		}
 		if current == nil {
-			// Blocks before the first heading (frontmatter, intro paragraphs)
-			// are ignored by the legacy Section view.
-			continue
+			if _, ok := n.(*Frontmatter); ok {
+				continue
+			}
+			preamble = &Section{Title: PreambleTitle, LineStart: n.LineStart()}
+			current = preamble
 		}
 		contentBuf.WriteString(nodeRaw(n))
 		contentBuf.WriteString("\n")
@@ -74,6 +78,10 @@ func sectionsFromNodes(nodes []Node) ([]*Section, int) {
 	finalize(0)
 
 	roots := buildTree(all)
+	if preamble != nil {
+		roots = append([]*Section{preamble}, roots...)
+		all = append(all, preamble)
+	}
 
 	// Parent sections' LineEnd only reflects their own direct content,
 	// stopping where the first child subsection begins. Extend LineEnd so
@@ -243,8 +251,10 @@ type Document struct {
 	Filename    string
 	TotalTokens int
 	Sections    []*Section
-	References  []Reference // Links to other .md files
-	Nodes       []Node      // Typed AST (populated by the new parser)
+	References  []Reference  // Links to other .md files
+	Nodes       []Node       // Typed AST (populated by the new parser)
+	Cells       []Cell       // Notebook cells by rendered line (notebooks only)
+	Index       *SearchIndex // Section index for ranked search; see BuildIndex
 }
 
 // Reference represents a link to another markdown file
@@ -266,7 +276,7 @@ type Reference struct {
 // (task list items, Obsidian wiki links, Obsidian embeds). They are
 // rendered as a single summary line per section.
 type Section struct {
-	Level     int // 1 = #, 2 = ##, etc.
+	Level     int // 1 = #, 2 = ##, etc.; 0 for the preamble
 	Title     string
 	Content   string   // raw content (excluding children)
 	Tokens    int      // estimated tokens for this section
@@ -279,6 +289,16 @@ type Section struct {
 	Stats     NotableStats
 }
 
+// PreambleTitle is the title of the level-0 section holding the content
+// before a document's first heading.
+const PreambleTitle = "(preamble)"
+
+// IsPreamble reports whether s is the synthetic section for the content
+// before the first heading rather than a real heading.
+func (s *Section) IsPreamble() bool {
+	return s.Level == 0 && s.Parent == nil && s.Title == PreambleTitle
+}
+
 // NotableStats aggregates counts of constructs that would be noisy if
 // listed per-instance under a section.
 type NotableStats struct {
@@ -292,11 +312,6 @@ type NotableStats struct {
 	Emojis       int
 }
 
-// Token estimation: ~4 chars per token (rough approximation)
-func estimateTokens(s string) int {
-	return len(s) / 4
-}
-
 // buildTree organizes flat sections into a tree based on heading levels
 func buildTree(sections []*Section) []*Section {
 	if len(sections) == 0 {
@@ -377,20 +392,11 @@ func extractKeyTerms(content string) []string {
 	return terms
 }
 
-// GetSection finds a section by name (case-insensitive partial match)
+// GetSection finds a section by name: the best of MatchSections, so a
+// whole-title match beats an earlier partial one.
 func (d *Document) GetSection(name string) *Section {
-	name = strings.ToLower(name)
-	return findSection(d.Sections, name)
-}
-
-func findSection(sections []*Section, name string) *Section {
-	for _, s := range sections {
-		if strings.Contains(strings.ToLower(s.Title), name) {
-			return s
-		}
-		if found := findSection(s.Children, name); found != nil {
-			return found
-		}
+	if found := d.MatchSections(name); len(found) > 0 {
+		return found[0].Section
 	}
 	return nil
 }
@@ -430,6 +436,29 @@ func (d *Document) Summary() ContentSummary {
 			case *LinkRefDef:
 				s.LinkRefDefs++
 				return false
+			case *Operation:
+				s.Operations++
+				return false
+			case *Anchor:
+				s.Anchors++
+				return false
+			case *Alias:
+				s.Aliases++
+				return false
+			case *ConfigItem:
+				switch v.Kind() {
+				case KindResource:
+					s.Resources++
+				case KindContainer:
+					s.Containers++
+				case KindService:
+					s.Services++
+				case KindJob:
+					s.Jobs++
+				case KindStep:
+					s.Steps++
+				}
+				return false
 			case *TaskItem:
 				s.Tasks++
 				if v.Checked {
@@ -16,7 +16,7 @@ import (
 
 // parseWithGoldmark parses source into docmap Node values using goldmark
 // for the CommonMark/GFM core and post-passes for everything goldmark does
-// not natively handle: YAML frontmatter, math ($$ and \[), GFM callouts,
+// not natively handle: YAML/TOML/JSON frontmatter, math ($$ and \[), GFM callouts,
 // Obsidian wiki links and embeds, HTML entities, @mentions, #issue refs,
 // commit SHAs, :emoji:, and hard-line-break classification.
 func parseWithGoldmark(source []byte) []Node {
@@ -77,60 +77,6 @@ func parseWithGoldmark(source []byte) []Node {
 	return nodes
 }
 
-// ---------- Frontmatter splitter ----------
-
-var frontmatterFence = regexp.MustCompile(`(?m)\A---\r?\n`)
-
-// splitFrontmatter peels a `---\n...\n---\n` YAML block off the top of source.
-// It returns the Frontmatter node, the remaining body, and how many lines
-// were consumed (so downstream line numbers can be shifted back).
-func splitFrontmatter(source []byte) (Node, []byte, int) {
-	if !frontmatterFence.Match(source) {
-		return nil, source, 0
-	}
-	// Find the closing fence after the opening one.
-	rest := source[4:] // skip "---\n" (len 4 for unix LF)
-	// Handle CRLF opener.
-	if len(source) >= 5 && source[3] == '\r' {
-		rest = source[5:]
-	}
-	closingIdx := bytes.Index(rest, []byte("\n---"))
-	if closingIdx < 0 {
-		return nil, source, 0
-	}
-	// Make sure the closing fence is at a line boundary followed by newline or EOF.
-	after := closingIdx + 4 // past "\n---"
-	if after < len(rest) && rest[after] != '\n' && rest[after] != '\r' {
-		return nil, source, 0
-	}
-
-	raw := string(rest[:closingIdx])
-	// Count consumed lines in the original source (opening fence through closing).
-	consumedEnd := len(source) - len(rest) + after
-	if consumedEnd < len(source) && source[consumedEnd] == '\n' {
-		consumedEnd++
-	} else if consumedEnd+1 < len(source) && source[consumedEnd] == '\r' && source[consumedEnd+1] == '\n' {
-		consumedEnd += 2
-	}
-	consumedLines := bytes.Count(source[:consumedEnd], []byte{'\n'})
-
-	// Replace the consumed prefix with blank lines so line numbers in the
-	// remaining body stay stable relative to the original source.
-	body := append(bytes.Repeat([]byte{'\n'}, consumedLines), source[consumedEnd:]...)
-
-	fm := &Frontmatter{
-		BaseNode: BaseNode{
-			NKind:    KindFrontmatter,
-			Start:    1,
-			End:      consumedLines,
-			TokCount: estimateTokens(raw),
-		},
-		Format: FrontmatterYAML,
-		Raw:    raw,
-	}
-	return fm, body, consumedLines
-}
-
 // ---------- Line number helper ----------
 
 func lineAt(source []byte, offset int) int {
@@ -186,6 +132,11 @@ func (c *converter) rangeOf(n ast.Node) (int, int) {
 		}
 		return ast.WalkContinue, nil
 	})
+	// Blocks with no text at all (a bare `##`) still know where they began.
+	if start == 0 && n.Type() == ast.TypeBlock && n.Pos() >= 0 {
+		start = lineAt(c.source, n.Pos())
+		end = start
+	}
 	return start, end
 }
 
@@ -664,7 +615,37 @@ func collectLinkRefDefs(source []byte) []Node {
 
 // ---------- Post-pass: GFM callouts ----------
 
-var calloutRe = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*`)
+var (
+	calloutRe       = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*`)
+	customCalloutRe = regexp.MustCompile(`^\[!([A-Za-z][\w-]*)\]`)
+	customCallouts  map[string]bool
+)
+
+// SetCalloutVariants registers extra callout variants recognized alongside
+// the five GFM alerts, e.g. "danger" for > [!DANGER]. Names match
+// case-insensitively and become the Callout's lowercase Variant. Call it
+// before parsing; it must not change while documents are being parsed.
+func SetCalloutVariants(names []string) {
+	customCallouts = nil
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			if customCallouts == nil {
+				customCallouts = map[string]bool{}
+			}
+			customCallouts[name] = true
+		}
+	}
+}
+
+// CalloutVariants returns the custom callout variants in sorted order.
+func CalloutVariants() []string {
+	names := make([]string, 0, len(customCallouts))
+	for name := range customCallouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
 // detectCallouts walks the tree looking for Blockquotes whose first paragraph
 // begins with a GFM alert marker, and upgrades them to Callout nodes.
@@ -703,6 +684,9 @@ func calloutVariant(bq *Blockquote) CalloutKind {
 	first := strings.TrimSpace(strings.SplitN(p.Text, "\n", 2)[0])
 	m := calloutRe.FindStringSubmatch(first)
 	if m == nil {
+		if m = customCalloutRe.FindStringSubmatch(first); m != nil && customCallouts[strings.ToLower(m[1])] {
+			return CalloutKind(strings.ToLower(m[1]))
+		}
 		return ""
 	}
 	switch m[1] {
@@ -764,6 +748,7 @@ func postProcessInline(nodes []Node) {
 // existing is consulted so we don't double-emit things goldmark already gave
 // us as children (inline links, angle-bracket autolinks).
 func scanInline(text string, line int, existing []Node) []Node {
+	text = maskCodeSpans(text)
 	var out []Node
 	base := func(k NodeKind) BaseNode {
 		return BaseNode{NKind: k, Start: line, End: line}
@@ -894,6 +879,20 @@ func scanInline(text string, line int, existing []Node) []Node {
 	return out
 }
 
+// codeSpanRe matches single- and double-backtick inline code spans.
+var codeSpanRe = regexp.MustCompile("``[^\n]*?``|`[^`\n]*`")
+
+// maskCodeSpans blanks out inline code so `[[not a link]]` or `:not_emoji:`
+// inside backticks isn't scanned. Lengths are preserved.
+func maskCodeSpans(text string) string {
+	if !strings.Contains(text, "`") {
+		return text
+	}
+	return codeSpanRe.ReplaceAllStringFunc(text, func(span string) string {
+		return strings.Repeat(" ", len(span))
+	})
+}
+
 func parseEmbedSize(s string) (int, int) {
 	if s == "" {
 		return 0, 0
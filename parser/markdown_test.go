@@ -125,6 +125,37 @@ Usage info.
 	}
 }
 
+func TestPreambleSection(t *testing.T) {
+	content := "---\ntitle: x\n---\n[![CI](https://ci/badge.svg)](https://ci)\n\n> [!WARNING]\n> Beta.\n\n# Preamble\n\nText.\n"
+	doc := Parse(content)
+	if len(doc.Sections) != 2 {
+		t.Fatalf("expected preamble + 1 root section, got %d", len(doc.Sections))
+	}
+	pre := doc.Sections[0]
+	if !pre.IsPreamble() || pre.Level != 0 || pre.Title != PreambleTitle {
+		t.Fatalf("first section = %+v, want the preamble", pre)
+	}
+	if pre.LineStart != 4 || pre.LineEnd != 7 {
+		t.Errorf("preamble spans %d-%d, want 4-7 (after the frontmatter)", pre.LineStart, pre.LineEnd)
+	}
+	if len(pre.Notables) != 1 || pre.Notables[0].Kind() != KindCallout || pre.Tokens == 0 {
+		t.Errorf("preamble should carry the callout and its tokens, got %+v", pre)
+	}
+	if got := doc.GetSection("(preamble)"); got != pre {
+		t.Errorf("GetSection((preamble)) = %+v", got)
+	}
+	if got := doc.GetSection("preamble"); got != doc.Sections[1] {
+		t.Errorf("GetSection(preamble) should prefer the exact heading, got %+v", got)
+	}
+	if s := doc.SectionByAnchor("preamble"); s == nil || s.IsPreamble() {
+		t.Errorf("#preamble should name the real heading, got %+v", s)
+	}
+
+	if doc := Parse("---\ntitle: x\n---\n# Title\n"); len(doc.Sections) != 1 {
+		t.Errorf("frontmatter alone shouldn't make a preamble, got %d sections", len(doc.Sections))
+	}
+}
+
 func TestGetAllSections(t *testing.T) {
 	content := `# One
 
@@ -191,3 +222,50 @@ Also check [external](https://example.com).
 	}
 	t.Error("expected api.md reference with anchor stripped")
 }
+
+func TestCodeSpansAreNotScanned(t *testing.T) {
+	content := "# Notes\n\nWrite `[[Page]]` or ``:smile: [[Other]]`` for a link; see [[Real]] :tada:.\n"
+
+	doc := Parse(content)
+
+	var wiki []string
+	var emoji int
+	for _, root := range doc.Nodes {
+		Walk(root, func(n Node) bool {
+			switch v := n.(type) {
+			case *WikiLink:
+				wiki = append(wiki, v.Target)
+			case *Emoji:
+				emoji++
+			}
+			return true
+		})
+	}
+	if len(wiki) != 1 || wiki[0] != "Real" {
+		t.Errorf("expected only [[Real]] outside code spans, got %v", wiki)
+	}
+	if emoji != 1 {
+		t.Errorf("expected 1 emoji outside code spans, got %d", emoji)
+	}
+}
+
+func TestCustomCalloutVariants(t *testing.T) {
+	content := "# Doc\n\n> [!DANGER]\n> Hot.\n\n> [!Info] Heads up\n\n> [!UNKNOWN]\n> Plain quote.\n"
+
+	SetCalloutVariants([]string{"danger", " INFO "})
+	defer SetCalloutVariants(nil)
+
+	if got := CalloutVariants(); len(got) != 2 || got[0] != "danger" || got[1] != "info" {
+		t.Errorf("CalloutVariants() = %v", got)
+	}
+	doc := Parse(content)
+	var variants []string
+	for _, n := range doc.Nodes {
+		if c, ok := n.(*Callout); ok {
+			variants = append(variants, string(c.Variant))
+		}
+	}
+	if len(variants) != 2 || variants[0] != "danger" || variants[1] != "info" {
+		t.Errorf("expected danger and info callouts, got %v", variants)
+	}
+}
@@ -25,6 +25,14 @@ const (
 	KindDefinition     NodeKind = "definition"
 	KindFootnoteDef    NodeKind = "footnote_def"
 	KindLinkRefDef     NodeKind = "link_ref_def"
+	KindOperation      NodeKind = "operation"
+	KindAnchor         NodeKind = "anchor"
+	KindAlias          NodeKind = "alias"
+	KindResource       NodeKind = "resource"
+	KindContainer      NodeKind = "container"
+	KindService        NodeKind = "service"
+	KindJob            NodeKind = "job"
+	KindStep           NodeKind = "step"
 
 	KindText        NodeKind = "text"
 	KindEmphasis    NodeKind = "emphasis"
@@ -107,10 +115,12 @@ func (b *BaseNode) Children() []Node { return b.Kids }
 // ---------- Block-level nodes ----------
 
 // Frontmatter is a YAML/TOML/JSON header block at the top of a file.
+// Fields holds the decoded block, or nil when it's empty or malformed.
 type Frontmatter struct {
 	BaseNode
 	Format FrontmatterFormat
 	Raw    string
+	Fields map[string]any
 }
 
 // Heading is an ATX (# Title) or Setext (underline) heading.
@@ -190,6 +200,8 @@ type CodeBlock struct {
 	Info     string
 	Fenced   bool
 	Code     string
+	Cell     int          // 1-based notebook cell index; 0 outside notebooks
+	Outputs  *CellOutputs // a notebook code cell's saved outputs
 }
 
 // MathBlock is a block-level math expression ($$...$$ or \[...\]).
@@ -240,6 +252,55 @@ type LinkRefDef struct {
 	Title string
 }
 
+// Operation is an API operation in an OpenAPI or AsyncAPI spec. Method is
+// the HTTP method, or the AsyncAPI action (PUBLISH, SUBSCRIBE, SEND,
+// RECEIVE); Path is the path template or channel.
+type Operation struct {
+	BaseNode
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Tags        []string
+	Parameters  []string // "id (path)"
+	Request     string   // request body schema, or AsyncAPI message
+	Responses   []string // "200 User", "404"
+	Refs        []string // every $ref inside the operation
+}
+
+// Title is the operation's display name, e.g. "GET /users/{id}".
+func (o *Operation) Title() string {
+	return o.Method + " " + o.Path
+}
+
+// Anchor is a YAML &anchor on a node.
+type Anchor struct {
+	BaseNode
+	Name string
+	Uses int // aliases that refer to it
+}
+
+// Alias is a YAML *alias. Merge is set for "<<: *name" merge keys.
+type Alias struct {
+	BaseNode
+	Name       string
+	AnchorLine int // line of the anchor it refers to
+	Merge      bool
+}
+
+// ConfigItem is a key construct of a known YAML format: a Kubernetes
+// resource or container, a compose service, or a GitHub Actions job or
+// step. Its kind says which.
+type ConfigItem struct {
+	BaseNode
+	Profile string   // kubernetes, compose, or actions
+	Name    string   // Kind/name, container or service name, job id, step title
+	Image   string   // container or service image
+	Ports   []string // "8080", "8080:80", "53/UDP"
+	Uses    string   // action or reusable workflow
+	Detail  string   // apiVersion, runner and step count, build context
+}
+
 // ---------- Inline nodes ----------
 
 // Text is a plain run of characters.
@@ -389,6 +450,14 @@ type ContentSummary struct {
 	IssueRefs    int
 	CommitRefs   int
 	Emojis       int
+	Operations   int
+	Anchors      int
+	Aliases      int
+	Resources    int
+	Containers   int
+	Services     int
+	Jobs         int
+	Steps        int
 }
 
 // ---------- Traversal ----------
@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Notebooks are mapped through a markdown rendering of the notebook, in
+// the style of jupytext: markdown cells verbatim, code cells as fenced
+// blocks in the kernel language, one blank line between cells. Line
+// numbers in the Document refer to that rendering; Document.Cells maps
+// them back to cell index plus line within the cell.
+
+// Cell records where a notebook cell sits in the rendered notebook.
+type Cell struct {
+	Index      int    // 1-based position in the notebook
+	Type       string // markdown, code, or raw
+	LineStart  int    // first rendered line, including any opening fence
+	LineEnd    int    // last rendered line, including any closing fence
+	SourceLine int    // rendered line holding the cell's first source line
+	Lines      int    // number of source lines
+}
+
+// CellOutputs summarizes a code cell's saved outputs.
+type CellOutputs struct {
+	Text   int // stream, text/plain, text/html, and other text results
+	Images int // image/* results
+	Errors int
+	Tokens int // tokens across text and error outputs
+}
+
+// Empty reports whether the cell had no outputs.
+func (o CellOutputs) Empty() bool {
+	return o.Text == 0 && o.Images == 0 && o.Errors == 0
+}
+
+// String summarizes the outputs, e.g. "2 text, 1 image (~40 tok)".
+func (o CellOutputs) String() string {
+	var parts []string
+	if o.Text > 0 {
+		parts = append(parts, fmt.Sprintf("%d text", o.Text))
+	}
+	if o.Images > 0 {
+		parts = append(parts, fmt.Sprintf("%d image%s", o.Images, plural(o.Images)))
+	}
+	if o.Errors > 0 {
+		parts = append(parts, fmt.Sprintf("%d error%s", o.Errors, plural(o.Errors)))
+	}
+	if len(parts) == 0 {
+		return "no output"
+	}
+	s := strings.Join(parts, ", ")
+	if o.Tokens > 0 {
+		s += fmt.Sprintf(" (~%d tok)", o.Tokens)
+	}
+	return s
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// CellAt returns the notebook cell holding rendered line and the 1-based
+// line within that cell's source. ok is false outside notebooks and for
+// lines between cells.
+func (d *Document) CellAt(line int) (cell Cell, cellLine int, ok bool) {
+	for _, c := range d.Cells {
+		if line >= c.LineStart && line <= c.LineEnd {
+			return c, min(max(line-c.SourceLine+1, 1), c.Lines), true
+		}
+	}
+	return Cell{}, 0, false
+}
+
+// LineOf returns the rendered line for line n (1-based) of cell index.
+func (d *Document) LineOf(index, n int) (int, bool) {
+	for _, c := range d.Cells {
+		if c.Index == index {
+			if n < 1 || n > c.Lines {
+				return 0, false
+			}
+			return c.SourceLine + n - 1, true
+		}
+	}
+	return 0, false
+}
+
+// notebook is the subset of the nbformat 4 schema docmap reads.
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   multiline        `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string               `json:"output_type"`
+	Text       multiline            `json:"text"`
+	Data       map[string]multiline `json:"data"`
+	EName      string               `json:"ename"`
+	EValue     string               `json:"evalue"`
+	Traceback  []string             `json:"traceback"`
+}
+
+// multiline is nbformat's string-or-list-of-lines.
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*m = multiline(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Non-text data such as application/json payloads.
+		*m = multiline(data)
+		return nil
+	}
+	*m = multiline(s)
+	return nil
+}
+
+// cellMagicRe matches a %%magic that switches a cell's language.
+var cellMagicRe = regexp.MustCompile(`^%%(bash|sh|html|javascript|js|sql|latex|markdown|ruby|perl|python|python3|svg|writefile)\b`)
+
+// NotebookSource renders an .ipynb file as markdown and reports where each
+// cell landed, along with each code cell's output summary.
+func NotebookSource(data []byte) (string, []Cell, map[int]CellOutputs, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", nil, nil, err
+	}
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernelspec.Language
+	}
+
+	var lines []string
+	var cells []Cell
+	outputs := map[int]CellOutputs{}
+	for k, c := range nb.Cells {
+		if k > 0 {
+			lines = append(lines, "")
+		}
+		src := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(c.Source), "\r\n", "\n"), "\n"), "\n")
+		cell := Cell{Index: k + 1, Type: c.CellType, LineStart: len(lines) + 1, Lines: len(src)}
+		switch c.CellType {
+		case "markdown":
+			cell.SourceLine = cell.LineStart
+			lines = append(lines, src...)
+		default:
+			fence := strings.Repeat("`", max(3, longestBacktickRun(string(c.Source))+1))
+			info := lang
+			if c.CellType == "raw" {
+				info = "raw"
+			} else if m := cellMagicRe.FindStringSubmatch(src[0]); m != nil {
+				info = cellMagicLanguage(m[1])
+			}
+			lines = append(lines, fence+info)
+			cell.SourceLine = len(lines) + 1
+			lines = append(lines, src...)
+			lines = append(lines, fence)
+			if c.CellType == "code" {
+				outputs[cell.Index] = summarizeOutputs(c.Outputs)
+			}
+		}
+		cell.LineEnd = len(lines)
+		cells = append(cells, cell)
+	}
+	return strings.Join(lines, "\n") + "\n", cells, outputs, nil
+}
+
+func cellMagicLanguage(magic string) string {
+	switch magic {
+	case "sh":
+		return "bash"
+	case "js":
+		return "javascript"
+	case "python3":
+		return "python"
+	case "writefile":
+		return ""
+	}
+	return magic
+}
+
+func longestBacktickRun(s string) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func summarizeOutputs(outs []notebookOutput) CellOutputs {
+	var o CellOutputs
+	for _, out := range outs {
+		switch out.OutputType {
+		case "stream":
+			o.Text++
+			o.Tokens += estimateTokens(string(out.Text))
+		case "error":
+			o.Errors++
+			o.Tokens += estimateTokens(out.EName + ": " + out.EValue + "\n" + strings.Join(out.Traceback, "\n"))
+		case "execute_result", "display_data":
+			image := false
+			for mime := range out.Data {
+				if strings.HasPrefix(mime, "image/") {
+					image = true
+				}
+			}
+			if image {
+				o.Images++
+				continue
+			}
+			o.Text++
+			text := out.Data["text/plain"]
+			if text == "" {
+				for _, v := range out.Data {
+					text = v
+					break
+				}
+			}
+			o.Tokens += estimateTokens(string(text))
+		}
+	}
+	return o
+}
+
+// ParseNotebook parses a Jupyter notebook. Markdown cells go through the
+// markdown parser and code cells become CodeBlocks in the kernel language
+// carrying their cell index and output summary.
+func ParseNotebook(data []byte) (*Document, error) {
+	source, cells, outputs, err := NotebookSource(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := Parse(source)
+	doc.Cells = cells
+	for _, root := range doc.Nodes {
+		Walk(root, func(n Node) bool {
+			cb, ok := n.(*CodeBlock)
+			if !ok {
+				return true
+			}
+			if c, _, ok := doc.CellAt(cb.LineStart()); ok && c.Type != "markdown" {
+				cb.Cell = c.Index
+				if out, ok := outputs[c.Index]; ok {
+					cb.Outputs = &out
+				}
+			}
+			return false
+		})
+	}
+	return doc, nil
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const notebookSample = `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Analysis\n", "\n", "Intro text."]},
+  {"cell_type": "code", "metadata": {}, "source": ["import pandas as pd\n", "print('hi')"], "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["hello from the notebook\n"]},
+    {"output_type": "display_data", "data": {"image/png": "iVBOR", "text/plain": ["<Figure>"]}, "metadata": {}},
+    {"output_type": "execute_result", "data": {"text/plain": "42"}, "metadata": {}, "execution_count": 1}
+  ]},
+  {"cell_type": "markdown", "metadata": {}, "source": "## Shell"},
+  {"cell_type": "code", "metadata": {}, "source": "%%bash\nls", "outputs": [
+    {"output_type": "error", "ename": "CalledProcessError", "evalue": "exit 1", "traceback": ["..."]}
+  ]},
+  {"cell_type": "code", "metadata": {}, "source": "s = '` + "```" + `'", "outputs": []}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+func TestParseNotebook(t *testing.T) {
+	doc, err := ParseNotebook([]byte(notebookSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Analysis" || len(doc.Sections[0].Children) != 1 {
+		t.Fatalf("unexpected sections: %+v", doc.Sections)
+	}
+	if len(doc.Cells) != 5 {
+		t.Fatalf("expected 5 cells, got %d", len(doc.Cells))
+	}
+
+	var codes []*CodeBlock
+	for _, root := range doc.Nodes {
+		if cb, ok := root.(*CodeBlock); ok {
+			codes = append(codes, cb)
+		}
+	}
+	if len(codes) != 3 {
+		t.Fatalf("expected 3 code cells, got %d", len(codes))
+	}
+	var langs []string
+	for _, cb := range codes {
+		langs = append(langs, cb.Language)
+	}
+	if got := strings.Join(langs, ","); got != "python,bash,python" {
+		t.Errorf("languages = %s", got)
+	}
+	if strings.TrimSpace(codes[2].Code) != "s = '```'" {
+		t.Errorf("backticks in a cell should survive the fence, got %q", codes[2].Code)
+	}
+
+	if codes[0].Cell != 2 || codes[0].Outputs == nil {
+		t.Fatalf("first code block should be cell 2 with outputs: %+v", codes[0])
+	}
+	if out := *codes[0].Outputs; out.Text != 2 || out.Images != 1 || out.Errors != 0 || out.Tokens == 0 {
+		t.Errorf("cell 2 outputs = %+v", out)
+	}
+	if out := *codes[1].Outputs; out.Errors != 1 || !strings.HasPrefix(out.String(), "1 error (~") {
+		t.Errorf("cell 4 outputs = %+v (%s)", out, out)
+	}
+	if !codes[2].Outputs.Empty() {
+		t.Errorf("cell 5 has no outputs, got %+v", codes[2].Outputs)
+	}
+}
+
+func TestNotebookCellLines(t *testing.T) {
+	doc, err := ParseNotebook([]byte(notebookSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cell 2's second source line sits after the markdown cell, a blank
+	// separator, and the opening fence.
+	line, ok := doc.LineOf(2, 2)
+	if !ok || line != 7 {
+		t.Fatalf("LineOf(2, 2) = %d, %v; want 7", line, ok)
+	}
+	cell, n, ok := doc.CellAt(line)
+	if !ok || cell.Index != 2 || cell.Type != "code" || n != 2 {
+		t.Errorf("CellAt(%d) = %+v, %d, %v", line, cell, n, ok)
+	}
+	if _, ok := doc.LineOf(2, 3); ok {
+		t.Error("cell 2 has only two lines")
+	}
+	if _, _, ok := doc.CellAt(4); ok {
+		t.Error("line 4 is the separator between cells")
+	}
+
+	source, _, _, err := NotebookSource([]byte(notebookSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(source, "\n")[6]; got != "print('hi')" {
+		t.Errorf("rendered line 7 = %q", got)
+	}
+}
+
+func TestParseNotebookInvalid(t *testing.T) {
+	if _, err := ParseNotebook([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
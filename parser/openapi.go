@@ -0,0 +1,520 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// API specs (OpenAPI/Swagger and AsyncAPI) get a domain tree instead of
+// the generic key tree: one section per tag, holding one section per
+// operation ("GET /users/{id}"), each carrying an Operation node. The
+// remaining top-level keys (info, servers, components) keep the generic
+// YAML layout, and tag sections take the place of paths/channels.
+
+// httpMethods are the operation keys of an OpenAPI path item.
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// specFormat returns "openapi", "swagger", or "asyncapi" when top is the
+// root mapping of an API spec, or "" otherwise.
+func specFormat(top *yaml.Node) string {
+	if top == nil || top.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		switch k := top.Content[i].Value; k {
+		case "openapi", "swagger", "asyncapi":
+			return k
+		}
+	}
+	return ""
+}
+
+// ErrNotSpec is returned by ParseJSON for JSON that isn't an API spec.
+var ErrNotSpec = errors.New("not an OpenAPI or AsyncAPI spec")
+
+// ParseJSON parses a JSON OpenAPI or AsyncAPI spec. JSON is read through
+// the YAML parser so line numbers carry over; JSON that isn't an API spec
+// is rejected up front, before the YAML parse, which keeps package.json
+// and friends out of directory maps cheaply.
+func ParseJSON(content string) (*Document, error) {
+	if !isSpecJSON(content) {
+		return nil, ErrNotSpec
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(untabJSON(content)), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || specFormat(root.Content[0]) == "" {
+		return nil, ErrNotSpec
+	}
+	return parseSpec(root.Content[0]), nil
+}
+
+// isSpecJSON reports whether content is a JSON object with a top-level
+// openapi, swagger or asyncapi key. Values are skipped without decoding,
+// and the scan stops at the first spec key.
+func isSpecJSON(content string) bool {
+	dec := json.NewDecoder(strings.NewReader(content))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "openapi", "swagger", "asyncapi":
+			return true
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// untabJSON swaps tab indentation for spaces, which YAML rejects. Raw tabs
+// can't appear inside JSON strings, so only whitespace changes.
+func untabJSON(content string) string {
+	if !strings.Contains(content, "\t") {
+		return content
+	}
+	return strings.ReplaceAll(content, "\t", "  ")
+}
+
+type specParser struct {
+	root *yaml.Node
+	ops  []*Operation
+	refs []Reference
+}
+
+// parseSpec builds the domain tree for an API spec rooted at top.
+func parseSpec(top *yaml.Node) *Document {
+	p := &specParser{root: top}
+	format := specFormat(top)
+
+	// Which top-level keys hold operations.
+	opKeys := map[string]bool{"paths": true, "webhooks": true}
+	if format == "asyncapi" {
+		opKeys = map[string]bool{"channels": true}
+		if strings.HasPrefix(mapScalar(top, "asyncapi"), "3") {
+			opKeys = map[string]bool{"operations": true}
+		}
+	}
+
+	var sections []*Section
+	tagsAt := -1
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		key, val := top.Content[i], top.Content[i+1]
+		switch {
+		case key.Value == "tags":
+			continue
+		case opKeys[key.Value]:
+			if tagsAt < 0 {
+				tagsAt = len(sections)
+			}
+			switch {
+			case format != "asyncapi":
+				p.pathItems(val)
+			case key.Value == "channels":
+				p.asyncChannels(val)
+			default:
+				p.asyncOperations(val)
+			}
+			continue
+		}
+		pair := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, val}}
+		sections = append(sections, yamlMappingToSections(pair, 1)...)
+	}
+	if tagsAt >= 0 {
+		tags := p.tagSections(mapValue(top, "tags"))
+		sections = append(sections[:tagsAt], append(tags, sections[tagsAt:]...)...)
+	}
+
+	doc := &Document{Sections: sections, References: p.refs}
+	for _, op := range p.ops {
+		doc.Nodes = append(doc.Nodes, op)
+	}
+	for _, s := range sections {
+		doc.TotalTokens += s.Tokens
+	}
+	return doc
+}
+
+// pathItems adds the operations under an OpenAPI paths (or webhooks) map.
+func (p *specParser) pathItems(paths *yaml.Node) {
+	if paths.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(paths.Content); i += 2 {
+		path, item := paths.Content[i], p.resolve(paths.Content[i+1])
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		shared := mapValue(item, "parameters")
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			key, node := item.Content[j], item.Content[j+1]
+			if !httpMethods[strings.ToLower(key.Value)] {
+				continue
+			}
+			op := p.operation(strings.ToUpper(key.Value), path.Value, key, node)
+			op.OperationID = mapScalar(node, "operationId")
+			for _, list := range []*yaml.Node{shared, mapValue(node, "parameters")} {
+				p.parameters(op, list)
+			}
+			if body := mapValue(node, "requestBody"); body != nil {
+				op.Request = p.contentSchema(body)
+			}
+			if responses := mapValue(node, "responses"); responses != nil && responses.Kind == yaml.MappingNode {
+				for k := 0; k+1 < len(responses.Content); k += 2 {
+					label := responses.Content[k].Value
+					if schema := p.contentSchema(responses.Content[k+1]); schema != "" {
+						label += " " + schema
+					}
+					op.Responses = append(op.Responses, label)
+				}
+			}
+		}
+	}
+}
+
+// parameters appends list's parameters to op as "name (in)". Swagger 2
+// body parameters become the request schema instead.
+func (p *specParser) parameters(op *Operation, list *yaml.Node) {
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, item := range list.Content {
+		param := p.resolve(item)
+		name, in := mapScalar(param, "name"), mapScalar(param, "in")
+		if in == "body" {
+			op.Request = schemaLabel(mapValue(param, "schema"))
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		if in != "" {
+			name += " (" + in + ")"
+		}
+		op.Parameters = append(op.Parameters, name)
+	}
+}
+
+// asyncChannels adds AsyncAPI 2 publish/subscribe operations.
+func (p *specParser) asyncChannels(channels *yaml.Node) {
+	if channels.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(channels.Content); i += 2 {
+		name, channel := channels.Content[i], channels.Content[i+1]
+		if channel.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(channel.Content); j += 2 {
+			key, node := channel.Content[j], channel.Content[j+1]
+			if key.Value != "publish" && key.Value != "subscribe" {
+				continue
+			}
+			op := p.operation(strings.ToUpper(key.Value), name.Value, key, node)
+			op.OperationID = mapScalar(node, "operationId")
+			op.Request = p.messageLabel(mapValue(node, "message"))
+		}
+	}
+}
+
+// asyncOperations adds AsyncAPI 3 operations, which name their channel by
+// $ref.
+func (p *specParser) asyncOperations(ops *yaml.Node) {
+	if ops.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(ops.Content); i += 2 {
+		key, node := ops.Content[i], ops.Content[i+1]
+		channel := mapValue(node, "channel")
+		address := mapScalar(p.resolve(channel), "address")
+		if address == "" {
+			address = refName(mapScalar(channel, "$ref"))
+		}
+		op := p.operation(strings.ToUpper(mapScalar(node, "action")), address, key, node)
+		op.OperationID = key.Value
+		if msgs := mapValue(node, "messages"); msgs != nil && msgs.Kind == yaml.SequenceNode {
+			var labels []string
+			for _, m := range msgs.Content {
+				if l := p.messageLabel(m); l != "" {
+					labels = append(labels, l)
+				}
+			}
+			op.Request = strings.Join(labels, " | ")
+		}
+	}
+}
+
+// operation records the parts every spec format shares: position,
+// summary, tags, and $ref targets.
+func (p *specParser) operation(method, path string, key, node *yaml.Node) *Operation {
+	op := &Operation{
+		BaseNode: BaseNode{NKind: KindOperation, Start: key.Line, End: lastLine(node)},
+		Method:   method,
+		Path:     path,
+		Summary:  mapScalar(node, "summary"),
+	}
+	if op.Summary == "" {
+		op.Summary, _, _ = strings.Cut(strings.TrimSpace(mapScalar(node, "description")), "\n")
+	}
+	if tags := mapValue(node, "tags"); tags != nil && tags.Kind == yaml.SequenceNode {
+		for _, t := range tags.Content {
+			name := t.Value
+			if t.Kind == yaml.MappingNode {
+				name = mapScalar(t, "name") // AsyncAPI tag objects
+			}
+			if name != "" {
+				op.Tags = append(op.Tags, name)
+			}
+		}
+	}
+	seen := map[string]bool{}
+	walkYAML(node, func(n *yaml.Node) {
+		ref := mapValue(n, "$ref")
+		if ref == nil || ref.Value == "" || seen[ref.Value] {
+			return
+		}
+		seen[ref.Value] = true
+		op.Refs = append(op.Refs, ref.Value)
+		target := ref.Value
+		if !strings.HasPrefix(target, "#") {
+			target, _, _ = strings.Cut(target, "#")
+		}
+		p.refs = append(p.refs, Reference{Text: op.Title(), Target: target, Line: ref.Line})
+	})
+	// Count the operation's own keys and values rather than its source
+	// lines: a minified JSON spec has every operation on the same line.
+	op.TokCount = estimateTokens(key.Value + "\n" + scalarText(node))
+	p.ops = append(p.ops, op)
+	return op
+}
+
+// tagSections groups operations under their first tag: declared tags in
+// declaration order, then undeclared ones as they appear, then "default"
+// for untagged operations.
+func (p *specParser) tagSections(decls *yaml.Node) []*Section {
+	byName := map[string]*Section{}
+	var order []*Section
+	tag := func(name string) *Section {
+		if s, ok := byName[name]; ok {
+			return s
+		}
+		s := &Section{Level: 1, Title: name}
+		byName[name] = s
+		order = append(order, s)
+		return s
+	}
+	if decls != nil && decls.Kind == yaml.SequenceNode {
+		for _, d := range decls.Content {
+			if name := mapScalar(d, "name"); name != "" {
+				tag(name).Content = mapScalar(d, "description")
+			}
+		}
+	}
+	var untagged []*Operation
+	for _, op := range p.ops {
+		if len(op.Tags) == 0 {
+			untagged = append(untagged, op)
+			continue
+		}
+		addOperationSection(tag(op.Tags[0]), op)
+	}
+	for _, op := range untagged {
+		addOperationSection(tag("default"), op)
+	}
+
+	var out []*Section
+	for _, s := range order {
+		if len(s.Children) == 0 {
+			continue
+		}
+		s.LineStart, s.LineEnd = s.Children[0].LineStart, s.Children[0].LineEnd
+		for _, c := range s.Children {
+			s.LineStart = min(s.LineStart, c.LineStart)
+			s.LineEnd = max(s.LineEnd, c.LineEnd)
+			s.Tokens += c.Tokens
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func addOperationSection(parent *Section, op *Operation) {
+	s := &Section{
+		Level:     2,
+		Title:     op.Title(),
+		Content:   operationContent(op),
+		Tokens:    op.TokCount,
+		Parent:    parent,
+		LineStart: op.Start,
+		LineEnd:   op.End,
+		Notables:  []Node{op},
+	}
+	if op.OperationID != "" {
+		s.KeyTerms = []string{op.OperationID}
+	}
+	parent.Children = append(parent.Children, s)
+}
+
+// operationContent is the text of an operation section: its summary,
+// then one line each for its id, parameters, request and responses.
+func operationContent(op *Operation) string {
+	var lines []string
+	if op.Summary != "" {
+		lines = append(lines, op.Summary)
+	}
+	if op.OperationID != "" {
+		lines = append(lines, "operationId: "+op.OperationID)
+	}
+	if len(op.Parameters) > 0 {
+		lines = append(lines, "parameters: "+strings.Join(op.Parameters, ", "))
+	}
+	if op.Request != "" {
+		lines = append(lines, "request: "+op.Request)
+	}
+	if len(op.Responses) > 0 {
+		lines = append(lines, "responses: "+strings.Join(op.Responses, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// contentSchema names the schema of a request body or response: the first
+// media type's schema in OpenAPI 3, or the schema itself in Swagger 2.
+func (p *specParser) contentSchema(n *yaml.Node) string {
+	n = p.resolve(n)
+	if content := mapValue(n, "content"); content != nil && content.Kind == yaml.MappingNode && len(content.Content) >= 2 {
+		return schemaLabel(mapValue(content.Content[1], "schema"))
+	}
+	return schemaLabel(mapValue(n, "schema"))
+}
+
+// messageLabel names an AsyncAPI message by its $ref, name, or payload,
+// joining oneOf alternatives with " | ".
+func (p *specParser) messageLabel(n *yaml.Node) string {
+	if n == nil {
+		return ""
+	}
+	if ref := mapScalar(n, "$ref"); ref != "" {
+		return refName(ref)
+	}
+	if alts := mapValue(n, "oneOf"); alts != nil && alts.Kind == yaml.SequenceNode {
+		var labels []string
+		for _, a := range alts.Content {
+			if l := p.messageLabel(a); l != "" {
+				labels = append(labels, l)
+			}
+		}
+		return strings.Join(labels, " | ")
+	}
+	if name := mapScalar(n, "name"); name != "" {
+		return name
+	}
+	return schemaLabel(mapValue(n, "payload"))
+}
+
+// schemaLabel names a schema: its $ref target, []Item for arrays of a
+// named schema, or its type.
+func schemaLabel(n *yaml.Node) string {
+	if n == nil {
+		return ""
+	}
+	if ref := mapScalar(n, "$ref"); ref != "" {
+		return refName(ref)
+	}
+	switch t := mapScalar(n, "type"); t {
+	case "array":
+		if item := schemaLabel(mapValue(n, "items")); item != "" {
+			return "[]" + item
+		}
+		return t
+	default:
+		return t
+	}
+}
+
+// refName is the last segment of a $ref: "User" for
+// "#/components/schemas/User", or the file for a whole-file ref.
+func refName(ref string) string {
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		return ref[i+1:]
+	}
+	return strings.TrimPrefix(ref, "#")
+}
+
+// resolve follows local "#/..." $refs to their target, so shared
+// parameters, request bodies, and responses read like inline ones.
+func (p *specParser) resolve(n *yaml.Node) *yaml.Node {
+	for hops := 0; n != nil && hops < 8; hops++ {
+		ref := mapScalar(n, "$ref")
+		if !strings.HasPrefix(ref, "#/") {
+			return n
+		}
+		target := p.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			if target = mapValue(target, part); target == nil {
+				return n
+			}
+		}
+		n = target
+	}
+	return n
+}
+
+// mapValue returns the value for key in a mapping node, or nil.
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mapScalar returns the scalar value for key in a mapping node, or "".
+func mapScalar(n *yaml.Node, key string) string {
+	if v := mapValue(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// scalarText joins the keys and values under n, one per line.
+func scalarText(n *yaml.Node) string {
+	var parts []string
+	walkYAML(n, func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			parts = append(parts, n.Value)
+		}
+	})
+	return strings.Join(parts, "\n")
+}
+
+// walkYAML calls fn for n and every node beneath it, not following
+// aliases.
+func walkYAML(n *yaml.Node, fn func(*yaml.Node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, c := range n.Content {
+		walkYAML(c, fn)
+	}
+}
@@ -0,0 +1,224 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const openapiSample = `openapi: 3.0.3
+info:
+  title: Users API
+tags:
+  - name: users
+    description: User management
+paths:
+  /users/{id}:
+    parameters:
+      - $ref: '#/components/parameters/UserId'
+    get:
+      tags: [users]
+      operationId: getUser
+      summary: Fetch a user
+      responses:
+        '200':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        '404':
+          description: Not found
+  /health:
+    get:
+      operationId: health
+      responses:
+        '200':
+          description: OK
+  /users:
+    post:
+      tags: [users]
+      operationId: createUser
+      requestBody:
+        $ref: '#/components/requestBodies/NewUser'
+      responses:
+        '201':
+          description: Created
+components:
+  parameters:
+    UserId:
+      name: id
+      in: path
+  requestBodies:
+    NewUser:
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: '#/components/schemas/NewUser'
+  schemas:
+    User:
+      type: object
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	doc, err := ParseYAML(openapiSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, s := range doc.Sections {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, ","); got != "openapi,info,users,default,components" {
+		t.Fatalf("top-level sections = %s", got)
+	}
+
+	users := doc.Sections[2]
+	if users.Content != "User management" || len(users.Children) != 2 {
+		t.Fatalf("users tag: %+v", users)
+	}
+	get, post := users.Children[0], users.Children[1]
+	if get.Title != "GET /users/{id}" || get.LineStart != 11 || get.LineEnd != 22 || get.Parent != users {
+		t.Errorf("GET section: %q lines %d-%d", get.Title, get.LineStart, get.LineEnd)
+	}
+	op := get.Notables[0].(*Operation)
+	if op.OperationID != "getUser" || op.Summary != "Fetch a user" {
+		t.Errorf("GET operation: %+v", op)
+	}
+	if want := "Fetch a user\noperationId: getUser\nparameters: id (path)\nresponses: 200 User, 404"; get.Content != want {
+		t.Errorf("GET content = %q, want %q", get.Content, want)
+	}
+	if strings.Join(op.Parameters, ",") != "id (path)" {
+		t.Errorf("path-level $ref parameter should resolve, got %v", op.Parameters)
+	}
+	if strings.Join(op.Responses, ",") != "200 User,404" {
+		t.Errorf("responses = %v", op.Responses)
+	}
+	if post.Title != "POST /users" || post.Notables[0].(*Operation).Request != "[]NewUser" {
+		t.Errorf("POST should resolve its request body ref, got %+v", post.Notables[0])
+	}
+	if health := doc.Sections[3]; len(health.Children) != 1 || health.Children[0].Title != "GET /health" {
+		t.Errorf("untagged operations belong under default, got %+v", health.Children)
+	}
+
+	if len(doc.Nodes) != 3 || doc.Summary().Operations != 3 {
+		t.Errorf("expected 3 operation nodes, got %d", len(doc.Nodes))
+	}
+	var refs []string
+	for _, r := range doc.References {
+		refs = append(refs, r.Target)
+	}
+	if got := strings.Join(refs, ","); got != "#/components/schemas/User,#/components/requestBodies/NewUser" {
+		t.Errorf("references = %s", got)
+	}
+}
+
+func TestParseJSONSpec(t *testing.T) {
+	spec := "{\n\t\"swagger\": \"2.0\",\n\t\"paths\": {\n\t\t\"/pets\": {\n\t\t\t\"post\": {\n" +
+		"\t\t\t\t\"operationId\": \"addPet\",\n" +
+		"\t\t\t\t\"parameters\": [{\"in\": \"body\", \"name\": \"pet\", \"schema\": {\"$ref\": \"#/definitions/Pet\"}}],\n" +
+		"\t\t\t\t\"responses\": {\"200\": {\"schema\": {\"$ref\": \"definitions.json#/Pet\"}}}\n" +
+		"\t\t\t}\n\t\t}\n\t}\n}\n"
+	doc, err := ParseJSON(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != 1 {
+		t.Fatalf("expected one operation, got %d", len(doc.Nodes))
+	}
+	op := doc.Nodes[0].(*Operation)
+	if op.Title() != "POST /pets" || op.LineStart() != 5 || op.Request != "Pet" || strings.Join(op.Responses, ",") != "200 Pet" {
+		t.Errorf("swagger operation: %+v", op)
+	}
+	if len(doc.References) != 2 || doc.References[1].Target != "definitions.json" {
+		t.Errorf("external refs should point at the file, got %+v", doc.References)
+	}
+
+	if _, err := ParseJSON(`{"name": "pkg", "version": "1.0.0"}`); !errors.Is(err, ErrNotSpec) {
+		t.Errorf("JSON that isn't an API spec should be rejected, got %v", err)
+	}
+	if _, err := ParseJSON(`{"info": {"openapi": "nested"}, "paths": {}}`); !errors.Is(err, ErrNotSpec) {
+		t.Errorf("only top-level keys mark a spec, got %v", err)
+	}
+}
+
+func TestMinifiedJSONSpecTokens(t *testing.T) {
+	var spec map[string]any
+	if err := yaml.Unmarshal([]byte(openapiSample), &spec); err != nil {
+		t.Fatal(err)
+	}
+	minified, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := ParseJSON(string(minified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := ParseYAML(openapiSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every operation sits on line 1 of the minified spec; each must still
+	// count only its own content.
+	if len(fromJSON.Nodes) != 3 {
+		t.Fatalf("expected 3 operations, got %d", len(fromJSON.Nodes))
+	}
+	for _, n := range fromJSON.Nodes {
+		if n.Tokens() >= estimateTokens(string(minified))/2 {
+			t.Errorf("%s counts %d tokens of a %d-token file", n.(*Operation).Title(), n.Tokens(), estimateTokens(string(minified)))
+		}
+	}
+	if fromJSON.TotalTokens > 2*fromYAML.TotalTokens {
+		t.Errorf("minified JSON counts %d tokens, YAML %d", fromJSON.TotalTokens, fromYAML.TotalTokens)
+	}
+}
+
+func TestParseAsyncAPI(t *testing.T) {
+	v2 := `asyncapi: 2.6.0
+channels:
+  user/signedup:
+    subscribe:
+      operationId: onSignup
+      tags:
+        - name: users
+      message:
+        $ref: '#/components/messages/UserSignedUp'
+`
+	doc, err := ParseYAML(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[1].Title != "users" {
+		t.Fatalf("sections: %+v", doc.Sections)
+	}
+	op := doc.Nodes[0].(*Operation)
+	if op.Title() != "SUBSCRIBE user/signedup" || op.OperationID != "onSignup" || op.Request != "UserSignedUp" {
+		t.Errorf("asyncapi 2 operation: %+v", op)
+	}
+
+	v3 := `asyncapi: 3.0.0
+channels:
+  signup:
+    address: user/signedup
+operations:
+  sendSignup:
+    action: send
+    channel:
+      $ref: '#/channels/signup'
+    messages:
+      - $ref: '#/channels/signup/messages/UserSignedUp'
+`
+	doc, err = ParseYAML(v3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = doc.Nodes[0].(*Operation)
+	if op.Title() != "SEND user/signedup" || op.OperationID != "sendSignup" || op.Request != "UserSignedUp" {
+		t.Errorf("asyncapi 3 operation: %+v", op)
+	}
+}
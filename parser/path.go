@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FindSections returns every section addressed by a breadcrumb path such as
+// "Guide > Install > Linux". Each segment names one level of the Section
+// tree, matched case-insensitively; leading ancestors may be left out, so
+// "Install > Linux" also finds it. Whole-title matches win: only when no
+// section matches that way are segments treated as substrings. More than
+// one result means the path is ambiguous.
+func (d *Document) FindSections(path string) []*Section {
+	var segments []string
+	for _, seg := range strings.Split(path, ">") {
+		if seg = strings.ToLower(strings.TrimSpace(seg)); seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+
+	exact := func(title, seg string) bool { return strings.ToLower(strings.TrimSpace(title)) == seg }
+	partial := func(title, seg string) bool { return strings.Contains(strings.ToLower(title), seg) }
+	for _, match := range []func(string, string) bool{exact, partial} {
+		var found []*Section
+		for _, s := range d.GetAllSections() {
+			if pathMatches(s, segments, match) {
+				found = append(found, s)
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// pathMatches reports whether s and its nearest ancestors match segments,
+// last segment first.
+func pathMatches(s *Section, segments []string, match func(title, seg string) bool) bool {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if s == nil || !match(s.Title, segments[i]) {
+			return false
+		}
+		s = s.Parent
+	}
+	return true
+}
+
+// SectionMatch is a section a name could refer to, and how closely its
+// title matched.
+type SectionMatch struct {
+	Section  *Section
+	Kind     MatchKind
+	Distance int // typos between the name and the title, for fuzzy matches
+}
+
+// MatchKind orders the ways a name can match a section title, best first.
+type MatchKind int
+
+const (
+	MatchExact        MatchKind = iota // the whole title, ignoring case
+	MatchSubstring                     // part of the title
+	MatchFuzzy                         // the whole title, within a few typos
+	MatchFuzzyPartial                  // a run of the title's words, within a few typos
+)
+
+// MatchSections ranks the sections name could refer to: whole-title matches
+// first, then substring matches, each in document order. Only when nothing
+// matches literally are titles compared with typos allowed: whole titles
+// before runs of title words, then closest first.
+// A name containing ">" is a breadcrumb path (see FindSections).
+func (d *Document) MatchSections(name string) []SectionMatch {
+	if strings.Contains(name, ">") {
+		segments := strings.Split(name, ">")
+		last := strings.ToLower(strings.TrimSpace(segments[len(segments)-1]))
+		var out []SectionMatch
+		for _, s := range d.FindSections(name) {
+			kind := MatchSubstring
+			if strings.ToLower(strings.TrimSpace(s.Title)) == last {
+				kind = MatchExact
+			}
+			out = append(out, SectionMatch{Section: s, Kind: kind})
+		}
+		return out
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	var literal, fuzzy []SectionMatch
+	maxEdits := typoBudget(name)
+	for _, s := range d.GetAllSections() {
+		title := strings.ToLower(strings.TrimSpace(s.Title))
+		switch {
+		case title == name:
+			literal = append(literal, SectionMatch{Section: s, Kind: MatchExact})
+		case strings.Contains(title, name):
+			literal = append(literal, SectionMatch{Section: s, Kind: MatchSubstring})
+		case maxEdits > 0:
+			if dist := editDistance(name, title); dist <= maxEdits {
+				fuzzy = append(fuzzy, SectionMatch{Section: s, Kind: MatchFuzzy, Distance: dist})
+			} else if dist := wordsDistance(name, title); dist <= maxEdits {
+				fuzzy = append(fuzzy, SectionMatch{Section: s, Kind: MatchFuzzyPartial, Distance: dist})
+			}
+		}
+	}
+	if len(literal) == 0 {
+		literal = fuzzy
+	}
+	sort.SliceStable(literal, func(i, j int) bool {
+		if literal[i].Kind != literal[j].Kind {
+			return literal[i].Kind < literal[j].Kind
+		}
+		return literal[i].Distance < literal[j].Distance
+	})
+	return literal
+}
+
+// SplitLineSuffix splits a trailing "@line" off a section reference, as in
+// "Config@42". References without one, or with a non-numeric suffix (say
+// "user@host"), come back whole with line 0.
+func SplitLineSuffix(ref string) (string, int) {
+	i := strings.LastIndex(ref, "@")
+	if i <= 0 {
+		return ref, 0
+	}
+	line, err := strconv.Atoi(ref[i+1:])
+	if err != nil || line <= 0 {
+		return ref, 0
+	}
+	return ref[:i], line
+}
+
+// typoBudget is how many edits a fuzzy match of name may take: none for
+// short names, where a typo is as likely to be a different word.
+func typoBudget(name string) int {
+	switch n := len([]rune(name)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// wordsDistance is the edit distance between name and the closest run of
+// as many words in title.
+func wordsDistance(name, title string) int {
+	best := len(name) + len(title)
+	words := strings.Fields(title)
+	n := len(strings.Fields(name))
+	for i := 0; i+n <= len(words); i++ {
+		best = min(best, editDistance(name, strings.Join(words[i:i+n], " ")))
+	}
+	return best
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// adjacent transpositions that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// OwnLineEnd is the last line of the section's own content: the line before
+// its first subsection, or LineEnd when it has none. A section whose first
+// subsection starts on its own first line, like an API tag grouping its
+// operations, has no lines of its own and gets LineStart-1.
+func (s *Section) OwnLineEnd() int {
+	if len(s.Children) > 0 && s.LineStart > 0 && s.Children[0].LineStart >= s.LineStart {
+		return s.Children[0].LineStart - 1
+	}
+	return s.LineEnd
+}
+
+// SectionAt returns the innermost section whose range covers line, or nil.
+func (d *Document) SectionAt(line int) *Section {
+	return deepestSection(d.Sections, line)
+}
+
+func deepestSection(sections []*Section, line int) *Section {
+	for _, s := range sections {
+		if line >= s.LineStart && line <= s.LineEnd {
+			if child := deepestSection(s.Children, line); child != nil {
+				return child
+			}
+			return s
+		}
+	}
+	return nil
+}
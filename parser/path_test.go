@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+const pathFixture = `# Guide
+
+## Install
+
+### Linux
+
+apt install docmap
+
+### macOS
+
+brew install docmap
+
+## Usage
+
+### Linux
+
+Run it.
+
+# Appendix
+
+## Installing from source
+`
+
+func titlesAndLines(sections []*Section) [][2]any {
+	var out [][2]any
+	for _, s := range sections {
+		out = append(out, [2]any{s.Title, s.LineStart})
+	}
+	return out
+}
+
+func TestFindSections(t *testing.T) {
+	doc := Parse(pathFixture)
+	cases := []struct {
+		path string
+		want []int // LineStart of each match
+	}{
+		{"Guide > Install > Linux", []int{5}},
+		{"install > linux", []int{5}},
+		{"Usage>Linux", []int{15}},
+		{"Linux", []int{5, 15}},
+		{"Install", []int{3}},             // whole-title match beats "Installing from source"
+		{"Appendix > install", []int{21}}, // substring fallback
+		{"Guide > Linux", nil},            // segments must be consecutive levels
+		{"  ", nil},
+	}
+	for _, c := range cases {
+		found := doc.FindSections(c.path)
+		if len(found) != len(c.want) {
+			t.Errorf("FindSections(%q) = %v, want lines %v", c.path, titlesAndLines(found), c.want)
+			continue
+		}
+		for i, s := range found {
+			if s.LineStart != c.want[i] {
+				t.Errorf("FindSections(%q)[%d] at line %d, want %d", c.path, i, s.LineStart, c.want[i])
+			}
+		}
+	}
+}
+
+func TestOwnLineEnd(t *testing.T) {
+	doc := Parse(pathFixture)
+	install := doc.FindSections("Guide > Install")[0]
+	if got := install.OwnLineEnd(); got != 4 {
+		t.Errorf("Install own content should end at line 4, got %d", got)
+	}
+	if got := install.LineEnd; got != 11 {
+		t.Errorf("Install subtree should end at line 11, got %d", got)
+	}
+	linux := doc.FindSections("Install > Linux")[0]
+	if linux.OwnLineEnd() != linux.LineEnd {
+		t.Errorf("a leaf's own content is its whole range")
+	}
+	tag := &Section{LineStart: 5, LineEnd: 9, Children: []*Section{{LineStart: 5, LineEnd: 9}}}
+	if got := tag.OwnLineEnd(); got != 4 {
+		t.Errorf("a section whose first child starts on its first line has no own lines, got end %d", got)
+	}
+}
+
+func TestSectionAt(t *testing.T) {
+	doc := Parse(pathFixture)
+	if s := doc.SectionAt(7); s == nil || s.Title != "Linux" || s.Parent.Title != "Install" {
+		t.Errorf("line 7 should be in Install > Linux, got %+v", s)
+	}
+	if s := doc.SectionAt(3); s == nil || s.Title != "Install" {
+		t.Errorf("line 3 should be in Install, got %+v", s)
+	}
+	if s := doc.SectionAt(999); s != nil {
+		t.Errorf("a line past the end has no section, got %q", s.Title)
+	}
+}
+
+func TestMatchSections(t *testing.T) {
+	doc := Parse(pathFixture)
+	cases := []struct {
+		name string
+		want []int // LineStart of each match, best first
+	}{
+		{"Install", []int{3, 21}},    // whole title first, then "Installing from source"
+		{"linux", []int{5, 15}},      // equally good: the caller must pick
+		{"instal", []int{3, 21}},     // substrings in document order
+		{"macSO", []int{9}},          // a transposed pair
+		{"Insatll", []int{3}},        // swapped letters
+		{"from sorce", []int{21}},    // a typo inside a longer title
+		{"usage > linux", []int{15}}, // breadcrumb paths
+		{"Lnux", []int{5, 15}},       // a missing letter
+		{"Lnx", nil},                 // too short for typos
+		{"Deployment", nil},
+	}
+	for _, c := range cases {
+		found := doc.MatchSections(c.name)
+		var got []int
+		for _, m := range found {
+			got = append(got, m.Section.LineStart)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("MatchSections(%q) = %v, want lines %v", c.name, got, c.want)
+		}
+	}
+	if found := doc.MatchSections("Install"); found[0].Kind != MatchExact || found[1].Kind != MatchSubstring {
+		t.Errorf("Install: unexpected kinds %+v", found)
+	}
+}
+
+func TestSplitLineSuffix(t *testing.T) {
+	cases := []struct {
+		ref  string
+		name string
+		line int
+	}{
+		{"Config@42", "Config", 42},
+		{"Guide > Linux@15", "Guide > Linux", 15},
+		{"user@host", "user@host", 0},
+		{"@12", "@12", 0},
+		{"Config@0", "Config@0", 0},
+		{"Config", "Config", 0},
+	}
+	for _, c := range cases {
+		if name, line := SplitLineSuffix(c.ref); name != c.name || line != c.line {
+			t.Errorf("SplitLineSuffix(%q) = %q, %d; want %q, %d", c.ref, name, line, c.name, c.line)
+		}
+	}
+}
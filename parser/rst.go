@@ -0,0 +1,770 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseRST parses reStructuredText into a Document. It covers the
+// constructs that carry a document's structure — section titles, literal
+// and code blocks, admonitions, list and grid tables, lists, definition
+// lists, block quotes, and :ref:/:doc: cross-references — and maps them
+// onto the node types the markdown parser produces, so the Section tree,
+// notables, and every view work unchanged.
+func ParseRST(content string) *Document {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	p := &rstParser{}
+	doc := &Document{}
+	doc.Nodes = p.parse(lines, 0, true)
+	doc.Sections, doc.TotalTokens = sectionsFromNodes(doc.Nodes)
+	doc.References = p.refs
+	rstKeyTerms(doc.Sections)
+	return doc
+}
+
+// rstKeyTerms re-extracts key terms with RST inline literals rewritten
+// to markdown backticks and roles reduced to their titles, so the markdown
+// extractor doesn't pair up the wrong backticks.
+func rstKeyTerms(sections []*Section) {
+	for _, s := range sections {
+		content := rstInlineRe.ReplaceAllStringFunc(s.Content, func(m string) string {
+			sub := rstInlineRe.FindStringSubmatch(m)
+			if sub[1] != "" {
+				return "`" + sub[1] + "`"
+			}
+			return rstInlineText(m)
+		})
+		s.KeyTerms = extractKeyTerms(content)
+		rstKeyTerms(s.Children)
+	}
+}
+
+var (
+	rstDirectiveRe  = regexp.MustCompile(`^\.\.\s+([A-Za-z][\w:.-]*)::(?:\s+(.*))?$`)
+	rstTargetRe     = regexp.MustCompile("^\\.\\.\\s+_(`[^`]+`|[^:]+):\\s*(.*)$")
+	rstFootnoteRe   = regexp.MustCompile(`^\.\.\s+\[([^\]]+)\](?:\s+(.*))?$`)
+	rstOptionRe     = regexp.MustCompile(`^:([\w-]+):(?:\s+(.*))?$`)
+	rstBulletRe     = regexp.MustCompile(`^([-*+•])(?:\s+|$)`)
+	rstEnumRe       = regexp.MustCompile(`^(?:(\d+|#)[.)]|\((\d+|#)\))(?:\s+|$)`)
+	rstGridBorderRe = regexp.MustCompile(`^\+(?:[-=]+\+)+$`)
+
+	// rstInlineRe finds ``literals``, :role:`content`, and `text <url>`_
+	// hyperlinks, in that order of precedence.
+	rstInlineRe = regexp.MustCompile("``(.+?)``|:([\\w.-]+(?::[\\w.-]+)*):`([^`]+)`|`([^`<]*?)\\s*<([^<>`]+)>`__?")
+)
+
+// rstAdmonitions maps admonition directives to callout variants.
+var rstAdmonitions = map[string]CalloutKind{
+	"note":       CalloutNote,
+	"seealso":    CalloutNote,
+	"admonition": CalloutNote,
+	"tip":        CalloutTip,
+	"hint":       CalloutTip,
+	"important":  CalloutImportant,
+	"warning":    CalloutWarning,
+	"attention":  CalloutWarning,
+	"caution":    CalloutCaution,
+	"danger":     CalloutCaution,
+	"error":      CalloutCaution,
+}
+
+type rstParser struct {
+	styles    []string // title adornment styles in order of first use; index+1 is the level
+	highlight string   // default language for :: literal blocks (.. highlight::)
+	label     string   // a pending .. _label: for the next section title
+	refs      []Reference
+}
+
+// parse turns a dedented run of lines into block nodes. offset is the
+// 0-based file line of lines[0]. Section titles are only recognized at the
+// top level, as in docutils.
+func (p *rstParser) parse(lines []string, offset int, top bool) []Node {
+	var nodes []Node
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlankLine(line) {
+			i++
+			continue
+		}
+		line = strings.TrimRight(line, " ")
+		start := offset + i + 1
+
+		if rstIndent(line) > 0 {
+			end := rstBlockEnd(lines, i, 1)
+			nodes = append(nodes, &Blockquote{BaseNode: p.container(KindBlockquote, lines, offset, i, end)})
+			i = end
+			continue
+		}
+
+		if top {
+			if n, next, ok := p.title(lines, offset, i); ok {
+				nodes = append(nodes, n)
+				i = next
+				continue
+			}
+		}
+
+		switch {
+		case isRSTAdornment(line) && utf8.RuneCountInString(line) >= 4:
+			nodes = append(nodes, &ThematicBreak{BaseNode: BaseNode{NKind: KindThematicBreak, Start: start, End: start}})
+			i++
+			continue
+
+		case rstDirectiveRe.MatchString(line):
+			m := rstDirectiveRe.FindStringSubmatch(line)
+			end := rstBlockEnd(lines, i+1, 1)
+			nodes = append(nodes, p.directive(strings.ToLower(m[1]), strings.TrimSpace(m[2]), lines[i+1:end], offset+i+1, start, offset+end)...)
+			i = end
+			continue
+
+		case rstTargetRe.MatchString(line):
+			m := rstTargetRe.FindStringSubmatch(line)
+			label := strings.Trim(m[1], "`")
+			end := rstBlockEnd(lines, i+1, 1)
+			url := strings.TrimSpace(m[2] + " " + strings.Join(trimLines(lines[i+1:end]), ""))
+			if url == "" {
+				p.label = label
+			} else {
+				nodes = append(nodes, &LinkRefDef{
+					BaseNode: BaseNode{NKind: KindLinkRefDef, Start: start, End: offset + end},
+					Label:    label,
+					URL:      url,
+				})
+			}
+			i = end
+			continue
+
+		case rstFootnoteRe.MatchString(line):
+			m := rstFootnoteRe.FindStringSubmatch(line)
+			end := rstBlockEnd(lines, i+1, 1)
+			body := append([]string{m[2]}, dedent(lines[i+1:end])...)
+			def := &FootnoteDef{BaseNode: BaseNode{NKind: KindFootnoteDef, Start: start, End: offset + end}, ID: m[1]}
+			def.Kids = p.parse(body, offset+i, false)
+			def.TokCount = sumTokens(def.Kids)
+			nodes = append(nodes, def)
+			i = end
+			continue
+
+		case line == ".." || strings.HasPrefix(line, ".. "):
+			// A comment, and everything indented under it.
+			i = rstBlockEnd(lines, i+1, 1)
+			continue
+
+		case strings.HasPrefix(line, "+") && rstGridBorderRe.MatchString(line):
+			end := i + 1
+			for end < len(lines) && (strings.HasPrefix(lines[end], "+") || strings.HasPrefix(lines[end], "|")) {
+				end++
+			}
+			nodes = append(nodes, rstGridTable(lines[i:end], offset+i))
+			i = end
+			continue
+
+		case rstBulletRe.MatchString(line) || rstEnumRe.MatchString(line):
+			list, end := p.list(lines, offset, i)
+			nodes = append(nodes, list)
+			i = end
+			continue
+		}
+
+		// A line directly followed by an indented one is a definition list
+		// term, unless it looks like a malformed "..directive", which
+		// definitionList won't take and is read as a paragraph instead.
+		if i+1 < len(lines) && !isBlankLine(lines[i+1]) && rstIndent(lines[i+1]) > 0 {
+			if dl, end := p.definitionList(lines, offset, i); end > i {
+				nodes = append(nodes, dl)
+				i = end
+				continue
+			}
+		}
+
+		// Paragraph: consecutive unindented lines.
+		end := i + 1
+		for end < len(lines) && !isBlankLine(lines[end]) && rstIndent(lines[end]) == 0 {
+			end++
+		}
+		raw := strings.Join(trimLines(lines[i:end]), "\n")
+		literal := strings.HasSuffix(raw, "::")
+		if literal {
+			switch {
+			case raw == "::":
+				raw = ""
+			case strings.HasSuffix(raw, " ::"):
+				raw = strings.TrimRight(strings.TrimSuffix(raw, "::"), " ")
+			default:
+				raw = strings.TrimSuffix(raw, ":")
+			}
+		}
+		if raw != "" {
+			nodes = append(nodes, p.paragraph(raw, start, offset+end))
+		}
+		i = end
+
+		// Expanded "::" literal block: the indented block after the paragraph.
+		if literal {
+			j := i
+			for j < len(lines) && isBlankLine(lines[j]) {
+				j++
+			}
+			if j < len(lines) && rstIndent(lines[j]) > 0 {
+				end := rstBlockEnd(lines, j, 1)
+				code := strings.Join(dedent(lines[j:end]), "\n")
+				nodes = append(nodes, &CodeBlock{
+					BaseNode: BaseNode{NKind: KindCodeBlock, Start: offset + j + 1, End: offset + end, TokCount: estimateTokens(code)},
+					Language: p.highlight,
+					Code:     code,
+				})
+				i = end
+			}
+		}
+	}
+	return nodes
+}
+
+// title recognizes a section title at lines[i]: text with an underline,
+// or an overline, text, and underline of the same character. Levels are
+// assigned in the order adornment styles first appear.
+func (p *rstParser) title(lines []string, offset, i int) (Node, int, bool) {
+	line := strings.TrimRight(lines[i], " ")
+	var text, style string
+	var next int
+	switch {
+	case isRSTAdornment(line) && i+2 < len(lines) && !isBlankLine(lines[i+1]) &&
+		strings.TrimRight(lines[i+2], " ") == line:
+		text, style, next = strings.TrimSpace(lines[i+1]), line[:1]+"/over", i+3
+	case !isRSTAdornment(line) && i+1 < len(lines) && isRSTAdornment(strings.TrimRight(lines[i+1], " ")):
+		under := strings.TrimRight(lines[i+1], " ")
+		n := utf8.RuneCountInString(under)
+		if n < 4 && n < utf8.RuneCountInString(line) {
+			return nil, 0, false
+		}
+		text, style, next = strings.TrimSpace(line), under[:1], i+2
+	default:
+		return nil, 0, false
+	}
+
+	level := 0
+	for k, s := range p.styles {
+		if s == style {
+			level = k + 1
+		}
+	}
+	if level == 0 {
+		p.styles = append(p.styles, style)
+		level = len(p.styles)
+	}
+	title := rstInlineText(text)
+	h := &Heading{
+		BaseNode: BaseNode{NKind: KindHeading, Start: offset + i + 1, End: offset + next, TokCount: estimateTokens(title)},
+		Level:    level,
+		Title:    title,
+		RawTitle: text,
+		IsSetext: true,
+		ID:       p.label,
+	}
+	p.label = ""
+	return h, next, true
+}
+
+// directive converts `.. name:: args` with its indented body. body starts
+// at file line bodyOffset (0-based); start and end are the directive's
+// 1-based line range.
+func (p *rstParser) directive(name, args string, body []string, bodyOffset, start, end int) []Node {
+	options := map[string]string{}
+	k := 0
+	for ; k < len(body) && !isBlankLine(body[k]); k++ {
+		m := rstOptionRe.FindStringSubmatch(strings.TrimSpace(body[k]))
+		if m == nil {
+			break
+		}
+		options[m[1]] = strings.TrimSpace(m[2])
+	}
+	content := dedent(body[k:])
+	contentOffset := bodyOffset + k
+	for len(content) > 0 && isBlankLine(content[0]) {
+		content, contentOffset = content[1:], contentOffset+1
+	}
+	text := strings.TrimRight(strings.Join(content, "\n"), "\n ")
+	base := BaseNode{Start: start, End: end, TokCount: estimateTokens(text)}
+
+	if variant, ok := rstAdmonitions[name]; ok {
+		if custom := CalloutKind(name); customCallouts[name] {
+			variant = custom
+		}
+		kids := p.parse(content, contentOffset, false)
+		if args != "" && name != "admonition" {
+			// `.. note:: Short text` puts the body on the directive line.
+			kids = append([]Node{p.paragraph(args, start, start)}, kids...)
+		}
+		base.NKind = KindCallout
+		base.Kids = kids
+		base.TokCount = estimateTokens(args) + sumTokens(kids)
+		return []Node{&Callout{BaseNode: base, Variant: variant}}
+	}
+	if customCallouts[name] {
+		base.NKind = KindCallout
+		base.Kids = p.parse(content, contentOffset, false)
+		return []Node{&Callout{BaseNode: base, Variant: CalloutKind(name)}}
+	}
+
+	switch name {
+	case "code-block", "code", "sourcecode":
+		base.NKind = KindCodeBlock
+		return []Node{&CodeBlock{BaseNode: base, Language: firstField(args), Info: args, Fenced: true, Code: text}}
+	case "literalinclude":
+		base.NKind = KindCodeBlock
+		return []Node{&CodeBlock{BaseNode: base, Language: options["language"], Info: "literalinclude " + args, Fenced: true}}
+	case "highlight":
+		p.highlight = firstField(args)
+		return nil
+	case "math":
+		if text == "" {
+			text = args
+		}
+		base.NKind = KindMathBlock
+		base.TokCount = estimateTokens(text)
+		return []Node{&MathBlock{BaseNode: base, TeX: text, Fence: ".. math::"}}
+	case "raw":
+		base.NKind = KindHTMLBlock
+		return []Node{&HTMLBlock{BaseNode: base, Raw: text}}
+	case "list-table":
+		headerRows, _ := strconv.Atoi(options["header-rows"])
+		return []Node{p.listTable(content, contentOffset, headerRows, base)}
+	case "toctree":
+		for n, line := range content {
+			entry := strings.TrimSpace(line)
+			if entry == "" || strings.HasPrefix(entry, ":") {
+				continue
+			}
+			title, target := splitRoleTarget(entry)
+			p.refs = append(p.refs, Reference{Text: title, Target: rstDocTarget(target), Line: contentOffset + n + 1})
+		}
+		return nil
+	}
+	// Other directives (only, container, topic, ...) keep their content.
+	return p.parse(content, contentOffset, false)
+}
+
+// paragraph builds a Paragraph from raw RST text, extracting inline
+// literals, cross-reference roles, and hyperlinks as child nodes.
+func (p *rstParser) paragraph(raw string, start, end int) *Paragraph {
+	para := &Paragraph{
+		BaseNode: BaseNode{NKind: KindParagraph, Start: start, End: end, TokCount: estimateTokens(raw)},
+		Text:     rstInlineText(raw),
+		Raw:      raw,
+	}
+	span := BaseNode{Start: start, End: end}
+	text := func(s string) {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			para.Kids = append(para.Kids, &Text{BaseNode: withKind(span, KindText, s), Value: s})
+		}
+	}
+	last := 0
+	for _, m := range rstInlineRe.FindAllStringSubmatchIndex(raw, -1) {
+		text(raw[last:m[0]])
+		last = m[1]
+		group := func(k int) string {
+			if m[2*k] < 0 {
+				return ""
+			}
+			return raw[m[2*k]:m[2*k+1]]
+		}
+		switch {
+		case m[2] >= 0:
+			code := group(1)
+			para.Kids = append(para.Kids, &InlineCode{BaseNode: withKind(span, KindInlineCode, code), Code: code})
+		case m[4] >= 0:
+			role := group(2)
+			title, target := splitRoleTarget(group(3))
+			switch strings.TrimPrefix(role, "std:") {
+			case "doc":
+				target = rstDocTarget(target)
+			case "ref":
+				target = "#" + target
+			default:
+				text(title)
+				continue
+			}
+			para.Kids = append(para.Kids, &Link{BaseNode: withKind(span, KindLink, title), URL: target, Text: title})
+			p.refs = append(p.refs, Reference{Text: title, Target: target, Line: start})
+		default:
+			title, url := group(4), group(5)
+			if title == "" {
+				title = url
+			}
+			para.Kids = append(para.Kids, &Link{BaseNode: withKind(span, KindLink, title), URL: url, Text: title})
+		}
+	}
+	text(raw[last:])
+	return para
+}
+
+// list parses a bullet or enumerated list starting at lines[i].
+func (p *rstParser) list(lines []string, offset, i int) (*List, int) {
+	first := lines[i]
+	ordered := !rstBulletRe.MatchString(first)
+	list := &List{BaseNode: BaseNode{NKind: KindList, Start: offset + i + 1}, Ordered: ordered, Tight: true}
+	if ordered {
+		m := rstEnumRe.FindStringSubmatch(first)
+		list.Start, _ = strconv.Atoi(m[1] + m[2])
+		list.Marker = '.'
+	} else {
+		list.Marker = []rune(first)[0]
+	}
+
+	end := i
+	for end < len(lines) {
+		line := lines[end]
+		var marker string
+		if ordered {
+			marker = rstEnumRe.FindString(line)
+		} else if m := rstBulletRe.FindString(line); m != "" && []rune(m)[0] == list.Marker {
+			marker = m
+		}
+		if marker == "" {
+			break
+		}
+		width := len(marker) // includes the spaces after the marker
+		itemEnd := rstBlockEnd(lines, end+1, max(width, 1))
+		body := []string{line[min(width, len(line)):]}
+		for _, l := range lines[end+1 : itemEnd] {
+			body = append(body, l[min(width, rstIndent(l), len(l)):])
+		}
+		item := &ListItem{BaseNode: BaseNode{NKind: KindListItem, Start: offset + end + 1, End: offset + itemEnd}}
+		item.Kids = p.parse(body, offset+end, false)
+		item.TokCount = sumTokens(item.Kids)
+		list.Kids = append(list.Kids, item)
+		list.TokCount += item.TokCount
+		list.End = item.End
+
+		end = itemEnd
+		for end < len(lines) && isBlankLine(lines[end]) {
+			end++
+			list.Tight = false
+		}
+	}
+	return list, end
+}
+
+// definitionList parses term lines each followed by an indented definition.
+func (p *rstParser) definitionList(lines []string, offset, i int) (*DefinitionList, int) {
+	dl := &DefinitionList{BaseNode: BaseNode{NKind: KindDefinitionList, Start: offset + i + 1}}
+	end := i
+	for end+1 < len(lines) && !isBlankLine(lines[end]) && rstIndent(lines[end]) == 0 &&
+		!strings.HasPrefix(lines[end], "..") && !isBlankLine(lines[end+1]) && rstIndent(lines[end+1]) > 0 {
+		term := strings.TrimSpace(lines[end])
+		defEnd := rstBlockEnd(lines, end+1, 1)
+		dl.Kids = append(dl.Kids, &DefinitionTerm{
+			BaseNode: BaseNode{NKind: KindDefTerm, Start: offset + end + 1, End: offset + end + 1, TokCount: estimateTokens(term)},
+			Term:     rstInlineText(term),
+		})
+		def := &Definition{BaseNode: BaseNode{NKind: KindDefinition, Start: offset + end + 2, End: offset + defEnd}}
+		def.Kids = p.parse(dedent(lines[end+1:defEnd]), offset+end+1, false)
+		def.TokCount = sumTokens(def.Kids)
+		dl.Kids = append(dl.Kids, def)
+		dl.End = def.End
+
+		end = defEnd
+		for end < len(lines) && isBlankLine(lines[end]) {
+			end++
+		}
+	}
+	dl.TokCount = sumTokens(dl.Kids)
+	return dl, end
+}
+
+// listTable converts a list-table's two-level bullet list into a Table.
+func (p *rstParser) listTable(content []string, offset, headerRows int, base BaseNode) *Table {
+	base.NKind = KindTable
+	t := &Table{BaseNode: base}
+	for _, n := range p.parse(content, offset, false) {
+		rows, ok := n.(*List)
+		if !ok {
+			continue
+		}
+		for r, item := range rows.Kids {
+			row := &TableRow{BaseNode: BaseNode{NKind: KindTableRow, Start: item.LineStart(), End: item.LineEnd()}, IsHeader: r < headerRows}
+			for _, k := range item.Children() {
+				cells, ok := k.(*List)
+				if !ok {
+					continue
+				}
+				for _, c := range cells.Kids {
+					row.Kids = append(row.Kids, tableCell(plainText(c), c.LineStart(), c.LineEnd()))
+				}
+			}
+			t.Kids = append(t.Kids, row)
+		}
+	}
+	t.Headers = tableHeaders(t)
+	return t
+}
+
+// rstGridTable parses a +---+---+ grid table. Column boundaries come from
+// the first border; a border of = marks the end of the header rows.
+func rstGridTable(lines []string, offset int) *Table {
+	border := []rune(strings.TrimRight(lines[0], " "))
+	var cols []int
+	for k, r := range border {
+		if r == '+' {
+			cols = append(cols, k)
+		}
+	}
+	t := &Table{BaseNode: BaseNode{NKind: KindTable, Start: offset + 1, End: offset + len(lines)}}
+	var rows []*TableRow
+	var cellText [][]string
+	rowStart := 0
+	flush := func(end int, header bool) {
+		if cellText == nil {
+			return
+		}
+		row := &TableRow{BaseNode: BaseNode{NKind: KindTableRow, Start: offset + rowStart + 1, End: offset + end}}
+		for _, parts := range cellText {
+			row.Kids = append(row.Kids, tableCell(strings.Join(parts, " "), row.Start, row.End))
+		}
+		rows = append(rows, row)
+		cellText = nil
+	}
+	for k, line := range lines {
+		if strings.HasPrefix(line, "+") {
+			flush(k, false)
+			if strings.Contains(line, "=") {
+				for _, row := range rows {
+					row.IsHeader = true
+				}
+			}
+			continue
+		}
+		if cellText == nil {
+			cellText = make([][]string, len(cols)-1)
+			rowStart = k
+		}
+		runes := []rune(line)
+		for c := 0; c+1 < len(cols); c++ {
+			if cols[c]+1 >= len(runes) {
+				break
+			}
+			seg := strings.TrimSpace(string(runes[cols[c]+1 : min(cols[c+1], len(runes))]))
+			if seg != "" {
+				cellText[c] = append(cellText[c], seg)
+			}
+		}
+	}
+	flush(len(lines), false)
+	for _, row := range rows {
+		t.Kids = append(t.Kids, row)
+		t.TokCount += row.TokCount
+	}
+	t.Headers = tableHeaders(t)
+	return t
+}
+
+// tableHeaders returns the text of the table's header row, or of its first
+// row when it has none, so the column count is always known.
+func tableHeaders(t *Table) []string {
+	var first *TableRow
+	for _, k := range t.Kids {
+		row := k.(*TableRow)
+		if first == nil {
+			first = row
+		}
+		if row.IsHeader {
+			first = row
+			break
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	var headers []string
+	for _, c := range first.Kids {
+		headers = append(headers, plainText(c))
+	}
+	return headers
+}
+
+func tableCell(text string, start, end int) *TableCell {
+	cell := &TableCell{BaseNode: BaseNode{NKind: KindTableCell, Start: start, End: end, TokCount: estimateTokens(text)}}
+	if text != "" {
+		cell.Kids = []Node{&Text{BaseNode: BaseNode{NKind: KindText, Start: start, End: end, TokCount: estimateTokens(text)}, Value: text}}
+	}
+	return cell
+}
+
+// container parses lines[i:end] (dedented) as the children of a block.
+func (p *rstParser) container(kind NodeKind, lines []string, offset, i, end int) BaseNode {
+	kids := p.parse(dedent(lines[i:end]), offset+i, false)
+	return BaseNode{NKind: kind, Start: offset + i + 1, End: offset + end, TokCount: sumTokens(kids), Kids: kids}
+}
+
+// rstInlineText renders RST inline markup as plain text.
+func rstInlineText(raw string) string {
+	s := rstInlineRe.ReplaceAllStringFunc(raw, func(m string) string {
+		sub := rstInlineRe.FindStringSubmatch(m)
+		switch {
+		case sub[1] != "":
+			return sub[1]
+		case sub[2] != "":
+			title, _ := splitRoleTarget(sub[3])
+			return title
+		case sub[4] != "":
+			return sub[4]
+		}
+		return sub[5]
+	})
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// splitRoleTarget splits "Title <target>" into its parts; a bare target is
+// its own title. Sphinx's "~" shortening prefix is dropped.
+func splitRoleTarget(s string) (title, target string) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "~")
+	if open := strings.LastIndex(s, "<"); open >= 0 && strings.HasSuffix(s, ">") {
+		title = strings.TrimSpace(s[:open])
+		target = s[open+1 : len(s)-1]
+		if title == "" {
+			title = target
+		}
+		return title, target
+	}
+	return s, s
+}
+
+// rstDocTarget turns a :doc: or toctree name into a file path.
+func rstDocTarget(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if strings.HasSuffix(name, ".rst") {
+		return name
+	}
+	return name + ".rst"
+}
+
+// isRSTAdornment reports whether line is a run of one repeated punctuation
+// character, as used for title underlines and transitions.
+func isRSTAdornment(line string) bool {
+	if len(line) < 2 {
+		return false
+	}
+	c := line[0]
+	if !strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", rune(c)) {
+		return false
+	}
+	for k := 1; k < len(line); k++ {
+		if line[k] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// rstBlockEnd returns the end (exclusive) of the block starting at from
+// whose lines are blank or indented at least minIndent, without trailing
+// blank lines.
+func rstBlockEnd(lines []string, from, minIndent int) int {
+	end := from
+	for end < len(lines) && (isBlankLine(lines[end]) || rstIndent(lines[end]) >= minIndent) {
+		end++
+	}
+	for end > from && isBlankLine(lines[end-1]) {
+		end--
+	}
+	return end
+}
+
+func rstIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// dedent strips the common leading indentation from lines.
+func dedent(lines []string) []string {
+	common := -1
+	for _, l := range lines {
+		if isBlankLine(l) {
+			continue
+		}
+		if n := rstIndent(l); common < 0 || n < common {
+			common = n
+		}
+	}
+	out := make([]string, len(lines))
+	for k, l := range lines {
+		if len(l) >= common && common > 0 {
+			l = l[common:]
+		}
+		out[k] = strings.TrimRight(l, " ")
+	}
+	return out
+}
+
+func trimLines(lines []string) []string {
+	out := make([]string, len(lines))
+	for k, l := range lines {
+		out[k] = strings.TrimSpace(l)
+	}
+	return out
+}
+
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// expandTabs replaces tabs with spaces to the next multiple of 8 columns,
+// as docutils does.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+func firstField(s string) string {
+	if f := strings.Fields(s); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+func withKind(b BaseNode, kind NodeKind, text string) BaseNode {
+	b.NKind = kind
+	b.TokCount = estimateTokens(text)
+	return b
+}
+
+// plainText concatenates the text of n's descendants.
+func plainText(n Node) string {
+	var parts []string
+	Walk(n, func(c Node) bool {
+		switch v := c.(type) {
+		case *Paragraph:
+			parts = append(parts, v.Text)
+			return false
+		case *Text:
+			parts = append(parts, v.Value)
+		case *InlineCode:
+			parts = append(parts, v.Code)
+		case *Link:
+			parts = append(parts, v.Text)
+			return false
+		}
+		return true
+	})
+	return strings.Join(parts, " ")
+}
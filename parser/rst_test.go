@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const rstSample = `=========
+ Project
+=========
+
+Intro paragraph with ` + "``code``" + ` and a link to :doc:` + "`install`" + `.
+
+.. _setup:
+
+Setup
+=====
+
+Run this::
+
+    make install
+    make test
+
+.. code-block:: python
+   :linenos:
+
+   print("hi")
+
+.. warning:: Back up first.
+
+   Really.
+
+.. deprecated:: 2.0
+
+   Use the new API.
+
+Details
+-------
+
+See :ref:` + "`the setup <setup>`" + ` and ` + "`Go <https://go.dev>`_" + `.
+
+.. list-table:: Flags
+   :header-rows: 1
+
+   * - Flag
+     - Meaning
+   * - -v
+     - verbose
+
++------+-------+
+| Name | Value |
++======+=======+
+| a    | 1     |
++------+-------+
+
+Usage
+=====
+
+- one
+- two
+
+term
+    definition
+
+.. toctree::
+   :maxdepth: 2
+
+   guide/intro
+   Reference <api>
+`
+
+func TestParseRSTSections(t *testing.T) {
+	doc := ParseRST(rstSample)
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Project" || doc.Sections[0].Level != 1 {
+		t.Fatalf("expected a single level-1 Project section, got %+v", doc.Sections)
+	}
+	project := doc.Sections[0]
+	var titles []string
+	for _, c := range project.Children {
+		titles = append(titles, c.Title)
+		if c.Level != 2 {
+			t.Errorf("%s: level %d, want 2", c.Title, c.Level)
+		}
+	}
+	if got := strings.Join(titles, ","); got != "Setup,Usage" {
+		t.Fatalf("children = %s", got)
+	}
+	setup := project.Children[0]
+	if setup.LineStart != 9 {
+		t.Errorf("Setup starts at line %d, want 9", setup.LineStart)
+	}
+	if len(setup.Children) != 1 || setup.Children[0].Title != "Details" || setup.Children[0].Level != 3 {
+		t.Errorf("expected Details at level 3 under Setup, got %+v", setup.Children)
+	}
+
+	for _, n := range doc.Nodes {
+		if h, ok := n.(*Heading); ok && h.Title == "Setup" && h.ID != "setup" {
+			t.Errorf("Setup heading should take its target label, got %q", h.ID)
+		}
+	}
+}
+
+func TestParseRSTBlocks(t *testing.T) {
+	doc := ParseRST(rstSample)
+	setup := doc.Sections[0].Children[0]
+
+	var codes []*CodeBlock
+	var callouts []*Callout
+	for _, n := range setup.Notables {
+		switch v := n.(type) {
+		case *CodeBlock:
+			codes = append(codes, v)
+		case *Callout:
+			callouts = append(callouts, v)
+		}
+	}
+	if len(codes) != 2 {
+		t.Fatalf("expected 2 code blocks, got %d", len(codes))
+	}
+	if codes[0].Code != "make install\nmake test" || codes[0].Language != "" {
+		t.Errorf("literal block = %q (%s)", codes[0].Code, codes[0].Language)
+	}
+	if codes[1].Code != `print("hi")` || codes[1].Language != "python" {
+		t.Errorf("code-block = %q (%s)", codes[1].Code, codes[1].Language)
+	}
+	if len(callouts) != 1 || callouts[0].Variant != CalloutWarning {
+		t.Fatalf("expected one warning callout, got %+v", callouts)
+	}
+	if text := plainText(callouts[0]); text != "Back up first. Really." {
+		t.Errorf("callout text = %q", text)
+	}
+	if !strings.Contains(setup.Content, "Run this:\n") {
+		t.Errorf("'::' should render as ':' in content:\n%s", setup.Content)
+	}
+
+	details := setup.Children[0]
+	var tables []*Table
+	for _, n := range details.Notables {
+		if tbl, ok := n.(*Table); ok {
+			tables = append(tables, tbl)
+		}
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected list-table and grid table, got %d tables", len(tables))
+	}
+	if got := strings.Join(tables[0].Headers, "|"); got != "Flag|Meaning" {
+		t.Errorf("list-table headers = %s", got)
+	}
+	if got := strings.Join(tables[1].Headers, "|"); got != "Name|Value" {
+		t.Errorf("grid table headers = %s", got)
+	}
+	if rows := len(tables[1].Kids); rows != 2 {
+		t.Errorf("grid table has %d rows, want 2", rows)
+	}
+}
+
+func TestParseRSTReferences(t *testing.T) {
+	doc := ParseRST(rstSample)
+	var got []string
+	for _, r := range doc.References {
+		got = append(got, r.Target)
+	}
+	want := "install.rst,#setup,guide/intro.rst,api.rst"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("references = %v, want %s", got, want)
+	}
+	if doc.References[1].Text != "the setup" || doc.References[3].Text != "Reference" {
+		t.Errorf("reference titles: %+v", doc.References)
+	}
+}
+
+func TestParseRSTLevelsByFirstUse(t *testing.T) {
+	// The first style seen is level 1 regardless of which character it uses.
+	doc := ParseRST("Alpha\n~~~~~\n\nBeta\n****\n\nGamma\n~~~~~\n")
+	if len(doc.Sections) != 2 || len(doc.Sections[0].Children) != 1 {
+		t.Fatalf("unexpected tree: %+v", doc.Sections)
+	}
+	if doc.Sections[0].Children[0].Title != "Beta" || doc.Sections[1].Title != "Gamma" {
+		t.Errorf("unexpected titles: %+v", doc.Sections)
+	}
+}
+
+func TestParseRSTMalformedDirectiveTerminates(t *testing.T) {
+	for _, src := range []string{"..code-block:: go\n   x\n", "..term\n   def\n"} {
+		done := make(chan *Document, 1)
+		go func() { done <- ParseRST(src) }()
+		select {
+		case doc := <-done:
+			if len(doc.Nodes) == 0 {
+				t.Errorf("%q: expected the lines to parse as content", src)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%q: parse did not terminate", src)
+		}
+	}
+}
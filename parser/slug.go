@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Slug returns the anchor GitHub generates for a heading title: lowercased,
+// punctuation dropped, spaces turned into hyphens. "Quick Start (v2)" becomes
+// "quick-start-v2".
+func Slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// Anchors maps every heading anchor in the document to its section (the
+// preamble has none). Repeated
+// titles get GitHub's -1, -2, ... suffixes in document order.
+func (d *Document) Anchors() map[string]*Section {
+	anchors := map[string]*Section{}
+	seen := map[string]int{}
+	for _, s := range d.GetAllSections() {
+		if s.IsPreamble() {
+			continue
+		}
+		slug := Slug(s.Title)
+		if n := seen[slug]; n > 0 {
+			seen[slug]++
+			slug += "-" + strconv.Itoa(n)
+		} else {
+			seen[slug] = 1
+		}
+		anchors[slug] = s
+	}
+	return anchors
+}
+
+// SectionByAnchor finds the section a #fragment points at, or nil. The
+// leading '#' is optional and matching ignores case.
+func (d *Document) SectionByAnchor(anchor string) *Section {
+	anchor = strings.ToLower(strings.TrimPrefix(anchor, "#"))
+	return d.Anchors()[anchor]
+}
@@ -0,0 +1,33 @@
+package parser
+
+import "testing"
+
+func TestSlug(t *testing.T) {
+	cases := map[string]string{
+		"Quick Start":          "quick-start",
+		"Quick Start (v2)":     "quick-start-v2",
+		"API: Auth & Tokens":   "api-auth--tokens",
+		"snake_case and-dash":  "snake_case-and-dash",
+		"Ünïcödé Heading":      "ünïcödé-heading",
+		"  padded  ":           "padded",
+		"What's new in 1.2.0?": "whats-new-in-120",
+	}
+	for title, want := range cases {
+		if got := Slug(title); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestSectionByAnchor(t *testing.T) {
+	doc := Parse("# Guide\n\n## Setup\n\n## Usage\n\n### Setup\n")
+	if s := doc.SectionByAnchor("#setup"); s == nil || s.LineStart != 3 {
+		t.Errorf("#setup should be the first Setup heading, got %+v", s)
+	}
+	if s := doc.SectionByAnchor("setup-1"); s == nil || s.LineStart != 7 {
+		t.Errorf("setup-1 should be the second Setup heading, got %+v", s)
+	}
+	if s := doc.SectionByAnchor("missing"); s != nil {
+		t.Errorf("expected nil for unknown anchor, got %+v", s)
+	}
+}
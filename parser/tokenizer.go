@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	tiktoken "github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Tokenizer counts the tokens a model would see for a piece of text. Every
+// token figure docmap reports — node, section, and document totals for
+// markdown, YAML, and PDF — goes through the active tokenizer.
+type Tokenizer interface {
+	Name() string
+	Count(s string) int
+}
+
+// DefaultTokenizer is the tokenizer used when none is selected: the fast
+// bytes/4 estimate.
+const DefaultTokenizer = "estimate"
+
+// estimator is the original len/4 heuristic. It is cheap and close enough
+// for English prose, but undercounts code, CJK text, and tables.
+type estimator struct{}
+
+func (estimator) Name() string       { return DefaultTokenizer }
+func (estimator) Count(s string) int { return len(s) / 4 }
+
+// bpeTokenizer counts tokens with a tiktoken BPE vocabulary embedded in the
+// binary, so no network access is needed.
+type bpeTokenizer struct {
+	name string
+	enc  *tiktoken.Tiktoken
+}
+
+func (b *bpeTokenizer) Name() string { return b.name }
+
+func (b *bpeTokenizer) Count(s string) int {
+	if s == "" {
+		return 0
+	}
+	return len(b.enc.EncodeOrdinary(s))
+}
+
+// bpeEncodings maps the --tokenizer names to tiktoken encodings.
+var bpeEncodings = map[string]string{
+	"cl100k": tiktoken.MODEL_CL100K_BASE,
+	"o200k":  tiktoken.MODEL_O200K_BASE,
+}
+
+var (
+	active     Tokenizer = estimator{}
+	loaderOnce sync.Once
+)
+
+// Tokenizers lists the names NewTokenizer accepts.
+func Tokenizers() []string {
+	names := []string{DefaultTokenizer}
+	for name := range bpeEncodings {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// NewTokenizer returns the tokenizer called name ("estimate", "cl100k", or
+// "o200k"). Loading a BPE vocabulary takes a moment, so callers should do
+// it once and reuse the result.
+func NewTokenizer(name string) (Tokenizer, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), "_base")
+	if name == "" || name == DefaultTokenizer {
+		return estimator{}, nil
+	}
+	encoding, ok := bpeEncodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q (want one of %s)", name, strings.Join(Tokenizers(), ", "))
+	}
+	loaderOnce.Do(func() { tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader()) })
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s vocabulary: %w", name, err)
+	}
+	return &bpeTokenizer{name: name, enc: enc}, nil
+}
+
+// SetTokenizer makes t the tokenizer for all subsequent parsing. It is not
+// safe to call while documents are being parsed.
+func SetTokenizer(t Tokenizer) {
+	active = t
+}
+
+// ActiveTokenizer returns the tokenizer parsing currently uses.
+func ActiveTokenizer() Tokenizer {
+	return active
+}
+
+// estimateTokens counts tokens in s with the active tokenizer.
+func estimateTokens(s string) int {
+	return active.Count(s)
+}
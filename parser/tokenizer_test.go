@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// withTokenizer runs fn with the named tokenizer active.
+func withTokenizer(t *testing.T, name string, fn func()) {
+	t.Helper()
+	tok, err := NewTokenizer(name)
+	if err != nil {
+		t.Fatalf("NewTokenizer(%q): %v", name, err)
+	}
+	prev := ActiveTokenizer()
+	SetTokenizer(tok)
+	defer SetTokenizer(prev)
+	fn()
+}
+
+func TestTokenizerCounts(t *testing.T) {
+	cases := []struct {
+		tokenizer string
+		text      string
+		want      int
+	}{
+		{"estimate", "hello world!", 3},
+		{"cl100k", "hello world", 2},
+		{"cl100k", "", 0},
+		{"o200k", "hello world", 2},
+	}
+	for _, c := range cases {
+		tok, err := NewTokenizer(c.tokenizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tok.Count(c.text); got != c.want {
+			t.Errorf("%s.Count(%q) = %d, want %d", c.tokenizer, c.text, got, c.want)
+		}
+	}
+}
+
+func TestTokenizerNames(t *testing.T) {
+	for _, name := range []string{"", "estimate", "cl100k", "CL100K_BASE", "o200k_base"} {
+		if _, err := NewTokenizer(name); err != nil {
+			t.Errorf("NewTokenizer(%q): %v", name, err)
+		}
+	}
+	if _, err := NewTokenizer("gpt2"); err == nil || !strings.Contains(err.Error(), "cl100k") {
+		t.Errorf("expected an error listing valid tokenizers, got %v", err)
+	}
+}
+
+// The bytes/4 estimate badly undercounts CJK text, which is the main reason
+// to pick a real vocabulary.
+func TestTokenizerCJK(t *testing.T) {
+	text := "東京は日本の首都であり、世界最大の都市圏の一つです。"
+	est, _ := NewTokenizer("estimate")
+	bpe, _ := NewTokenizer("cl100k")
+	if bpe.Count(text) <= est.Count(text) {
+		t.Errorf("expected cl100k (%d) to count more CJK tokens than the estimate (%d)", bpe.Count(text), est.Count(text))
+	}
+}
+
+func TestParseUsesActiveTokenizer(t *testing.T) {
+	content := "# Title\n\nSome prose about tokenizers.\n\n```go\nfunc main() { fmt.Println(\"hi\") }\n```\n"
+	estimated := Parse(content).TotalTokens
+
+	var doc *Document
+	var yamlDoc *Document
+	withTokenizer(t, "cl100k", func() {
+		doc = Parse(content)
+		yamlDoc, _ = ParseYAML("name: 東京は日本の首都です\n")
+	})
+	if doc.TotalTokens == estimated {
+		t.Errorf("expected cl100k totals to differ from the estimate (%d)", estimated)
+	}
+	sum := 0
+	for _, s := range doc.Sections {
+		sum += s.Tokens
+	}
+	if sum != doc.TotalTokens {
+		t.Errorf("section tokens (%d) should add up to the document total (%d)", sum, doc.TotalTokens)
+	}
+	if yamlDoc.Sections[0].Tokens <= len("東京は日本の首都です")/4 {
+		t.Errorf("expected YAML values to be counted with cl100k, got %d", yamlDoc.Sections[0].Tokens)
+	}
+	if ActiveTokenizer().Name() != DefaultTokenizer {
+		t.Errorf("withTokenizer should restore the default, got %s", ActiveTokenizer().Name())
+	}
+}
@@ -2,12 +2,17 @@ package parser
 
 import (
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// ParseYAML parses YAML content into a Document structure
+// ParseYAML parses YAML content into a Document structure. A stream of
+// ---separated documents gets one top-level section per document; anchors
+// and aliases become notables on the sections they sit in, and known file
+// formats (see yamlProfiles) get their own titles and notables.
 func ParseYAML(content string) (*Document, error) {
 	doc := &Document{}
 
@@ -15,18 +20,69 @@ func ParseYAML(content string) (*Document, error) {
 		return doc, nil
 	}
 
-	var root yaml.Node
-	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	// Each root is a DocumentNode wrapping the actual content.
+	var roots []*yaml.Node
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		root := &yaml.Node{}
+		err := dec.Decode(root)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if root.Kind == yaml.DocumentNode && len(root.Content) > 0 && !isEmptyYAML(root.Content[0]) {
+			roots = append(roots, root)
+		}
 	}
 
-	// The root node is a DocumentNode wrapping the actual content
-	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+	if len(roots) == 0 {
 		return doc, nil
 	}
+	if len(roots) == 1 && specFormat(roots[0].Content[0]) != "" {
+		return parseSpec(roots[0].Content[0]), nil
+	}
 
-	topNode := root.Content[0]
-	doc.Sections = yamlNodeToSections(topNode, 1)
+	// A lone document is mapped key by key, unless it's a Kubernetes
+	// resource, which gets its Kind/name section as it would in a stream.
+	perDocument := len(roots) > 1 || isKubernetesResource(roots[0].Content[0])
+	var anchors []Node
+	for i, root := range roots {
+		topNode := root.Content[0]
+		var docSection *Section
+		var sections []*Section
+		if perDocument {
+			docSection = &Section{
+				Level:     1,
+				Title:     yamlDocTitle(topNode, i),
+				LineStart: root.Line,
+				LineEnd:   lastLine(topNode),
+			}
+			sections = yamlNodeToSections(topNode, 2)
+			for _, child := range sections {
+				child.Parent = docSection
+				docSection.Tokens += child.Tokens // already cumulative
+			}
+			docSection.Children = sections
+			doc.Sections = append(doc.Sections, docSection)
+		} else {
+			sections = yamlNodeToSections(topNode, 1)
+			doc.Sections = sections
+		}
+
+		index := map[*yaml.Node]*Section{}
+		indexYAMLSections(topNode, sections, index)
+		doc.Nodes = append(doc.Nodes, applyYAMLProfiles(topNode, docSection, index)...)
+		anchors = append(anchors, yamlAnchorNodes(root)...)
+	}
+	for _, n := range anchors {
+		if s := doc.SectionAt(n.LineStart()); s != nil {
+			s.Notables = append(s.Notables, n)
+		}
+	}
+	doc.Nodes = append(doc.Nodes, anchors...)
+	sort.SliceStable(doc.Nodes, func(i, j int) bool { return doc.Nodes[i].LineStart() < doc.Nodes[j].LineStart() })
 
 	// Calculate total tokens
 	for _, s := range doc.GetAllSections() {
@@ -36,6 +92,65 @@ func ParseYAML(content string) (*Document, error) {
 	return doc, nil
 }
 
+// isEmptyYAML reports whether a document is empty, as after a trailing ---.
+func isEmptyYAML(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null" && n.Value == ""
+}
+
+// yamlDocTitle names one document of a stream: "Kind/name" for
+// Kubernetes-style manifests, a name/id/title field, or "document N".
+func yamlDocTitle(node *yaml.Node, index int) string {
+	kind := mapScalar(node, "kind")
+	name := mapScalar(mapValue(node, "metadata"), "name")
+	switch {
+	case kind != "" && name != "":
+		return kind + "/" + name
+	case kind != "":
+		return kind
+	case name != "":
+		return name
+	}
+	if node.Kind == yaml.MappingNode {
+		if title := yamlMapTitle(node, index); !strings.HasPrefix(title, "[") {
+			return title
+		}
+	}
+	return fmt.Sprintf("document %d", index+1)
+}
+
+// yamlAnchorNodes returns the anchors (&name) and aliases (*name) in a
+// document, in line order. Each anchor counts the aliases that use it.
+func yamlAnchorNodes(root *yaml.Node) []Node {
+	anchors := map[*yaml.Node]*Anchor{}
+	var out []Node
+	var walk func(n *yaml.Node, mergeKey bool)
+	walk = func(n *yaml.Node, mergeKey bool) {
+		if n.Anchor != "" {
+			a := &Anchor{BaseNode: BaseNode{NKind: KindAnchor, Start: n.Line, End: lastLine(n)}, Name: n.Anchor}
+			anchors[n] = a
+			out = append(out, a)
+		}
+		if n.Kind == yaml.AliasNode {
+			a := &Alias{BaseNode: BaseNode{NKind: KindAlias, Start: n.Line, End: n.Line}, Name: n.Value, Merge: mergeKey}
+			if n.Alias != nil {
+				a.AnchorLine = n.Alias.Line
+				if target, ok := anchors[n.Alias]; ok {
+					target.Uses++
+				}
+			}
+			out = append(out, a)
+			return
+		}
+		for i, c := range n.Content {
+			merge := n.Kind == yaml.MappingNode && i%2 == 1 && n.Content[i-1].Value == "<<"
+			walk(c, merge)
+		}
+	}
+	walk(root, false)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LineStart() < out[j].LineStart() })
+	return out
+}
+
 // yamlNodeToSections converts a yaml.Node into a slice of Sections
 func yamlNodeToSections(node *yaml.Node, level int) []*Section {
 	switch node.Kind {
@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlProfile teaches the YAML mapper one file format. detect looks at a
+// document's top-level node; apply retitles the generic sections and
+// attaches ConfigItem notables for the format's key constructs, returning
+// them for Document.Nodes. doc is the document's own section in a stream
+// (or a lone Kubernetes resource), nil otherwise; index maps each mapping
+// value and sequence item to the section built for it.
+type yamlProfile struct {
+	name   string
+	detect func(top *yaml.Node) bool
+	apply  func(top *yaml.Node, doc *Section, index map[*yaml.Node]*Section) []Node
+}
+
+// yamlProfiles are tried in order; the first match applies.
+var yamlProfiles = []yamlProfile{
+	{name: "kubernetes", detect: isKubernetesResource, apply: applyKubernetes},
+	{name: "actions", detect: isActionsWorkflow, apply: applyActions},
+	{name: "compose", detect: isComposeFile, apply: applyCompose},
+}
+
+func applyYAMLProfiles(top *yaml.Node, doc *Section, index map[*yaml.Node]*Section) []Node {
+	for _, p := range yamlProfiles {
+		if p.detect(top) {
+			nodes := p.apply(top, doc, index)
+			for _, n := range nodes {
+				n.(*ConfigItem).Profile = p.name
+			}
+			return nodes
+		}
+	}
+	return nil
+}
+
+// indexYAMLSections pairs each mapping value and sequence item under node
+// with the section yamlNodeToSections built for it.
+func indexYAMLSections(node *yaml.Node, sections []*Section, index map[*yaml.Node]*Section) {
+	var items []*yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			items = append(items, node.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.AliasNode {
+				return // expanded aliases break the one-section-per-item pairing
+			}
+		}
+		items = node.Content
+	}
+	for i, item := range items {
+		if i >= len(sections) {
+			return
+		}
+		index[item] = sections[i]
+		if item.Kind == yaml.MappingNode || item.Kind == yaml.SequenceNode {
+			indexYAMLSections(item, sections[i].Children, index)
+		}
+	}
+}
+
+// newConfigItem builds a ConfigItem spanning node and attaches it to s.
+func newConfigItem(kind NodeKind, name string, node *yaml.Node, s *Section) *ConfigItem {
+	item := &ConfigItem{
+		BaseNode: BaseNode{NKind: kind, Start: node.Line, End: lastLine(node)},
+		Name:     name,
+	}
+	if s != nil {
+		item.TokCount = s.Tokens
+		s.Notables = append(s.Notables, item)
+	}
+	return item
+}
+
+// ---------- Kubernetes ----------
+
+func isKubernetesResource(top *yaml.Node) bool {
+	return mapScalar(top, "apiVersion") != "" && mapScalar(top, "kind") != ""
+}
+
+// applyKubernetes marks the resource itself (and the items of a List) and
+// every container in its pod specs.
+func applyKubernetes(top *yaml.Node, doc *Section, index map[*yaml.Node]*Section) []Node {
+	var out []Node
+	var resource func(n *yaml.Node, s *Section)
+	resource = func(n *yaml.Node, s *Section) {
+		kind := mapScalar(n, "kind")
+		item := newConfigItem(KindResource, yamlDocTitle(n, 0), n, s)
+		item.Detail = mapScalar(n, "apiVersion")
+		if ns := mapScalar(mapValue(n, "metadata"), "namespace"); ns != "" {
+			item.Detail += " · ns " + ns
+		}
+		out = append(out, item)
+
+		if items := mapValue(n, "items"); strings.HasSuffix(kind, "List") && items != nil && items.Kind == yaml.SequenceNode {
+			for _, child := range items.Content {
+				if isKubernetesResource(child) {
+					if cs := index[child]; cs != nil {
+						cs.Title = yamlDocTitle(child, 0)
+					}
+					resource(child, index[child])
+				}
+			}
+			return
+		}
+		walkYAML(n, func(m *yaml.Node) {
+			if m.Kind != yaml.MappingNode {
+				return
+			}
+			for i := 0; i+1 < len(m.Content); i += 2 {
+				key, val := m.Content[i].Value, m.Content[i+1]
+				if (key != "containers" && key != "initContainers" && key != "ephemeralContainers") || val.Kind != yaml.SequenceNode {
+					continue
+				}
+				for _, c := range val.Content {
+					out = append(out, kubernetesContainer(c, index[c]))
+				}
+			}
+		})
+	}
+	resource(top, doc)
+	return out
+}
+
+func kubernetesContainer(c *yaml.Node, s *Section) *ConfigItem {
+	item := newConfigItem(KindContainer, mapScalar(c, "name"), c, s)
+	item.Image = mapScalar(c, "image")
+	if ports := mapValue(c, "ports"); ports != nil && ports.Kind == yaml.SequenceNode {
+		for _, p := range ports.Content {
+			port := mapScalar(p, "containerPort")
+			if port == "" {
+				continue
+			}
+			if proto := mapScalar(p, "protocol"); proto != "" && proto != "TCP" {
+				port += "/" + proto
+			}
+			item.Ports = append(item.Ports, port)
+		}
+	}
+	return item
+}
+
+// ---------- docker compose ----------
+
+// isComposeFile looks for a services map whose entries look like compose
+// services.
+func isComposeFile(top *yaml.Node) bool {
+	services := mapValue(top, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 1; i < len(services.Content); i += 2 {
+		svc := services.Content[i]
+		for _, key := range []string{"image", "build", "ports", "depends_on", "command", "environment"} {
+			if mapValue(svc, key) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applyCompose(top *yaml.Node, _ *Section, index map[*yaml.Node]*Section) []Node {
+	var out []Node
+	services := mapValue(top, "services")
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, svc := services.Content[i], services.Content[i+1]
+		item := newConfigItem(KindService, name.Value, svc, index[svc])
+		item.Start = name.Line
+		item.Image = mapScalar(svc, "image")
+		if item.Image == "" {
+			if build := mapValue(svc, "build"); build != nil {
+				context := build.Value
+				if build.Kind == yaml.MappingNode {
+					context = mapScalar(build, "context")
+				}
+				item.Detail = "build " + context
+			}
+		}
+		if ports := mapValue(svc, "ports"); ports != nil && ports.Kind == yaml.SequenceNode {
+			for _, p := range ports.Content {
+				switch p.Kind {
+				case yaml.ScalarNode:
+					item.Ports = append(item.Ports, p.Value)
+				case yaml.MappingNode:
+					port := mapScalar(p, "target")
+					if published := mapScalar(p, "published"); published != "" {
+						port = published + ":" + port
+					}
+					item.Ports = append(item.Ports, port)
+				}
+			}
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// ---------- GitHub Actions ----------
+
+func isActionsWorkflow(top *yaml.Node) bool {
+	jobs := mapValue(top, "jobs")
+	return jobs != nil && jobs.Kind == yaml.MappingNode && mapValue(top, "on") != nil
+}
+
+// applyActions marks each job (runner or reusable workflow, step count)
+// and each step, titling steps by name, action, or command.
+func applyActions(top *yaml.Node, _ *Section, index map[*yaml.Node]*Section) []Node {
+	var out []Node
+	jobs := mapValue(top, "jobs")
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i], jobs.Content[i+1]
+		item := newConfigItem(KindJob, id.Value, job, index[job])
+		item.Start = id.Line
+		item.Uses = mapScalar(job, "uses")
+		var detail []string
+		if runsOn := mapValue(job, "runs-on"); runsOn != nil {
+			if runsOn.Kind == yaml.ScalarNode {
+				detail = append(detail, runsOn.Value)
+			} else {
+				detail = append(detail, "runs-on …")
+			}
+		}
+		steps := mapValue(job, "steps")
+		if steps != nil && steps.Kind == yaml.SequenceNode {
+			detail = append(detail, fmt.Sprintf("%d step%s", len(steps.Content), plural(len(steps.Content))))
+		}
+		item.Detail = strings.Join(detail, " · ")
+		out = append(out, item)
+
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for k, step := range steps.Content {
+			title := actionsStepTitle(step, k)
+			s := index[step]
+			if s != nil {
+				s.Title = title
+			}
+			st := newConfigItem(KindStep, title, step, s)
+			st.Uses = mapScalar(step, "uses")
+			if st.Uses == "" && mapValue(step, "run") != nil {
+				st.Detail = "run"
+			}
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// actionsStepTitle names a step by its name, the action it uses, or the
+// first line of its run script.
+func actionsStepTitle(step *yaml.Node, index int) string {
+	if name := mapScalar(step, "name"); name != "" {
+		return truncateTitle(name)
+	}
+	if uses := mapScalar(step, "uses"); uses != "" {
+		return truncateTitle(uses)
+	}
+	if run := mapScalar(step, "run"); run != "" {
+		first, _, _ := strings.Cut(strings.TrimSpace(run), "\n")
+		return truncateTitle("run: " + first)
+	}
+	return fmt.Sprintf("[%d]", index)
+}
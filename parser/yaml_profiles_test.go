@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYAMLProfileKubernetes(t *testing.T) {
+	content := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: prod
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: ghcr.io/acme/api:1.2
+          ports:
+            - containerPort: 8080
+            - containerPort: 53
+              protocol: UDP
+`
+	doc, err := ParseYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Title != "Deployment/api" {
+		t.Fatalf("a lone resource should get its Kind/name section, got %+v", doc.Sections)
+	}
+	res, ok := doc.Sections[0].Notables[0].(*ConfigItem)
+	if !ok || res.Kind() != KindResource || res.Profile != "kubernetes" || res.Detail != "apps/v1 · ns prod" {
+		t.Errorf("unexpected resource: %+v", doc.Sections[0].Notables)
+	}
+
+	var container *ConfigItem
+	for _, n := range doc.Nodes {
+		if c, ok := n.(*ConfigItem); ok && c.Kind() == KindContainer {
+			container = c
+		}
+	}
+	if container == nil || container.Name != "api" || container.Image != "ghcr.io/acme/api:1.2" || strings.Join(container.Ports, ",") != "8080,53/UDP" {
+		t.Fatalf("unexpected container: %+v", container)
+	}
+	if container.LineStart() != 10 {
+		t.Errorf("container should start on line 10, got %d", container.LineStart())
+	}
+	if s := doc.Summary(); s.Resources != 1 || s.Containers != 1 {
+		t.Errorf("summary: %d resources, %d containers", s.Resources, s.Containers)
+	}
+}
+
+func TestYAMLProfileCompose(t *testing.T) {
+	content := `services:
+  web:
+    image: nginx:1.25
+    ports:
+      - "8080:80"
+      - target: 443
+        published: 8443
+  worker:
+    build:
+      context: ./worker
+`
+	doc, err := ParseYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	services := doc.Sections[0].Children
+	web := services[0].Notables[0].(*ConfigItem)
+	if web.Kind() != KindService || web.Name != "web" || web.Image != "nginx:1.25" || strings.Join(web.Ports, ",") != "8080:80,8443:443" {
+		t.Errorf("unexpected web service: %+v", web)
+	}
+	if web.LineStart() != 2 {
+		t.Errorf("service should start at its key, got line %d", web.LineStart())
+	}
+	worker := services[1].Notables[0].(*ConfigItem)
+	if worker.Detail != "build ./worker" {
+		t.Errorf("worker detail = %q", worker.Detail)
+	}
+}
+
+func TestYAMLProfileActions(t *testing.T) {
+	content := `name: CI
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Set up Go
+        uses: actions/setup-go@v5
+      - run: |
+          go test ./...
+          go vet ./...
+  release:
+    uses: acme/workflows/.github/workflows/release.yml@main
+`
+	doc, err := ParseYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	jobs := doc.Sections[2]
+	test := jobs.Children[0].Notables[0].(*ConfigItem)
+	if test.Kind() != KindJob || test.Detail != "ubuntu-latest · 3 steps" {
+		t.Errorf("unexpected test job: %+v", test)
+	}
+	release := jobs.Children[1].Notables[0].(*ConfigItem)
+	if release.Uses != "acme/workflows/.github/workflows/release.yml@main" {
+		t.Errorf("reusable workflow job should carry uses, got %+v", release)
+	}
+
+	var titles []string
+	for _, s := range jobs.Children[0].Children[1].Children {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, " | "); got != "actions/checkout@v4 | Set up Go | run: go test ./..." {
+		t.Errorf("step titles = %s", got)
+	}
+	if s := doc.Summary(); s.Jobs != 2 || s.Steps != 3 {
+		t.Errorf("summary: %d jobs, %d steps", s.Jobs, s.Steps)
+	}
+}
@@ -469,3 +469,87 @@ func TestParseYAMLParentPointers(t *testing.T) {
 		t.Error("grandchild's parent should be child")
 	}
 }
+
+func TestParseYAMLMultiDocument(t *testing.T) {
+	content := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+---
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+---
+name: plain
+---
+- a
+- b
+---
+`
+
+	doc, err := ParseYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"ConfigMap/app-config", "Deployment/web", "plain", "document 4"}
+	if len(doc.Sections) != len(want) {
+		t.Fatalf("expected %d documents, got %d", len(want), len(doc.Sections))
+	}
+	for i, title := range want {
+		if doc.Sections[i].Title != title {
+			t.Errorf("document %d: expected title %q, got %q", i+1, title, doc.Sections[i].Title)
+		}
+	}
+
+	deploy := doc.Sections[1]
+	if deploy.LineStart != 5 || deploy.LineEnd != 10 {
+		t.Errorf("expected Deployment on lines 5-10, got %d-%d", deploy.LineStart, deploy.LineEnd)
+	}
+	if len(deploy.Children) != 3 || deploy.Children[0].Level != 2 || deploy.Children[0].Parent != deploy {
+		t.Errorf("expected the document's keys as level-2 children, got %+v", deploy.Children)
+	}
+}
+
+func TestParseYAMLAnchorsAndAliases(t *testing.T) {
+	content := `defaults: &defaults
+  image: nginx
+  port: 80
+dev:
+  <<: *defaults
+  port: 8080
+prod: *defaults`
+
+	doc, err := ParseYAML(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defaults := doc.Sections[0]
+	if len(defaults.Notables) != 1 {
+		t.Fatalf("expected the anchor on defaults, got %d notables", len(defaults.Notables))
+	}
+	anchor, ok := defaults.Notables[0].(*Anchor)
+	if !ok || anchor.Name != "defaults" || anchor.Uses != 2 || anchor.LineStart() != 1 || anchor.LineEnd() != 3 {
+		t.Errorf("unexpected anchor: %+v", defaults.Notables[0])
+	}
+
+	merge := doc.Sections[1].Children[0]
+	if merge.Title != "<<" || len(merge.Notables) != 1 {
+		t.Fatalf("expected the merge alias on <<, got %q with %d notables", merge.Title, len(merge.Notables))
+	}
+	alias := merge.Notables[0].(*Alias)
+	if alias.Name != "defaults" || !alias.Merge || alias.LineStart() != 5 || alias.AnchorLine != 1 {
+		t.Errorf("unexpected merge alias: %+v", alias)
+	}
+
+	prod := doc.Sections[2].Notables
+	if len(prod) != 1 || prod[0].(*Alias).Merge {
+		t.Errorf("expected a plain alias on prod, got %+v", prod)
+	}
+	if s := doc.Summary(); s.Anchors != 1 || s.Aliases != 2 {
+		t.Errorf("summary: %d anchors, %d aliases", s.Anchors, s.Aliases)
+	}
+}
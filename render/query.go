@@ -0,0 +1,161 @@
+package render
+
+import "github.com/JordanCoin/docmap/parser"
+
+// The functions in this file (and search.go, for --search) answer the same
+// questions as the --type, --at, and --since views, but return data instead
+// of printing. The terminal views are built on them, and so are the
+// machine-facing modes (JSON-RPC servers, HTTP API) that need typed results.
+
+// TypeHit is one section's matches for a --type drill-down. Nodes holds
+// per-instance notables; Count (and Checked, for tasks) holds aggregate
+// counts for kinds that are only tracked as section stats.
+type TypeHit struct {
+	Section *parser.Section
+	Nodes   []parser.Node
+	Count   int
+	Checked int
+}
+
+// FindType walks every section and collects the notables of the given kind,
+// narrowed by the optional lang (code blocks) and variant (callouts)
+// sub-filters. Sections with no matches are omitted.
+func FindType(doc *parser.Document, kind parser.NodeKind, lang, variant string) []TypeHit {
+	var hits []TypeHit
+
+	var walkSections func(sections []*parser.Section)
+	walkSections = func(sections []*parser.Section) {
+		for _, s := range sections {
+			h := TypeHit{Section: s}
+			// Per-instance notables, with optional sub-filter.
+			for _, n := range s.Notables {
+				if n.Kind() != kind {
+					continue
+				}
+				if !matchesSubFilter(n, lang, variant) {
+					continue
+				}
+				h.Nodes = append(h.Nodes, n)
+			}
+			// Aggregate stats.
+			switch kind {
+			case parser.KindTaskItem:
+				h.Count = s.Stats.Tasks
+				h.Checked = s.Stats.TasksChecked
+			case parser.KindWikiLink:
+				h.Count = s.Stats.WikiLinks
+			case parser.KindWikiEmbed:
+				h.Count = s.Stats.WikiEmbeds
+			case parser.KindMention:
+				h.Count = s.Stats.Mentions
+			case parser.KindIssueRef:
+				h.Count = s.Stats.IssueRefs
+			case parser.KindCommitRef:
+				h.Count = s.Stats.CommitRefs
+			case parser.KindEmoji:
+				h.Count = s.Stats.Emojis
+			}
+			if len(h.Nodes) > 0 || h.Count > 0 {
+				hits = append(hits, h)
+			}
+			walkSections(s.Children)
+		}
+	}
+	walkSections(doc.Sections)
+	return hits
+}
+
+// NodeAt returns the deepest node in the typed AST whose range contains
+// line, and the deepest section containing it. Either may be nil.
+func NodeAt(doc *parser.Document, line int) (parser.Node, *parser.Section) {
+	// Find the deepest block node whose range contains `line`.
+	var found parser.Node
+	var findDeepest func(nodes []parser.Node)
+	findDeepest = func(nodes []parser.Node) {
+		for _, n := range nodes {
+			if n.LineStart() <= line && (n.LineEnd() == 0 || n.LineEnd() >= line) {
+				found = n
+			}
+			findDeepest(n.Children())
+		}
+	}
+	findDeepest(doc.Nodes)
+
+	// Find the section breadcrumb that contains this line.
+	var containingSection *parser.Section
+	var walkSections func(sections []*parser.Section)
+	walkSections = func(sections []*parser.Section) {
+		for _, s := range sections {
+			if s.LineStart <= line && (s.LineEnd == 0 || s.LineEnd >= line) {
+				containingSection = s
+				walkSections(s.Children)
+			}
+		}
+	}
+	walkSections(doc.Sections)
+
+	return found, containingSection
+}
+
+// ChangeHit is one section touched by a set of changed lines. Notables are
+// the constructs that start on a changed line; Lines are the changed lines
+// that belong to this section directly rather than to a subsection.
+type ChangeHit struct {
+	Section  *parser.Section
+	Notables []parser.Node
+	Lines    []int
+}
+
+// FindChanges returns the sections whose range intersects any line in
+// changed, in document order.
+func FindChanges(doc *parser.Document, changed map[int]bool) []ChangeHit {
+	// Find sections whose range intersects any changed line, and within
+	// each, the notables whose start line is in the changed set.
+	var hits []ChangeHit
+
+	var walkSections func(sections []*parser.Section)
+	walkSections = func(sections []*parser.Section) {
+		for _, s := range sections {
+			if sectionHasChangedLine(s, changed) {
+				h := ChangeHit{Section: s}
+				for _, n := range s.Notables {
+					if changed[n.LineStart()] {
+						h.Notables = append(h.Notables, n)
+					}
+				}
+				// Collect which changed lines fall inside this section (not
+				// its children) so we can show them even when no notable
+				// lives on those exact lines.
+				for line := s.LineStart; line <= s.LineEnd; line++ {
+					if changed[line] && !lineBelongsToDeeperSection(line, s.Children) {
+						h.Lines = append(h.Lines, line)
+					}
+				}
+				if len(h.Notables) > 0 || len(h.Lines) > 0 {
+					hits = append(hits, h)
+				}
+			}
+			walkSections(s.Children)
+		}
+	}
+	walkSections(doc.Sections)
+	return hits
+}
+
+func sectionHasChangedLine(s *parser.Section, changed map[int]bool) bool {
+	for line := s.LineStart; line <= s.LineEnd; line++ {
+		if changed[line] {
+			return true
+		}
+	}
+	return false
+}
+
+func lineBelongsToDeeperSection(line int, children []*parser.Section) bool {
+	for _, c := range children {
+		if c.LineStart <= line && line <= c.LineEnd {
+			return true
+		}
+	}
+	return false
+}
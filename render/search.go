@@ -0,0 +1,413 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// SearchOptions are the --regex, --case-sensitive, --word, and --limit
+// flags.
+type SearchOptions struct {
+	Regex         bool
+	CaseSensitive bool
+	Word          bool
+	Limit         int // show at most this many results, best first; 0 for all
+}
+
+// SearchQuery is a parsed --search query: free text, optionally scoped to
+// one field with a prefix. Each word of the text matches on its own (a
+// "quoted phrase" matches as a whole); results are ranked by BM25 score
+// over all the words.
+//
+//	title:auth                 section titles only
+//	code:jwt                   code blocks
+//	code:lang=go jwt           Go code blocks
+//	callout:warning deprecated warning callouts (callout: text for any variant)
+//	table:header=Status        tables with a matching header (and text in cells)
+//
+// Anything else, including an unknown prefix, is plain text.
+type SearchQuery struct {
+	Raw    string
+	Scope  string // "", "title", "code", "callout", or "table"
+	Filter string // code language, callout variant, or table header
+	Text   string // what's left after the scope and filter
+	Limit  int
+
+	text   *regexp.Regexp // nil when Text is empty
+	header *regexp.Regexp // table:header= pattern
+	terms  []string       // index terms for ranking
+	prefix bool           // rank prefix matches of terms too
+}
+
+// ParseSearchQuery compiles query under opts.
+func ParseSearchQuery(query string, opts SearchOptions) (*SearchQuery, error) {
+	q := &SearchQuery{Raw: query, Text: strings.TrimSpace(query), Limit: opts.Limit, prefix: !opts.Word}
+	if scope, rest, ok := strings.Cut(query, ":"); ok {
+		switch scope = strings.ToLower(strings.TrimSpace(scope)); scope {
+		case "title":
+			q.Scope, q.Text = scope, strings.TrimSpace(rest)
+		case "code", "table":
+			prefix := map[string]string{"code": "lang=", "table": "header="}[scope]
+			q.Scope = scope
+			if strings.HasPrefix(strings.ToLower(rest), prefix) {
+				q.Filter, rest = cutToken(rest[len(prefix):])
+			}
+			q.Text = strings.TrimSpace(rest)
+		case "callout":
+			q.Scope = scope
+			if rest != "" && rest[0] != ' ' {
+				q.Filter, rest = cutToken(rest)
+			}
+			q.Text = strings.TrimSpace(rest)
+		}
+	}
+	if q.Text == "" && q.Filter == "" {
+		if q.Scope != "" {
+			return nil, fmt.Errorf("search %q: nothing to look for after %s:", query, q.Scope)
+		}
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	var err error
+	if q.Text != "" {
+		pattern, textOpts := q.Text, opts
+		if opts.Regex {
+			q.terms = parser.IndexTerms(regexEscape.ReplaceAllString(q.Text, " "))
+		} else {
+			var alts []string
+			for _, term := range splitTerms(q.Text) {
+				alts = append(alts, regexp.QuoteMeta(term))
+				q.terms = append(q.terms, parser.IndexTerms(term)...)
+			}
+			pattern, textOpts.Regex = strings.Join(alts, "|"), true
+		}
+		if q.text, err = compileSearch(pattern, textOpts); err != nil {
+			return nil, err
+		}
+	}
+	if q.Scope == "table" && q.Filter != "" {
+		if q.header, err = compileSearch(q.Filter, opts); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// regexEscape matches escape sequences such as \b and \. so they don't leak
+// into ranking terms.
+var regexEscape = regexp.MustCompile(`\\.`)
+
+// splitTerms splits search text into words and "quoted phrases".
+func splitTerms(text string) []string {
+	var terms []string
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		var term string
+		term, text = cutToken(text)
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// cutToken splits off the first space-separated token of s, which may be
+// double-quoted to include spaces.
+func cutToken(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `"`); end >= 0 {
+			return s[1 : end+1], s[end+2:]
+		}
+	}
+	tok, rest, _ := strings.Cut(s, " ")
+	return tok, rest
+}
+
+func compileSearch(pattern string, opts SearchOptions) (*regexp.Regexp, error) {
+	if opts.Regex {
+		// Check the pattern as given, so errors don't quote our wrapping.
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid --regex pattern: %w", err)
+		}
+	} else {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.Word {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// LineMatch is one matching line of a search result. Text is the trimmed
+// source line and Spans the byte ranges of each match within it. Line is 0
+// when the document has no source lines (PDFs).
+type LineMatch struct {
+	Line  int
+	Text  string
+	Spans [][2]int
+}
+
+// SearchResult holds a matched section with its file context, its BM25
+// score, and the lines that matched in its own content (not its
+// subsections').
+type SearchResult struct {
+	Filename string
+	Path     string // e.g. "endpoints > Ban Member"
+	Section  *parser.Section
+	Score    float64
+	Matches  []LineMatch
+}
+
+// scopeFields are the index fields a scoped query is ranked on. Tables have
+// no field of their own; their text is indexed as body.
+var scopeFields = map[string][]parser.IndexField{
+	"title":   {parser.FieldTitle},
+	"code":    {parser.FieldCode},
+	"callout": {parser.FieldCallout},
+	"table":   {parser.FieldBody},
+}
+
+// Search returns every section across docs that matches q, best score
+// first; ties, such as every hit of a scope-only query, keep document
+// order. linesOf supplies each document's source lines for line numbers
+// and snippets; it may be nil, or return nil, and the search falls back to
+// the parsed content.
+func Search(docs []*parser.Document, q *SearchQuery, linesOf func(*parser.Document) []string) []SearchResult {
+	var results []SearchResult
+	for _, doc := range docs {
+		var lines []string
+		if linesOf != nil {
+			lines = linesOf(doc)
+		}
+		searchSections(doc.Filename, doc.Sections, "", q, lines, &results)
+	}
+	scores := parser.ScoreSections(docs, q.terms, q.prefix, scopeFields[q.Scope]...)
+	for i := range results {
+		results[i].Score = scores[results[i].Section]
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+func searchSections(filename string, sections []*parser.Section, parentPath string, q *SearchQuery, lines []string, results *[]SearchResult) {
+	for _, s := range sections {
+		if matches := q.matchSection(s, lines); len(matches) > 0 {
+			*results = append(*results, SearchResult{
+				Filename: filename,
+				Path:     parentPath,
+				Section:  s,
+				Matches:  matches,
+			})
+		}
+
+		// Search children
+		childPath := parentPath
+		if childPath != "" {
+			childPath += " > " + s.Title
+		} else {
+			childPath = s.Title
+		}
+		searchSections(filename, s.Children, childPath, q, lines, results)
+	}
+}
+
+// matchSection returns the lines of s that match q, or nil.
+func (q *SearchQuery) matchSection(s *parser.Section, lines []string) []LineMatch {
+	switch q.Scope {
+	case "title":
+		if !q.text.MatchString(s.Title) {
+			return nil
+		}
+		return []LineMatch{q.lineMatch(lines, s.LineStart, s.Title)}
+	case "code", "callout", "table":
+		var out []LineMatch
+		for _, n := range s.Notables {
+			if q.scopeMatches(n) {
+				out = append(out, q.nodeMatches(n, lines)...)
+			}
+		}
+		return out
+	}
+
+	var out []LineMatch
+	if len(lines) > 0 {
+		out = q.scanLines(lines, s.LineStart, s.OwnLineEnd())
+	} else {
+		out = q.scanText(s.Title + "\n" + s.Content)
+	}
+	if len(out) == 0 && (q.text.MatchString(s.Title) || notablesMatch(s, q.text.MatchString)) {
+		// A field that isn't on the section's own lines, such as a YAML
+		// key or an operation's tag: point at the section itself.
+		out = append(out, q.lineMatch(lines, s.LineStart, s.Title))
+	}
+	return out
+}
+
+// scopeMatches reports whether n is the kind of notable the scope names and
+// passes its filter.
+func (q *SearchQuery) scopeMatches(n parser.Node) bool {
+	switch v := n.(type) {
+	case *parser.CodeBlock:
+		return q.Scope == "code" && (q.Filter == "" || strings.EqualFold(v.Language, q.Filter))
+	case *parser.Callout:
+		return q.Scope == "callout" && (q.Filter == "" || strings.EqualFold(string(v.Variant), q.Filter))
+	case *parser.Table:
+		if q.Scope != "table" {
+			return false
+		}
+		if q.header == nil {
+			return true
+		}
+		for _, h := range v.Headers {
+			if q.header.MatchString(h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeMatches returns the lines of n matching the query text, or n's first
+// line when the query is only a scope and filter.
+func (q *SearchQuery) nodeMatches(n parser.Node, lines []string) []LineMatch {
+	if q.text == nil {
+		return []LineMatch{q.lineMatch(lines, n.LineStart(), nodeText(n))}
+	}
+	if len(lines) > 0 {
+		return q.scanLines(lines, n.LineStart(), n.LineEnd())
+	}
+	return q.scanText(nodeText(n))
+}
+
+// scanLines matches each source line in [from, to].
+func (q *SearchQuery) scanLines(lines []string, from, to int) []LineMatch {
+	var out []LineMatch
+	for line := max(from, 1); line <= to && line <= len(lines); line++ {
+		if m, ok := q.match(lines[line-1]); ok {
+			m.Line = line
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// scanText matches each line of parsed text, for documents without source
+// lines.
+func (q *SearchQuery) scanText(text string) []LineMatch {
+	var out []LineMatch
+	for _, l := range strings.Split(text, "\n") {
+		if m, ok := q.match(l); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (q *SearchQuery) match(line string) (LineMatch, bool) {
+	m := q.lineMatch(nil, 0, line)
+	return m, len(m.Spans) > 0
+}
+
+// lineMatch builds the match for a line, taking its text from lines when
+// they cover it and from fallback otherwise.
+func (q *SearchQuery) lineMatch(lines []string, line int, fallback string) LineMatch {
+	text := fallback
+	if line >= 1 && line <= len(lines) {
+		text = lines[line-1]
+	} else {
+		line = 0
+	}
+	m := LineMatch{Line: line, Text: strings.TrimSpace(text)}
+	if q.text != nil {
+		for _, loc := range q.text.FindAllStringIndex(m.Text, -1) {
+			if loc[1] > loc[0] {
+				m.Spans = append(m.Spans, [2]int{loc[0], loc[1]})
+			}
+		}
+	}
+	return m
+}
+
+// nodeText is the searchable text of a scoped notable when there are no
+// source lines.
+func nodeText(n parser.Node) string {
+	switch v := n.(type) {
+	case *parser.CodeBlock:
+		return v.Code
+	case *parser.Callout:
+		var parts []string
+		for _, k := range v.Kids {
+			if p, ok := k.(*parser.Paragraph); ok {
+				parts = append(parts, p.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case *parser.Table:
+		return strings.Join(v.Headers, " | ")
+	}
+	return ""
+}
+
+// notablesMatch returns true if any of a section's notable nodes match.
+// The per-kind comparisons cover the fields an agent is likely to search
+// by: code language, callout variant, wiki link target, link ref label,
+// footnote id, etc.
+func notablesMatch(s *parser.Section, match func(string) bool) bool {
+	for _, n := range s.Notables {
+		switch v := n.(type) {
+		case *parser.CodeBlock:
+			if match(v.Language) || match(v.Code) {
+				return true
+			}
+		case *parser.Callout:
+			if match(string(v.Variant)) || match(nodeText(v)) {
+				return true
+			}
+		case *parser.Table:
+			for _, h := range v.Headers {
+				if match(h) {
+					return true
+				}
+			}
+		case *parser.MathBlock:
+			if match(v.TeX) {
+				return true
+			}
+		case *parser.FootnoteDef:
+			if match(v.ID) {
+				return true
+			}
+		case *parser.LinkRefDef:
+			if match(v.Label) || match(v.URL) {
+				return true
+			}
+		case *parser.HTMLBlock:
+			if match(v.Raw) {
+				return true
+			}
+		case *parser.Anchor:
+			if match(v.Name) {
+				return true
+			}
+		case *parser.Alias:
+			if match(v.Name) {
+				return true
+			}
+		case *parser.ConfigItem:
+			if match(v.Name) || match(v.Image) || match(v.Uses) {
+				return true
+			}
+		case *parser.Operation:
+			if match(v.OperationID) || match(v.Summary) || match(strings.Join(v.Tags, " ")) {
+				return true
+			}
+		}
+	}
+	return false
+}
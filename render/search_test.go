@@ -0,0 +1,136 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+const searchFixture = "# Auth\n\nJWT tokens are signed. Authorization uses a jwt.\n\n```go\ntoken := jwt.New()\n```\n\n```python\nimport jwt\n```\n\n> [!WARNING]\n> This flow is deprecated.\n\n## Status\n\n| Status | Meaning |\n|--------|---------|\n| done   | shipped |\n"
+
+// searchLines runs query over the fixture and returns "line:text" for each
+// matching line, across all results.
+func searchLines(t *testing.T, query string, opts SearchOptions) []string {
+	t.Helper()
+	q, err := ParseSearchQuery(query, opts)
+	if err != nil {
+		t.Fatalf("%q: %v", query, err)
+	}
+	doc := parser.Parse(searchFixture)
+	lines := strings.Split(searchFixture, "\n")
+	var out []string
+	for _, r := range Search([]*parser.Document{doc}, q, func(*parser.Document) []string { return lines }) {
+		for _, m := range r.Matches {
+			out = append(out, strconv.Itoa(m.Line)+":"+m.Text)
+		}
+	}
+	return out
+}
+
+func TestSearchScopes(t *testing.T) {
+	tests := []struct {
+		query string
+		opts  SearchOptions
+		want  []string
+	}{
+		{"jwt", SearchOptions{}, []string{"3:JWT tokens are signed. Authorization uses a jwt.", "6:token := jwt.New()", "10:import jwt"}},
+		{"JWT", SearchOptions{CaseSensitive: true}, []string{"3:JWT tokens are signed. Authorization uses a jwt."}},
+		{"auth", SearchOptions{Word: true}, []string{"1:# Auth"}},
+		{`jwt\.\w+`, SearchOptions{Regex: true}, []string{"6:token := jwt.New()"}},
+		{"title:status", SearchOptions{}, []string{"16:## Status"}},
+		{"code:lang=go jwt", SearchOptions{}, []string{"6:token := jwt.New()"}},
+		{"code:lang=python", SearchOptions{}, []string{"10:import jwt"}},
+		{"callout:warning deprecated", SearchOptions{}, []string{"14:> This flow is deprecated."}},
+		{"callout:note deprecated", SearchOptions{}, nil},
+		{"table:header=status shipped", SearchOptions{}, []string{"20:| done   | shipped |"}},
+		{"notascope:jwt", SearchOptions{}, nil},
+	}
+	for _, tt := range tests {
+		got := searchLines(t, tt.query, tt.opts)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	for _, query := range []string{"", "title:", "code:", "("} {
+		if _, err := ParseSearchQuery(query, SearchOptions{Regex: true}); err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+	q, err := ParseSearchQuery(`table:header="Due date" soon`, SearchOptions{})
+	if err != nil || q.Filter != "Due date" || q.Text != "soon" {
+		t.Errorf("quoted header: %+v, %v", q, err)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	m := LineMatch{Text: "use a jwt here", Spans: [][2]int{{6, 9}}}
+	if got, want := highlightSnippet(m, 100), "use a "+bold+yellow+"jwt"+reset+" here"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	long := LineMatch{Text: strings.Repeat("a", 200) + "jwt" + strings.Repeat("b", 200), Spans: [][2]int{{200, 203}}}
+	got := highlightSnippet(long, 40)
+	if !strings.HasPrefix(got, dim+"…") || !strings.HasSuffix(got, "…"+reset) || !strings.Contains(got, bold+yellow+"jwt"+reset) {
+		t.Errorf("long line should be trimmed around the match, got %q", got)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	doc := parser.Parse("# Guide\n\nSee the deploy notes.\n\n## Deploy\n\nDeploy with make deploy.\n\n## Misc\n\nUnrelated.\n")
+	q, err := ParseSearchQuery("deploy", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Search([]*parser.Document{doc}, q, nil)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Section.Title != "Deploy" || results[0].Score <= results[1].Score {
+		t.Errorf("ranking: %q %.3f, %q %.3f",
+			results[0].Section.Title, results[0].Score, results[1].Section.Title, results[1].Score)
+	}
+}
+
+func TestSearchRankingExactTitle(t *testing.T) {
+	doc := parser.Parse("# Config\n\nSet options.\n\n# Configuration\n\nThe config file holds every configuration option; config is read at start.\n\n# Setup\n\nEdit the config.\n")
+	for _, query := range []string{"config", "title:config"} {
+		q, err := ParseSearchQuery(query, SearchOptions{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := Search([]*parser.Document{doc}, q, nil)
+		if len(results) == 0 || results[0].Section.Title != "Config" {
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Section.Title+" "+strconv.FormatFloat(r.Score, 'f', 3, 64))
+			}
+			t.Errorf("%q: exact title should rank first, got %q", query, got)
+		}
+	}
+}
+
+func TestSearchSpecNoDuplicateLines(t *testing.T) {
+	spec := "openapi: 3.0.0\ntags:\n  - name: users\npaths:\n  /users/{id}:\n    get:\n      tags: [users]\n      operationId: getUser\n      responses:\n        '200':\n          description: ok\n"
+	doc, err := parser.ParseYAML(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := ParseSearchQuery("getUser", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(spec, "\n")
+	var got []string
+	for _, r := range Search([]*parser.Document{doc}, q, func(*parser.Document) []string { return lines }) {
+		for _, m := range r.Matches {
+			got = append(got, r.Path+":"+strconv.Itoa(m.Line))
+		}
+	}
+	if len(got) != 1 {
+		t.Errorf("expected one hit under the operation only, got %v", got)
+	}
+}
@@ -3,6 +3,7 @@ package render
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/JordanCoin/docmap/parser"
 )
@@ -106,10 +107,39 @@ func buildSummaryLines(s parser.ContentSummary) []string {
 	if s.HTMLBlocks > 0 {
 		blocks = append(blocks, fmt.Sprintf("%d HTML", s.HTMLBlocks))
 	}
+	if s.Operations > 0 {
+		blocks = append(blocks, fmt.Sprintf("%d operation%s", s.Operations, pluralS(s.Operations)))
+	}
+	if s.Anchors > 0 {
+		blocks = append(blocks, fmt.Sprintf("%d anchor%s", s.Anchors, pluralS(s.Anchors)))
+	}
+	if s.Aliases > 0 {
+		blocks = append(blocks, fmt.Sprintf("%d alias%s", s.Aliases, pluralES(s.Aliases)))
+	}
 	if len(blocks) > 0 {
 		lines = append(lines, strings.Join(blocks, " · "))
 	}
 
+	var config []string
+	if s.Resources > 0 {
+		config = append(config, fmt.Sprintf("%d resource%s", s.Resources, pluralS(s.Resources)))
+	}
+	if s.Containers > 0 {
+		config = append(config, fmt.Sprintf("%d container%s", s.Containers, pluralS(s.Containers)))
+	}
+	if s.Services > 0 {
+		config = append(config, fmt.Sprintf("%d service%s", s.Services, pluralS(s.Services)))
+	}
+	if s.Jobs > 0 {
+		config = append(config, fmt.Sprintf("%d job%s", s.Jobs, pluralS(s.Jobs)))
+	}
+	if s.Steps > 0 {
+		config = append(config, fmt.Sprintf("%d step%s", s.Steps, pluralS(s.Steps)))
+	}
+	if len(config) > 0 {
+		lines = append(lines, strings.Join(config, " · "))
+	}
+
 	var interactive []string
 	if s.Tasks > 0 {
 		interactive = append(interactive, fmt.Sprintf("%d task%s (%d done)", s.Tasks, pluralS(s.Tasks), s.TasksChecked))
@@ -151,6 +181,13 @@ func pluralS(n int) string {
 	return "s"
 }
 
+func pluralES(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "es"
+}
+
 func centerText(s string, width int) string {
 	if len(s) >= width {
 		return s[:width]
@@ -184,6 +221,8 @@ func renderSection(s *parser.Section, prefix string, isLast bool, isFiltered boo
 	// Title color by level.
 	var titleColor string
 	switch s.Level {
+	case 0: // preamble
+		titleColor = dim
 	case 1:
 		titleColor = bold + cyan
 	case 2:
@@ -373,6 +412,55 @@ func formatNotableGroup(kind parser.NodeKind, nodes []parser.Node) string {
 			pieces = append(pieces, fmt.Sprintf("[%s] :%d", lrd.Label, lrd.LineStart()))
 		}
 		return fmt.Sprintf("%d ref%s %s", len(nodes), pluralS(len(nodes)), strings.Join(pieces, ", "))
+
+	case parser.KindOperation:
+		// Operation sections are titled "GET /path" already, so show the
+		// signature: id, parameter count, request and responses.
+		var pieces []string
+		for _, n := range nodes {
+			op := n.(*parser.Operation)
+			var sig []string
+			if op.OperationID != "" {
+				sig = append(sig, op.OperationID)
+			}
+			if len(op.Parameters) > 0 {
+				sig = append(sig, fmt.Sprintf("%d param%s", len(op.Parameters), pluralS(len(op.Parameters))))
+			}
+			if op.Request != "" {
+				sig = append(sig, "← "+op.Request)
+			}
+			if len(op.Responses) > 0 {
+				sig = append(sig, "→ "+strings.Join(op.Responses, ", "))
+			}
+			pieces = append(pieces, strings.Join(sig, " · "))
+		}
+		return strings.Join(pieces, "; ")
+
+	case parser.KindAnchor:
+		var pieces []string
+		for _, n := range nodes {
+			a := n.(*parser.Anchor)
+			pieces = append(pieces, fmt.Sprintf("&%s :%d (%d use%s)", a.Name, a.LineStart(), a.Uses, pluralS(a.Uses)))
+		}
+		return strings.Join(pieces, ", ")
+
+	case parser.KindAlias:
+		var pieces []string
+		for _, n := range nodes {
+			a := n.(*parser.Alias)
+			pieces = append(pieces, fmt.Sprintf("*%s :%d", a.Name, a.LineStart()))
+		}
+		return strings.Join(pieces, ", ")
+
+	case parser.KindResource, parser.KindContainer, parser.KindService, parser.KindJob, parser.KindStep:
+		// The section title already names the item; show what it runs.
+		var pieces []string
+		for _, n := range nodes {
+			if summary := configItemSummary(n.(*parser.ConfigItem)); summary != "" {
+				pieces = append(pieces, summary)
+			}
+		}
+		return strings.Join(pieces, "; ")
 	}
 	return ""
 }
@@ -404,66 +492,18 @@ func TypeFilter(doc *parser.Document, kindName string) {
 // (for code blocks) or --kind (for callout variants). Unmatched sub-filters
 // are silently ignored when the kind doesn't support them.
 func TypeFilterFiltered(doc *parser.Document, kindName, lang, variant string) {
-	kind, ok := resolveKindName(kindName)
+	kind, ok := ResolveKindName(kindName)
 	if !ok {
-		fmt.Printf("Unknown type %q. Try: code, callout, table, math, footnote, deflist, linkref, html, task, wiki, embed, mention, issue, sha, emoji\n", kindName)
+		fmt.Printf("Unknown type %q. Try: code, callout, table, math, footnote, deflist, linkref, html, task, wiki, embed, mention, issue, sha, emoji, operation, anchor, alias, resource, container, service, job, step\n", kindName)
 		return
 	}
 
-	// Walk every section and collect matching nodes along with their
-	// section breadcrumb.
-	type hit struct {
-		section *parser.Section
-		nodes   []parser.Node
-		count   int // for aggregate-only kinds like Tasks
-		extra   int // TasksChecked, etc.
-	}
-	var hits []hit
-
-	var walkSections func(sections []*parser.Section)
-	walkSections = func(sections []*parser.Section) {
-		for _, s := range sections {
-			h := hit{section: s}
-			// Per-instance notables, with optional sub-filter.
-			for _, n := range s.Notables {
-				if n.Kind() != kind {
-					continue
-				}
-				if !matchesSubFilter(n, lang, variant) {
-					continue
-				}
-				h.nodes = append(h.nodes, n)
-			}
-			// Aggregate stats.
-			switch kind {
-			case parser.KindTaskItem:
-				h.count = s.Stats.Tasks
-				h.extra = s.Stats.TasksChecked
-			case parser.KindWikiLink:
-				h.count = s.Stats.WikiLinks
-			case parser.KindWikiEmbed:
-				h.count = s.Stats.WikiEmbeds
-			case parser.KindMention:
-				h.count = s.Stats.Mentions
-			case parser.KindIssueRef:
-				h.count = s.Stats.IssueRefs
-			case parser.KindCommitRef:
-				h.count = s.Stats.CommitRefs
-			case parser.KindEmoji:
-				h.count = s.Stats.Emojis
-			}
-			if len(h.nodes) > 0 || h.count > 0 {
-				hits = append(hits, h)
-			}
-			walkSections(s.Children)
-		}
-	}
-	walkSections(doc.Sections)
+	hits := FindType(doc, kind, lang, variant)
 
 	// Header box.
 	total := 0
 	for _, h := range hits {
-		total += len(h.nodes) + h.count
+		total += len(h.Nodes) + h.Count
 	}
 	label := kindDisplayName(kind)
 	info := fmt.Sprintf("%d %s in %d section%s", total, label, len(hits), pluralS(len(hits)))
@@ -475,17 +515,17 @@ func TypeFilterFiltered(doc *parser.Document, kindName, lang, variant string) {
 	}
 
 	for _, h := range hits {
-		crumb := breadcrumb(h.section)
-		fmt.Printf("%s%s%s %s(%s)%s\n", bold+cyan, crumb, reset, dim, formatTokens(h.section.Tokens), reset)
-		for _, n := range h.nodes {
+		crumb := Breadcrumb(h.Section)
+		fmt.Printf("%s%s%s %s(%s)%s\n", bold+cyan, crumb, reset, dim, formatTokens(h.Section.Tokens), reset)
+		for _, n := range h.Nodes {
 			fmt.Printf("  %s%s%s\n", dim, formatTypeHit(kind, n), reset)
 		}
-		if h.count > 0 {
+		if h.Count > 0 {
 			switch kind {
 			case parser.KindTaskItem:
-				fmt.Printf("  %s%d tasks (%d done)%s\n", dim, h.count, h.extra, reset)
+				fmt.Printf("  %s%d tasks (%d done)%s\n", dim, h.Count, h.Checked, reset)
 			default:
-				fmt.Printf("  %s%d %s%s\n", dim, h.count, label, reset)
+				fmt.Printf("  %s%d %s%s\n", dim, h.Count, label, reset)
 			}
 		}
 		fmt.Println()
@@ -513,8 +553,8 @@ func matchesSubFilter(n parser.Node, lang, variant string) bool {
 	return true
 }
 
-// resolveKindName maps a short CLI token to a parser.NodeKind.
-func resolveKindName(name string) (parser.NodeKind, bool) {
+// ResolveKindName maps a short CLI token to a parser.NodeKind.
+func ResolveKindName(name string) (parser.NodeKind, bool) {
 	switch strings.ToLower(name) {
 	case "code", "codeblock", "code-block":
 		return parser.KindCodeBlock, true
@@ -546,6 +586,22 @@ func resolveKindName(name string) (parser.NodeKind, bool) {
 		return parser.KindCommitRef, true
 	case "emoji":
 		return parser.KindEmoji, true
+	case "operation", "operations", "op", "endpoint":
+		return parser.KindOperation, true
+	case "anchor", "anchors":
+		return parser.KindAnchor, true
+	case "alias", "aliases":
+		return parser.KindAlias, true
+	case "resource", "resources":
+		return parser.KindResource, true
+	case "container", "containers":
+		return parser.KindContainer, true
+	case "service", "services":
+		return parser.KindService, true
+	case "job", "jobs":
+		return parser.KindJob, true
+	case "step", "steps":
+		return parser.KindStep, true
 	}
 	return "", false
 }
@@ -583,6 +639,22 @@ func kindDisplayName(k parser.NodeKind) string {
 		return "commit refs"
 	case parser.KindEmoji:
 		return "emoji"
+	case parser.KindOperation:
+		return "operations"
+	case parser.KindAnchor:
+		return "anchors"
+	case parser.KindAlias:
+		return "aliases"
+	case parser.KindResource:
+		return "resources"
+	case parser.KindContainer:
+		return "containers"
+	case parser.KindService:
+		return "services"
+	case parser.KindJob:
+		return "jobs"
+	case parser.KindStep:
+		return "steps"
 	}
 	return string(k)
 }
@@ -602,9 +674,9 @@ func formatTypeHit(kind parser.NodeKind, n parser.Node) string {
 			}
 		}
 		if v.LineEnd() > v.LineStart() {
-			return fmt.Sprintf(":%d-%d  %-10s", v.LineStart(), v.LineEnd(), lang)
+			return fmt.Sprintf(":%d-%d  %-10s%s", v.LineStart(), v.LineEnd(), lang, cellSuffix(v))
 		}
-		return fmt.Sprintf(":%d     %-10s", v.LineStart(), lang)
+		return fmt.Sprintf(":%d     %-10s%s", v.LineStart(), lang, cellSuffix(v))
 	case *parser.Callout:
 		snippet := calloutSnippetText(v)
 		return fmt.Sprintf(":%-4d  %-10s  %s", v.LineStart(), v.Variant, snippet)
@@ -636,10 +708,52 @@ func formatTypeHit(kind parser.NodeKind, n parser.Node) string {
 	case *parser.HTMLBlock:
 		tag := htmlTag(v.Raw)
 		return fmt.Sprintf(":%-4d  <%s>", v.LineStart(), tag)
+	case *parser.Operation:
+		s := fmt.Sprintf(":%-4d  %-7s %s", v.LineStart(), v.Method, v.Path)
+		if v.OperationID != "" {
+			s += "  " + v.OperationID
+		}
+		if v.Summary != "" {
+			s += " — " + v.Summary
+		}
+		return s
+	case *parser.Anchor:
+		return fmt.Sprintf(":%-4d  &%s  %d use%s", v.LineStart(), v.Name, v.Uses, pluralS(v.Uses))
+	case *parser.Alias:
+		s := fmt.Sprintf(":%-4d  *%s → :%d", v.LineStart(), v.Name, v.AnchorLine)
+		if v.Merge {
+			s += "  (merge)"
+		}
+		return s
+	case *parser.ConfigItem:
+		s := fmt.Sprintf(":%-4d  %s", v.LineStart(), v.Name)
+		if summary := configItemSummary(v); summary != "" {
+			s += "  " + summary
+		}
+		return s
 	}
 	return fmt.Sprintf(":%d", n.LineStart())
 }
 
+// configItemSummary lists what a YAML profile item runs: image, ports,
+// action, and any detail such as the runner or apiVersion.
+func configItemSummary(c *parser.ConfigItem) string {
+	var parts []string
+	if c.Image != "" {
+		parts = append(parts, c.Image)
+	}
+	if len(c.Ports) > 0 {
+		parts = append(parts, "ports "+strings.Join(c.Ports, ", "))
+	}
+	if c.Uses != "" {
+		parts = append(parts, "uses "+c.Uses)
+	}
+	if c.Detail != "" {
+		parts = append(parts, c.Detail)
+	}
+	return strings.Join(parts, " · ")
+}
+
 // calloutSnippetText extracts the first line of a callout's body, stripping
 // the [!KIND] marker, so --type callout can show meaningful context.
 func calloutSnippetText(c *parser.Callout) string {
@@ -660,8 +774,8 @@ func calloutSnippetText(c *parser.Callout) string {
 	return strings.TrimSpace(text)
 }
 
-// breadcrumb walks up from a section to the root, joining titles with ` > `.
-func breadcrumb(s *parser.Section) string {
+// Breadcrumb walks up from a section to the root, joining titles with ` > `.
+func Breadcrumb(s *parser.Section) string {
 	var parts []string
 	for cur := s; cur != nil; cur = cur.Parent {
 		parts = append([]string{cur.Title}, parts...)
@@ -693,37 +807,16 @@ func printMiniHeader(title, info string) {
 // from elsewhere (a grep hit, a diff, an error) and needs to know what
 // construct lives there.
 func AtLine(doc *parser.Document, line int) {
-	// Find the deepest block node whose range contains `line`.
-	var found parser.Node
-	var findDeepest func(nodes []parser.Node)
-	findDeepest = func(nodes []parser.Node) {
-		for _, n := range nodes {
-			if n.LineStart() <= line && (n.LineEnd() == 0 || n.LineEnd() >= line) {
-				found = n
-			}
-			findDeepest(n.Children())
-		}
-	}
-	findDeepest(doc.Nodes)
-
-	// Find the section breadcrumb that contains this line.
-	var containingSection *parser.Section
-	var walkSections func(sections []*parser.Section)
-	walkSections = func(sections []*parser.Section) {
-		for _, s := range sections {
-			if s.LineStart <= line && (s.LineEnd == 0 || s.LineEnd >= line) {
-				containingSection = s
-				walkSections(s.Children)
-			}
-		}
-	}
-	walkSections(doc.Sections)
+	found, containingSection := NodeAt(doc, line)
 
 	info := fmt.Sprintf("line %d", line)
+	if cell, cellLine, ok := doc.CellAt(line); ok {
+		info += fmt.Sprintf(" (cell %d:%d)", cell.Index, cellLine)
+	}
 	printMiniHeader(doc.Filename+" — "+info, nodeAtSummary(found, containingSection))
 
 	if containingSection != nil {
-		fmt.Printf("%sSection:%s %s%s%s\n", bold, reset, cyan, breadcrumb(containingSection), reset)
+		fmt.Printf("%sSection:%s %s%s%s\n", bold, reset, cyan, Breadcrumb(containingSection), reset)
 	}
 	if found != nil {
 		fmt.Printf("%sNode:   %s %s\n", bold, reset, detailForNode(found))
@@ -733,6 +826,18 @@ func AtLine(doc *parser.Document, line int) {
 	fmt.Println()
 }
 
+// cellSuffix labels a notebook code block with its cell and outputs.
+func cellSuffix(cb *parser.CodeBlock) string {
+	if cb.Cell == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("  cell %d", cb.Cell)
+	if cb.Outputs != nil && !cb.Outputs.Empty() {
+		s += "  → " + cb.Outputs.String()
+	}
+	return s
+}
+
 // nodeAtSummary is the single-line subtitle for the --at header.
 func nodeAtSummary(n parser.Node, s *parser.Section) string {
 	if n == nil && s == nil {
@@ -765,7 +870,7 @@ func detailForNode(n parser.Node) string {
 				lang = "(indent)"
 			}
 		}
-		return fmt.Sprintf("code L%d-%d  lang=%s", v.LineStart(), v.LineEnd(), lang)
+		return fmt.Sprintf("code L%d-%d  lang=%s%s", v.LineStart(), v.LineEnd(), lang, cellSuffix(v))
 	case *parser.Callout:
 		return fmt.Sprintf("callout L%d  kind=%s  %s", v.LineStart(), v.Variant, calloutSnippetText(v))
 	case *parser.Table:
@@ -792,6 +897,18 @@ func detailForNode(n parser.Node) string {
 		return fmt.Sprintf("frontmatter L%d-%d  format=%s", v.LineStart(), v.LineEnd(), v.Format)
 	case *parser.ThematicBreak:
 		return fmt.Sprintf("thematic break L%d", v.LineStart())
+	case *parser.Operation:
+		s := fmt.Sprintf("operation L%d-%d  %s", v.LineStart(), v.LineEnd(), v.Title())
+		if v.OperationID != "" {
+			s += "  id=" + v.OperationID
+		}
+		return s
+	case *parser.Anchor:
+		return fmt.Sprintf("anchor L%d-%d  &%s  %d use%s", v.LineStart(), v.LineEnd(), v.Name, v.Uses, pluralS(v.Uses))
+	case *parser.Alias:
+		return fmt.Sprintf("alias L%d  *%s → L%d", v.LineStart(), v.Name, v.AnchorLine)
+	case *parser.ConfigItem:
+		return fmt.Sprintf("%s L%d-%d  %s  %s", v.Kind(), v.LineStart(), v.LineEnd(), v.Name, configItemSummary(v))
 	}
 	return fmt.Sprintf("%s L%d-%d", n.Kind(), n.LineStart(), n.LineEnd())
 }
@@ -806,41 +923,7 @@ func ChangedSince(doc *parser.Document, changed map[int]bool, ref string) {
 		return
 	}
 
-	// Find sections whose range intersects any changed line, and within
-	// each, the notables whose start line is in the changed set.
-	type hit struct {
-		section  *parser.Section
-		notables []parser.Node
-		lines    []int
-	}
-	var hits []hit
-
-	var walkSections func(sections []*parser.Section)
-	walkSections = func(sections []*parser.Section) {
-		for _, s := range sections {
-			if sectionHasChangedLine(s, changed) {
-				h := hit{section: s}
-				for _, n := range s.Notables {
-					if changed[n.LineStart()] {
-						h.notables = append(h.notables, n)
-					}
-				}
-				// Collect which changed lines fall inside this section (not
-				// its children) so we can show them even when no notable
-				// lives on those exact lines.
-				for line := s.LineStart; line <= s.LineEnd; line++ {
-					if changed[line] && !lineBelongsToDeeperSection(line, s.Children) {
-						h.lines = append(h.lines, line)
-					}
-				}
-				if len(h.notables) > 0 || len(h.lines) > 0 {
-					hits = append(hits, h)
-				}
-			}
-			walkSections(s.Children)
-		}
-	}
-	walkSections(doc.Sections)
+	hits := FindChanges(doc, changed)
 
 	totalLines := len(changed)
 	info := fmt.Sprintf("%d changed line%s across %d section%s",
@@ -848,50 +931,26 @@ func ChangedSince(doc *parser.Document, changed map[int]bool, ref string) {
 	printMiniHeader(doc.Filename+" — since "+ref, info)
 
 	if len(hits) == 0 {
-		fmt.Println("Changes are outside any heading (frontmatter, intro, etc).")
+		fmt.Println("Changes are outside any section (frontmatter, etc).")
 		fmt.Println()
 		return
 	}
 
 	for _, h := range hits {
-		crumb := breadcrumb(h.section)
-		fmt.Printf("%s%s%s %s(%s)%s\n", bold+cyan, crumb, reset, dim, formatTokens(h.section.Tokens), reset)
-		for _, n := range h.notables {
+		crumb := Breadcrumb(h.Section)
+		fmt.Printf("%s%s%s %s(%s)%s\n", bold+cyan, crumb, reset, dim, formatTokens(h.Section.Tokens), reset)
+		for _, n := range h.Notables {
 			fmt.Printf("  %s%s%s\n", dim, detailForNode(n), reset)
 		}
-		if len(h.notables) == 0 && len(h.lines) > 0 {
-			fmt.Printf("  %s%d line%s changed%s\n", dim, len(h.lines), pluralS(len(h.lines)), reset)
+		if len(h.Notables) == 0 && len(h.Lines) > 0 {
+			fmt.Printf("  %s%d line%s changed%s\n", dim, len(h.Lines), pluralS(len(h.Lines)), reset)
 		}
 		fmt.Println()
 	}
 }
 
-func sectionHasChangedLine(s *parser.Section, changed map[int]bool) bool {
-	for line := s.LineStart; line <= s.LineEnd; line++ {
-		if changed[line] {
-			return true
-		}
-	}
-	return false
-}
-
-func lineBelongsToDeeperSection(line int, children []*parser.Section) bool {
-	for _, c := range children {
-		if c.LineStart <= line && line <= c.LineEnd {
-			return true
-		}
-	}
-	return false
-}
-
-// FilteredTree shows only sections matching the filter
-func FilteredTree(doc *parser.Document, filter string) {
-	section := doc.GetSection(filter)
-	if section == nil {
-		fmt.Printf("Section '%s' not found\n", filter)
-		return
-	}
-
+// FilteredTree shows only the given section and its subtree
+func FilteredTree(section *parser.Section) {
 	// Print mini header
 	fmt.Printf("%s╭── %s%s%s (%s tokens)%s\n", dim, reset, bold+cyan, section.Title, formatTokens(section.Tokens), reset)
 
@@ -905,13 +964,7 @@ func FilteredTree(doc *parser.Document, filter string) {
 }
 
 // ExpandSection shows full content of a section
-func ExpandSection(doc *parser.Document, name string) {
-	section := doc.GetSection(name)
-	if section == nil {
-		fmt.Printf("Section '%s' not found\n", name)
-		return
-	}
-
+func ExpandSection(section *parser.Section) {
 	fmt.Printf("%s%s%s\n", bold+cyan, section.Title, reset)
 	fmt.Println(dim + strings.Repeat("─", 50) + reset)
 	fmt.Println()
@@ -1001,6 +1054,14 @@ func aggregateSummary(docs []*parser.Document) parser.ContentSummary {
 		agg.IssueRefs += s.IssueRefs
 		agg.CommitRefs += s.CommitRefs
 		agg.Emojis += s.Emojis
+		agg.Operations += s.Operations
+		agg.Anchors += s.Anchors
+		agg.Aliases += s.Aliases
+		agg.Resources += s.Resources
+		agg.Containers += s.Containers
+		agg.Services += s.Services
+		agg.Jobs += s.Jobs
+		agg.Steps += s.Steps
 	}
 	return agg
 }
@@ -1053,29 +1114,29 @@ func renderDocSummary(doc *parser.Document, isLast bool) {
 	fmt.Printf("%s%s%s%s %s%s\n", dim, connector, reset, bold+green+doc.Filename+reset, tokenStr, annotation)
 }
 
-// SearchResult holds a matched section with its file context
-type SearchResult struct {
-	Filename string
-	Path     string // e.g. "endpoints > Ban Member"
-	Section  *parser.Section
-}
-
-// SearchResults searches all docs for sections matching the query and renders results
-func SearchResults(docs []*parser.Document, query string) {
-	query = strings.ToLower(query)
-	var results []SearchResult
-
-	for _, doc := range docs {
-		searchSections(doc.Filename, doc.Sections, "", query, &results)
-	}
+// SearchResults searches all docs for sections matching q and renders the
+// best q.Limit of them with their matching lines. linesOf supplies source
+// lines (see Search).
+func SearchResults(docs []*parser.Document, q *SearchQuery, linesOf func(*parser.Document) []string) {
+	results := Search(docs, q, linesOf)
 
 	if len(results) == 0 {
-		fmt.Printf("No sections matching '%s'\n", query)
+		fmt.Printf("No sections matching '%s'\n", q.Raw)
 		return
 	}
 
 	// Header
-	fmt.Printf("%s%d matches for '%s'%s\n\n", bold, len(results), query, reset)
+	lines := 0
+	for _, r := range results {
+		lines += len(r.Matches)
+	}
+	fmt.Printf("%s%d match%s in %d section%s for '%s'%s",
+		bold, lines, pluralES(lines), len(results), pluralS(len(results)), q.Raw, reset)
+	if q.Limit > 0 && len(results) > q.Limit {
+		fmt.Printf("%s · top %d%s", dim, q.Limit, reset)
+		results = results[:q.Limit]
+	}
+	fmt.Print("\n\n")
 
 	for i, r := range results {
 		isLast := i == len(results)-1
@@ -1091,104 +1152,65 @@ func SearchResults(docs []*parser.Document, query string) {
 		}
 		fmt.Printf("%s%s%s%s%s%s %s\n", dim, connector, reset, filePart, bold+cyan+r.Section.Title+reset, "", tokenStr)
 
-		// Show children summary if present
-		if len(r.Section.Children) > 0 {
-			childPrefix := "│   "
-			if isLast {
-				childPrefix = "    "
+		// Matching lines, with the matches highlighted.
+		childPrefix := "│   "
+		if isLast {
+			childPrefix = "    "
+		}
+		for j, m := range r.Matches {
+			if j >= 5 {
+				fmt.Printf("%s%s... %d more%s\n", childPrefix, dim, len(r.Matches)-5, reset)
+				break
 			}
-			for j, child := range r.Section.Children {
-				if j >= 5 {
-					fmt.Printf("%s%s... %d more%s\n", childPrefix, dim, len(r.Section.Children)-5, reset)
-					break
-				}
-				childIsLast := j == len(r.Section.Children)-1 || j == 4
-				childConn := "├─ "
-				if childIsLast {
-					childConn = "└─ "
-				}
-				fmt.Printf("%s%s%s%s %s\n", childPrefix, dim, childConn, child.Title+reset, dim+fmt.Sprintf("(%s)", formatTokens(child.Tokens))+reset)
+			lineStr := "    "
+			if m.Line > 0 {
+				lineStr = fmt.Sprintf("L%-3d", m.Line)
 			}
+			fmt.Printf("%s%s%s%s %s\n", childPrefix, dim, lineStr, reset, highlightSnippet(m, 100))
 		}
 	}
 
 	fmt.Println()
 }
 
-func searchSections(filename string, sections []*parser.Section, parentPath string, query string, results *[]SearchResult) {
-	for _, s := range sections {
-		match := strings.Contains(strings.ToLower(s.Title), query) ||
-			strings.Contains(strings.ToLower(s.Content), query) ||
-			notablesMatch(s, query)
-
-		if match {
-			*results = append(*results, SearchResult{
-				Filename: filename,
-				Path:     parentPath,
-				Section:  s,
-			})
+// highlightSnippet renders a matched line at most width bytes wide, centred
+// on the first match, with every match in bold yellow.
+func highlightSnippet(m LineMatch, width int) string {
+	text, spans := m.Text, m.Spans
+	start, end := 0, len(text)
+	if len(text) > width {
+		if len(spans) > 0 {
+			start = max(spans[0][0]-width/4, 0)
 		}
-
-		// Search children
-		childPath := parentPath
-		if childPath != "" {
-			childPath += " > " + s.Title
-		} else {
-			childPath = s.Title
+		end = min(start+width, len(text))
+		start = max(end-width, 0)
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
 		}
-		searchSections(filename, s.Children, childPath, query, results)
 	}
-}
 
-// notablesMatch returns true if any of a section's notable nodes match the
-// query. The per-kind comparisons cover the fields an agent is likely to
-// search by: code language, callout variant, wiki link target, link ref
-// label, footnote id, etc.
-func notablesMatch(s *parser.Section, query string) bool {
-	for _, n := range s.Notables {
-		switch v := n.(type) {
-		case *parser.CodeBlock:
-			if strings.Contains(strings.ToLower(v.Language), query) ||
-				strings.Contains(strings.ToLower(v.Code), query) {
-				return true
-			}
-		case *parser.Callout:
-			if strings.Contains(strings.ToLower(string(v.Variant)), query) {
-				return true
-			}
-			for _, k := range v.Kids {
-				if p, ok := k.(*parser.Paragraph); ok {
-					if strings.Contains(strings.ToLower(p.Text), query) {
-						return true
-					}
-				}
-			}
-		case *parser.Table:
-			for _, h := range v.Headers {
-				if strings.Contains(strings.ToLower(h), query) {
-					return true
-				}
-			}
-		case *parser.MathBlock:
-			if strings.Contains(strings.ToLower(v.TeX), query) {
-				return true
-			}
-		case *parser.FootnoteDef:
-			if strings.Contains(strings.ToLower(v.ID), query) {
-				return true
-			}
-		case *parser.LinkRefDef:
-			if strings.Contains(strings.ToLower(v.Label), query) ||
-				strings.Contains(strings.ToLower(v.URL), query) {
-				return true
-			}
-		case *parser.HTMLBlock:
-			if strings.Contains(strings.ToLower(v.Raw), query) {
-				return true
-			}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(dim + "…" + reset)
+	}
+	pos := start
+	for _, sp := range spans {
+		lo, hi := max(sp[0], pos), min(sp[1], end)
+		if lo >= hi {
+			continue
 		}
+		b.WriteString(text[pos:lo])
+		b.WriteString(bold + yellow + text[lo:hi] + reset)
+		pos = hi
+	}
+	b.WriteString(text[pos:end])
+	if end < len(text) {
+		b.WriteString(dim + "…" + reset)
 	}
-	return false
+	return b.String()
 }
 
 // RefsTree renders document references (links to other .md files)
@@ -62,12 +62,16 @@ func TestResolveKindName(t *testing.T) {
 		{"wiki", parser.KindWikiLink, true},
 		{"embed", parser.KindWikiEmbed, true},
 		{"linkref", parser.KindLinkRefDef, true},
+		{"operation", parser.KindOperation, true},
+		{"alias", parser.KindAlias, true},
+		{"step", parser.KindStep, true},
+		{"container", parser.KindContainer, true},
 		{"nonexistent", "", false},
 	}
 	for _, tc := range tests {
-		got, ok := resolveKindName(tc.input)
+		got, ok := ResolveKindName(tc.input)
 		if got != tc.want || ok != tc.ok {
-			t.Errorf("resolveKindName(%q) = (%q, %v), want (%q, %v)", tc.input, got, ok, tc.want, tc.ok)
+			t.Errorf("ResolveKindName(%q) = (%q, %v), want (%q, %v)", tc.input, got, ok, tc.want, tc.ok)
 		}
 	}
 }
@@ -0,0 +1,126 @@
+package main
+
+import "path/filepath"
+
+// Minimal SARIF 2.1.0 output for `docmap lint --format sarif`, enough for
+// GitHub code scanning and editors that read SARIF logs.
+
+const (
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// sarifSrcRoot is the base id result paths are relative to: the
+	// repository root, or the linted directory outside a git checkout.
+	sarifSrcRoot = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifact `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult            `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarif converts the report to a SARIF log listing the enabled rules.
+// Paths are made relative to the repository root, which is what code
+// scanning expects, rather than to the linted directory.
+func (r JSONLintReport) sarif(cfg lintConfig) sarifLog {
+	driver := sarifDriver{
+		Name:           "docmap",
+		Version:        version,
+		InformationURI: "https://github.com/JordanCoin/docmap",
+		Rules:          []sarifRule{},
+	}
+	index := map[string]int{}
+	for _, rule := range lintRules {
+		if cfg.Disabled[rule.ID] {
+			continue
+		}
+		index[rule.ID] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{rule.Description}})
+	}
+
+	srcRoot := gitRoot(r.Root)
+	if srcRoot == "" {
+		srcRoot = r.Root
+	}
+	uri := func(file string) string {
+		if rel, err := filepath.Rel(srcRoot, filepath.Join(r.Root, filepath.FromSlash(file))); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return file
+	}
+
+	results := []sarifResult{}
+	for _, p := range r.Problems {
+		results = append(results, sarifResult{
+			RuleID:    p.Rule,
+			RuleIndex: index[p.Rule],
+			Level:     "warning",
+			Message:   sarifMessage{p.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{URI: uri(p.File), URIBaseID: sarifSrcRoot},
+				Region:           sarifRegion{StartLine: max(p.Line, 1)},
+			}}},
+		})
+	}
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:               sarifTool{Driver: driver},
+			OriginalURIBaseIDs: map[string]sarifArtifact{sarifSrcRoot: {URI: pathToURI(srcRoot) + "/"}},
+			Results:            results,
+		}},
+	}
+}
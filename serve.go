@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/JordanCoin/docmap/parser"
+	"github.com/JordanCoin/docmap/render"
+)
+
+// docmap serve keeps a parsed tree in memory and answers JSON queries over
+// HTTP. Every request first rescans the tree with the same watchState that
+// drives --watch, so edited files are re-parsed (size/mtime check) before
+// they are served and unchanged files are never parsed twice.
+
+// JSONDirectorySummary is the answer to /api/summary: per-file totals
+// without the section trees.
+type JSONDirectorySummary struct {
+	Root        string            `json:"root"`
+	TotalTokens int               `json:"total_tokens"`
+	TotalDocs   int               `json:"total_docs"`
+	Files       []JSONFileSummary `json:"files"`
+}
+
+// JSONFileSummary is one file's line in a JSONDirectorySummary.
+type JSONFileSummary struct {
+	Filename string      `json:"filename"`
+	Tokens   int         `json:"tokens"`
+	Sections int         `json:"sections"`
+	Summary  JSONSummary `json:"summary"`
+}
+
+// apiError is the body of every non-200 response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiServer serves the HTTP API for one file or directory.
+type apiServer struct {
+	root  string // absolute path reported in responses
+	mu    sync.Mutex
+	state *watchState
+	mux   *http.ServeMux
+}
+
+func newAPIServer(target string, isDir bool, opts parseOptions) *apiServer {
+	root, _ := filepath.Abs(target)
+	s := &apiServer{root: root, state: newWatchState(target, isDir, opts), mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/summary", s.handleSummary)
+	s.mux.HandleFunc("GET /api/tree", s.handleTree)
+	s.mux.HandleFunc("GET /api/section", s.handleSection)
+	s.mux.HandleFunc("GET /api/type", s.handleType)
+	s.mux.HandleFunc("GET /api/at", s.handleAt)
+	s.mux.HandleFunc("GET /api/search", s.handleSearch)
+	s.mux.HandleFunc("GET /api/refs", s.handleRefs)
+	return s
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// runServe implements `docmap serve [--addr host:port] [dir]`.
+func runServe(target, addr string, opts parseOptions) {
+	if target == "" {
+		target = "."
+	}
+	if addr == "" {
+		addr = "localhost:8080"
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	srv := newAPIServer(target, info.IsDir(), opts)
+	srv.documents() // parse up front so the first request is fast
+	fmt.Fprintf(os.Stderr, "docmap serving %s on http://%s\n", srv.root, addr)
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// documents reloads changed files and returns the current documents.
+func (s *apiServer) documents() []*parser.Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.refresh()
+	return s.state.documents()
+}
+
+// scope returns every document, or just the one named by ?file= when set.
+func (s *apiServer) scope(r *http.Request) ([]*parser.Document, error) {
+	docs := s.documents()
+	name := r.URL.Query().Get("file")
+	if name == "" {
+		return docs, nil
+	}
+	for _, doc := range docs {
+		if doc.Filename == filepath.Clean(name) {
+			return []*parser.Document{doc}, nil
+		}
+	}
+	return nil, fmt.Errorf("file %q not found", name)
+}
+
+// file returns the document named by the required ?file= parameter. In
+// single-file mode the parameter may be omitted.
+func (s *apiServer) file(r *http.Request) (*parser.Document, error) {
+	if r.URL.Query().Get("file") == "" {
+		if docs := s.documents(); !s.state.isDir && len(docs) == 1 {
+			return docs[0], nil
+		}
+		return nil, errMissingParam("file")
+	}
+	docs, err := s.scope(r)
+	if err != nil {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+type errMissingParam string
+
+func (e errMissingParam) Error() string {
+	return fmt.Sprintf("missing %q parameter", string(e))
+}
+
+func (s *apiServer) handleSummary(w http.ResponseWriter, r *http.Request) {
+	docs := s.documents()
+	res := JSONDirectorySummary{Root: s.root, TotalDocs: len(docs), Files: []JSONFileSummary{}}
+	for _, doc := range docs {
+		res.TotalTokens += doc.TotalTokens
+		res.Files = append(res.Files, JSONFileSummary{
+			Filename: doc.Filename,
+			Tokens:   doc.TotalTokens,
+			Sections: len(doc.GetAllSections()),
+			Summary:  convertSummary(doc.Summary()),
+		})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *apiServer) handleTree(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("file") == "" {
+		writeJSON(w, http.StatusOK, buildJSONOutput(s.documents(), s.root))
+		return
+	}
+	doc, err := s.file(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, convertDocument(doc))
+}
+
+func (s *apiServer) handleSection(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	doc, err := s.file(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if q.Get("name") == "" {
+		writeError(w, errMissingParam("name"))
+		return
+	}
+	expand, _ := strconv.ParseBool(q.Get("expand"))
+	index, _ := strconv.Atoi(q.Get("section_index"))
+	res, err := querySection(doc, q.Get("name"), index, expand)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *apiServer) handleType(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("type") == "" {
+		writeError(w, errMissingParam("type"))
+		return
+	}
+	docs, err := s.scope(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := queryType(docs, q.Get("type"), q.Get("lang"), q.Get("kind"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *apiServer) handleAt(w http.ResponseWriter, r *http.Request) {
+	doc, err := s.file(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	line, err := strconv.Atoi(r.URL.Query().Get("line"))
+	if err != nil || line <= 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{"line must be a positive line number"})
+		return
+	}
+	writeJSON(w, http.StatusOK, queryAt(doc, line))
+}
+
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		writeError(w, errMissingParam("q"))
+		return
+	}
+	docs, err := s.scope(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var opts render.SearchOptions
+	opts.Regex, _ = strconv.ParseBool(q.Get("regex"))
+	opts.CaseSensitive, _ = strconv.ParseBool(q.Get("case_sensitive"))
+	opts.Word, _ = strconv.ParseBool(q.Get("word"))
+	opts.Limit, _ = strconv.Atoi(q.Get("limit"))
+	res, err := querySearch(docs, q.Get("q"), opts, sourceLines(s.state.target, s.state.isDir))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *apiServer) handleRefs(w http.ResponseWriter, r *http.Request) {
+	docs, err := s.scope(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, queryRefs(docs))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps a lookup error to 400 (bad request) or 404 (unknown file).
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusNotFound
+	if _, ok := err.(errMissingParam); ok {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, apiError{err.Error()})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func apiGet[T any](t *testing.T, srv *httptest.Server, path string, wantStatus int) T {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: expected status %d, got %d", path, wantStatus, resp.StatusCode)
+	}
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	return v
+}
+
+func writeServeFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide.md":   "# Guide\n\n## Install\n\n```go\nfmt.Println(1)\n```\n\nSee [api](api.md).\n",
+		"api.md":     "# API\n\n> [!WARNING]\n> Tokens expire.\n",
+		"config.yml": "server:\n  port: 8080\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestServeEndpoints(t *testing.T) {
+	dir := writeServeFixture(t)
+	srv := httptest.NewServer(newAPIServer(dir, true, parseOptions{Jobs: 1}))
+	defer srv.Close()
+
+	summary := apiGet[JSONDirectorySummary](t, srv, "/api/summary", http.StatusOK)
+	if summary.TotalDocs != 3 || len(summary.Files) != 3 {
+		t.Errorf("summary: expected 3 files, got %+v", summary)
+	}
+
+	tree := apiGet[JSONOutput](t, srv, "/api/tree", http.StatusOK)
+	if tree.TotalDocs != 3 || tree.Root == "" {
+		t.Errorf("tree: unexpected output %+v", tree)
+	}
+	doc := apiGet[JSONDocument](t, srv, "/api/tree?file=guide.md", http.StatusOK)
+	if doc.Filename != "guide.md" || len(doc.Sections) != 1 {
+		t.Errorf("tree?file: unexpected document %+v", doc)
+	}
+
+	section := apiGet[JSONSectionResult](t, srv, "/api/section?file=guide.md&name=install&expand=1", http.StatusOK)
+	if section.Breadcrumb != "Guide > Install" || section.Content == "" {
+		t.Errorf("section: unexpected result %+v", section)
+	}
+
+	typ := apiGet[JSONTypeResult](t, srv, "/api/type?type=callout&kind=warning", http.StatusOK)
+	if typ.Total != 1 || typ.Hits[0].Filename != "api.md" {
+		t.Errorf("type: unexpected result %+v", typ)
+	}
+
+	at := apiGet[JSONAtResult](t, srv, "/api/at?file=guide.md&line=6", http.StatusOK)
+	if at.Node == nil || at.Node.Kind != "code_block" {
+		t.Errorf("at: expected code block, got %+v", at)
+	}
+
+	search := apiGet[JSONSearchResult](t, srv, "/api/search?q=tokens", http.StatusOK)
+	if len(search.Matches) != 1 || search.Matches[0].Filename != "api.md" || search.Matches[0].Score <= 0 {
+		t.Errorf("search: unexpected matches %+v", search.Matches)
+	}
+	if m := search.Matches[0]; len(m.Lines) != 1 || m.Lines[0].Line != 4 || m.Lines[0].Spans[0] != [2]int{2, 8} {
+		t.Errorf("search: expected the match on line 4, got %+v", m.Lines)
+	}
+	if code := apiGet[JSONSearchResult](t, srv, "/api/search?q=code:lang=go+println&word=1", http.StatusOK); len(code.Matches) != 1 || code.Matches[0].Lines[0].Line != 6 {
+		t.Errorf("scoped search: unexpected matches %+v", code.Matches)
+	}
+
+	refs := apiGet[JSONRefsResult](t, srv, "/api/refs", http.StatusOK)
+	if len(refs.References) != 1 {
+		t.Errorf("refs: expected 1 reference, got %+v", refs)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	dir := writeServeFixture(t)
+	srv := httptest.NewServer(newAPIServer(dir, true, parseOptions{Jobs: 1}))
+	defer srv.Close()
+
+	for path, status := range map[string]int{
+		"/api/section?name=install":           http.StatusBadRequest,
+		"/api/section?file=nope.md&name=x":    http.StatusNotFound,
+		"/api/section?file=guide.md&name=zzz": http.StatusNotFound,
+		"/api/type?type=bogus":                http.StatusBadRequest,
+		"/api/at?file=guide.md&line=0":        http.StatusBadRequest,
+		"/api/search":                         http.StatusBadRequest,
+		"/api/search?q=(&regex=1":             http.StatusBadRequest,
+	} {
+		if e := apiGet[apiError](t, srv, path, status); e.Error == "" {
+			t.Errorf("GET %s: expected an error message", path)
+		}
+	}
+}
+
+func TestServeReloadsChangedFiles(t *testing.T) {
+	dir := writeServeFixture(t)
+	srv := httptest.NewServer(newAPIServer(dir, true, parseOptions{Jobs: 1}))
+	defer srv.Close()
+
+	if got := apiGet[JSONSearchResult](t, srv, "/api/search?q=rotation", http.StatusOK); len(got.Matches) != 0 {
+		t.Fatalf("unexpected match before edit: %+v", got.Matches)
+	}
+
+	path := filepath.Join(dir, "api.md")
+	if err := os.WriteFile(path, []byte("# API\n\n## Key rotation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	os.Remove(filepath.Join(dir, "config.yml"))
+
+	if got := apiGet[JSONSearchResult](t, srv, "/api/search?q=rotation", http.StatusOK); len(got.Matches) != 1 {
+		t.Errorf("expected edited file to be re-parsed, got %+v", got.Matches)
+	}
+	if summary := apiGet[JSONDirectorySummary](t, srv, "/api/summary", http.StatusOK); summary.TotalDocs != 2 {
+		t.Errorf("expected removed file to drop out, got %d docs", summary.TotalDocs)
+	}
+}
+
+func TestServeSingleFile(t *testing.T) {
+	dir := writeServeFixture(t)
+	srv := httptest.NewServer(newAPIServer(filepath.Join(dir, "guide.md"), false, parseOptions{Jobs: 1}))
+	defer srv.Close()
+
+	at := apiGet[JSONAtResult](t, srv, "/api/at?line=3", http.StatusOK)
+	if at.Filename != "guide.md" || at.Breadcrumb != "Guide > Install" {
+		t.Errorf("expected file parameter to be optional for a single file, got %+v", at)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+const (
+	// watchDebounce is how long the filesystem must stay quiet before a
+	// burst of events (editor save = write + rename + chmod) is handled.
+	watchDebounce = 200 * time.Millisecond
+	// watchPollInterval is the rescan interval when fsnotify is unavailable.
+	watchPollInterval = time.Second
+
+	clearScreen = "\033[H\033[2J"
+	dimText     = "\033[2m"
+	resetText   = "\033[0m"
+)
+
+// WatchEvent is one line of the --watch --json NDJSON stream. Documents
+// holds only the files parsed for this event (every file for "initial");
+// the totals always cover the whole watched tree.
+type WatchEvent struct {
+	Event       string         `json:"event"` // "initial" or "update"
+	Time        string         `json:"time"`
+	Root        string         `json:"root"`
+	TotalTokens int            `json:"total_tokens"`
+	TotalDocs   int            `json:"total_docs"`
+	Changed     []string       `json:"changed,omitempty"`
+	Removed     []string       `json:"removed,omitempty"`
+	Failed      []string       `json:"failed,omitempty"`
+	Documents   []JSONDocument `json:"documents,omitempty"`
+}
+
+// fileStamp is the cheap change signal for a watched file.
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+// watchState holds parsed documents between redraws so that only files
+// whose size or mtime changed are parsed again.
+type watchState struct {
+	target string
+	isDir  bool
+	opts   parseOptions
+	order  []string // watched paths in walk order
+	stamps map[string]fileStamp
+	docs   map[string]*parser.Document
+	errs   map[string]error // why each failed file didn't parse
+}
+
+// watchDelta describes one refresh in display names (relative paths in
+// directory mode, the base name in single-file mode).
+type watchDelta struct {
+	changed []string
+	removed []string
+	failed  []string
+	updated []*parser.Document
+}
+
+func (d watchDelta) empty() bool {
+	return len(d.changed) == 0 && len(d.removed) == 0 && len(d.failed) == 0
+}
+
+func newWatchState(target string, isDir bool, opts parseOptions) *watchState {
+	return &watchState{
+		target: target,
+		isDir:  isDir,
+		opts:   opts,
+		stamps: map[string]fileStamp{},
+		docs:   map[string]*parser.Document{},
+		errs:   map[string]error{},
+	}
+}
+
+// files lists the paths currently being watched.
+func (w *watchState) files() []string {
+	if w.isDir {
+		return collectFiles(w.target, w.opts.Files)
+	}
+	if _, err := os.Stat(w.target); err != nil {
+		return nil
+	}
+	return []string{w.target}
+}
+
+func (w *watchState) displayName(path string) string {
+	if w.isDir {
+		rel, _ := filepath.Rel(w.target, path)
+		return rel
+	}
+	return filepath.Base(path)
+}
+
+// refresh rescans the watched files, re-parses the ones whose stamp
+// changed, drops the ones that disappeared, and reports what happened.
+func (w *watchState) refresh() watchDelta {
+	var delta watchDelta
+	paths := w.files()
+	stamps := make(map[string]fileStamp, len(paths))
+	var stale []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		st := fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		stamps[p] = st
+		if old, ok := w.stamps[p]; !ok || old != st {
+			stale = append(stale, p)
+		}
+	}
+
+	for _, p := range w.order {
+		if _, ok := stamps[p]; !ok {
+			delete(w.docs, p)
+			delete(w.errs, p)
+			delta.removed = append(delta.removed, w.displayName(p))
+		}
+	}
+
+	for i, r := range parseFiles(stale, w.opts) {
+		p := stale[i]
+		name := w.displayName(p)
+		if r.notDocument() {
+			delete(w.docs, p)
+			continue
+		}
+		if r.err != nil {
+			// Keep showing the rest of the tree while a file is mid-edit
+			// and temporarily unparseable.
+			delete(w.docs, p)
+			w.errs[p] = r.err
+			delta.failed = append(delta.failed, name)
+			continue
+		}
+		r.doc.Filename = name
+		w.docs[p] = r.doc
+		delete(w.errs, p)
+		delta.changed = append(delta.changed, name)
+		delta.updated = append(delta.updated, r.doc)
+	}
+
+	w.order = paths
+	w.stamps = stamps
+	return delta
+}
+
+// documents returns the parsed documents in walk order.
+func (w *watchState) documents() []*parser.Document {
+	var docs []*parser.Document
+	for _, p := range w.order {
+		if doc := w.docs[p]; doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// runWatch renders target, then re-renders whenever a watched file changes
+// until the process is interrupted. With --json it emits an NDJSON stream of
+// WatchEvents instead of redrawing the screen.
+func runWatch(target string, isDir bool, opts parseOptions, view viewOptions) {
+	state := newWatchState(target, isDir, opts)
+	triggers, stop, mode := watchTriggers(target, isDir, opts.Files)
+	defer stop()
+
+	emit := func(event string, delta watchDelta) {
+		if view.jsonMode {
+			emitWatchEvent(state, event, delta)
+		} else {
+			redraw(state, view, mode, delta)
+		}
+	}
+	emit("initial", state.refresh())
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-triggers:
+			debounce = time.After(watchDebounce)
+		case <-debounce:
+			debounce = nil
+			if delta := state.refresh(); !delta.empty() {
+				emit("update", delta)
+			}
+		}
+	}
+}
+
+func emitWatchEvent(state *watchState, event string, delta watchDelta) {
+	root, _ := filepath.Abs(state.target)
+	docs := state.documents()
+	ev := WatchEvent{
+		Event:     event,
+		Time:      time.Now().Format(time.RFC3339),
+		Root:      root,
+		TotalDocs: len(docs),
+		Changed:   delta.changed,
+		Removed:   delta.removed,
+		Failed:    delta.failed,
+	}
+	for _, doc := range docs {
+		ev.TotalTokens += doc.TotalTokens
+	}
+	for _, doc := range delta.updated {
+		ev.Documents = append(ev.Documents, convertDocument(doc))
+	}
+	json.NewEncoder(os.Stdout).Encode(ev)
+}
+
+func redraw(state *watchState, view viewOptions, mode string, delta watchDelta) {
+	fmt.Print(clearScreen)
+	docs := state.documents()
+	var err error
+	switch {
+	case state.isDir && len(docs) == 0:
+		fmt.Printf("%s\n\n", noFilesMessage)
+	case state.isDir:
+		absPath, _ := filepath.Abs(state.target)
+		err = renderDirectory(docs, state.target, state.target, absPath, view)
+	case len(docs) == 0:
+		if err = state.errs[state.target]; err == nil {
+			err = fmt.Errorf("%s not found", state.target)
+		}
+	default:
+		err = renderFile(docs[0], state.target, view)
+	}
+	if err != nil {
+		// Keep watching: the next save may fix the file or the view.
+		fmt.Printf("Error: %v\n\n", err)
+	}
+
+	status := fmt.Sprintf("watching %d file%s (%s) · %s",
+		len(state.order), plural(len(state.order)), mode, time.Now().Format("15:04:05"))
+	if len(delta.changed) > 0 {
+		status += " · updated " + summarizeNames(delta.changed)
+	}
+	if len(delta.removed) > 0 {
+		status += " · removed " + summarizeNames(delta.removed)
+	}
+	if len(delta.failed) > 0 {
+		status += " · failed " + summarizeNames(delta.failed)
+	}
+	fmt.Printf("%s%s · Ctrl-C to quit%s\n", dimText, status, resetText)
+}
+
+// summarizeNames joins up to three names and counts the rest.
+func summarizeNames(names []string) string {
+	if len(names) <= 3 {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s +%d more", strings.Join(names[:3], ", "), len(names)-3)
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// watchTriggers returns a channel that receives a value whenever something
+// under target may have changed, a stop function, and the name of the
+// mechanism in use. It prefers fsnotify and falls back to polling when the
+// platform watcher can't be created (e.g. inotify watch limits).
+func watchTriggers(target string, isDir bool, rules fileRules) (<-chan struct{}, func(), string) {
+	out := make(chan struct{}, 1)
+	if stop, err := notifyTriggers(target, isDir, rules, out); err == nil {
+		return out, stop, "fsnotify"
+	}
+
+	ticker := time.NewTicker(watchPollInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				signal(out)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out, func() { ticker.Stop(); close(done) }, "polling"
+}
+
+// notifyTriggers watches target with fsnotify. Single files are watched via
+// their parent directory so editors that save by rename are still seen.
+// Directories are watched only where a directory map would look, so
+// ignored trees like node_modules don't use up watches.
+func notifyTriggers(target string, isDir bool, rules fileRules, out chan<- struct{}) (func(), error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dir := target
+	if !isDir {
+		dir = filepath.Dir(target)
+	}
+	if err := addWatchDirs(w, dir, isDir, rules); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	file := filepath.Clean(target)
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if !isDir && filepath.Clean(ev.Name) != file {
+					continue
+				}
+				if isDir && ev.Has(fsnotify.Create) {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						// Rescan from the top so the new directory is
+						// checked against every ignore file above it.
+						addWatchDirs(w, target, true, rules)
+					}
+				}
+				signal(out)
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return func() { w.Close() }, nil
+}
+
+// addWatchDirs adds dir to w, and with recursive set every directory
+// beneath it that collectFiles would walk (fsnotify watches are not
+// recursive). Adding a directory that is already watched is a no-op.
+func addWatchDirs(w *fsnotify.Watcher, dir string, recursive bool, rules fileRules) error {
+	if !recursive {
+		return w.Add(dir)
+	}
+	for _, d := range collectDirs(dir, rules) {
+		if err := w.Add(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signal does a non-blocking send; one pending trigger is enough since the
+// receiver rescans everything anyway.
+func signal(out chan<- struct{}) {
+	select {
+	case out <- struct{}{}:
+	default:
+	}
+}
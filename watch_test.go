@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+func TestWatchStateRefresh(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Bump mtime so coarse-grained filesystems still see a change.
+		later := time.Now().Add(time.Duration(len(content)) * time.Second)
+		os.Chtimes(path, later, later)
+	}
+	write("a.md", "# A\n")
+	write("b.md", "# B\n")
+	write("package.json", `{"name": "x"}`)
+
+	state := newWatchState(dir, true, parseOptions{Jobs: 1})
+	initial := state.refresh()
+	if len(initial.changed) != 2 {
+		t.Fatalf("initial refresh should parse every file, got %v", initial.changed)
+	}
+	if len(initial.failed) != 0 {
+		t.Errorf("non-spec JSON should be skipped, not failed, got %v", initial.failed)
+	}
+
+	if delta := state.refresh(); !delta.empty() {
+		t.Errorf("unchanged tree should produce an empty delta, got %+v", delta)
+	}
+
+	write("a.md", "# A changed\n")
+	write("c.md", "# C\n")
+	os.Remove(filepath.Join(dir, "b.md"))
+
+	delta := state.refresh()
+	if got := delta.changed; len(got) != 2 || got[0] != "a.md" || got[1] != "c.md" {
+		t.Errorf("expected a.md and c.md re-parsed, got %v", got)
+	}
+	if got := delta.removed; len(got) != 1 || got[0] != "b.md" {
+		t.Errorf("expected b.md removed, got %v", got)
+	}
+
+	docs := state.documents()
+	if len(docs) != 2 || docs[0].Sections[0].Title != "A changed" {
+		t.Errorf("expected updated documents in walk order, got %d docs", len(docs))
+	}
+}
+
+func TestWatchStateFailedParse(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.yaml")
+	if err := os.WriteFile(path, []byte("key: value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := newWatchState(path, false, parseOptions{Jobs: 1})
+	state.refresh()
+
+	if err := os.WriteFile(path, []byte("key: [unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	delta := state.refresh()
+	if len(delta.failed) != 1 || delta.failed[0] != "conf.yaml" {
+		t.Errorf("expected conf.yaml reported as failed, got %+v", delta)
+	}
+	if len(state.documents()) != 0 {
+		t.Error("unparseable file should be dropped until it parses again")
+	}
+	if state.errs[path] == nil {
+		t.Error("the parse error should be kept for redraw")
+	}
+}
+
+func TestRenderFileReturnsViewErrors(t *testing.T) {
+	doc := parser.Parse("# Guide\n\n## Setup\n\n## Setup\n")
+	// An ambiguous --section must not exit, so watch mode can report it.
+	if err := renderFile(doc, "guide.md", viewOptions{sectionFilter: "Setup"}); err == nil {
+		t.Error("expected an ambiguity error")
+	}
+	if err := renderDirectory([]*parser.Document{doc}, ".", ".", ".", viewOptions{lineRange: "1-2"}); err == nil {
+		t.Error("expected --lines on a directory to be an error")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+// whereClause is one --where filter on frontmatter fields:
+//
+//	key=value   the field equals value (a list field contains it)
+//	key!=value  the negation of key=value; also true when the field is missing
+//	key~=value  the field, or one of its items, contains value (case-insensitive)
+//
+// Keys may be dotted to reach nested tables ("params.author").
+type whereClause struct {
+	key   string
+	op    string
+	value string
+}
+
+func parseWhere(expr string) (whereClause, error) {
+	i := strings.Index(expr, "=")
+	if i <= 0 {
+		return whereClause{}, fmt.Errorf("invalid --where %q: want key=value, key!=value or key~=value", expr)
+	}
+	c := whereClause{key: expr[:i], op: "=", value: expr[i+1:]}
+	if strings.HasSuffix(c.key, "!") || strings.HasSuffix(c.key, "~") {
+		c.op = c.key[len(c.key)-1:] + "="
+		c.key = c.key[:len(c.key)-1]
+	}
+	c.key = strings.TrimSpace(c.key)
+	if c.key == "" {
+		return whereClause{}, fmt.Errorf("invalid --where %q: missing field name", expr)
+	}
+	return c, nil
+}
+
+// matches reports whether fm satisfies the clause. Documents without
+// frontmatter only satisfy != clauses.
+func (c whereClause) matches(fm *parser.Frontmatter) bool {
+	var v any
+	var ok bool
+	if fm != nil {
+		v, ok = fm.Field(c.key)
+	}
+	switch c.op {
+	case "!=":
+		return !ok || !anyValue(v, func(s string) bool { return s == c.value })
+	case "~=":
+		needle := strings.ToLower(c.value)
+		return ok && anyValue(v, func(s string) bool { return strings.Contains(strings.ToLower(s), needle) })
+	}
+	return ok && anyValue(v, func(s string) bool { return s == c.value })
+}
+
+// anyValue applies match to a scalar field's string form, or to each item
+// of a list field.
+func anyValue(v any, match func(string) bool) bool {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if anyValue(item, match) {
+				return true
+			}
+		}
+		return false
+	case map[string]any:
+		return false
+	case nil:
+		return match("")
+	}
+	return match(fmt.Sprint(v))
+}
+
+// filterWhere keeps the documents whose frontmatter satisfies every clause.
+func filterWhere(docs []*parser.Document, clauses []whereClause) []*parser.Document {
+	if len(clauses) == 0 {
+		return docs
+	}
+	var out []*parser.Document
+	for _, doc := range docs {
+		fm := doc.Frontmatter()
+		keep := true
+		for _, c := range clauses {
+			if !c.matches(fm) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
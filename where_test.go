@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/JordanCoin/docmap/parser"
+)
+
+func TestFilterWhere(t *testing.T) {
+	docs := []*parser.Document{
+		parser.Parse("---\nstatus: draft\ntags: [API, auth]\n---\n# A\n"),
+		parser.Parse("+++\nstatus = \"published\"\ntags = [\"guide\"]\n[params]\nauthor = \"ana\"\n+++\n# B\n"),
+		parser.Parse("# C\n"),
+	}
+	for i, name := range []string{"a.md", "b.md", "c.md"} {
+		docs[i].Filename = name
+	}
+
+	tests := []struct {
+		exprs []string
+		want  string
+	}{
+		{[]string{"status=draft"}, "a.md"},
+		{[]string{"status!=draft"}, "b.md c.md"},
+		{[]string{"tags~=api"}, "a.md"},
+		{[]string{"tags=auth"}, "a.md"},
+		{[]string{"tags=aut"}, ""},
+		{[]string{"params.author=ana"}, "b.md"},
+		{[]string{"status~=d", "tags~=guide"}, "b.md"},
+	}
+	for _, tt := range tests {
+		var clauses []whereClause
+		for _, expr := range tt.exprs {
+			c, err := parseWhere(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clauses = append(clauses, c)
+		}
+		var got string
+		for _, doc := range filterWhere(docs, clauses) {
+			if got != "" {
+				got += " "
+			}
+			got += doc.Filename
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.exprs, got, tt.want)
+		}
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	for _, expr := range []string{"status", "=draft", "~=x"} {
+		if _, err := parseWhere(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}